@@ -0,0 +1,274 @@
+package marquee
+
+import (
+	"fmt"
+	"image"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Canvas is what a layout is painted on. The widget paints on the raylib
+// window; other canvases draw into images or files, or record what they are
+// asked to draw so a test can check it. Coordinates are in pixels from the
+// top left, and text is placed by the top left of its line.
+type Canvas interface {
+	DrawText(text string, x, y float32, font Font, color rl.Color)
+	FillRect(bounds rl.Rectangle, color rl.Color)
+	StrokeRect(bounds rl.Rectangle, width float32, color rl.Color)
+	DrawLine(x1, y1, x2, y2, width float32, color rl.Color)
+	DrawImage(img image.Image, bounds rl.Rectangle)
+
+	// PushClip limits drawing to bounds, within any clip already in place,
+	// until the matching PopClip.
+	PushClip(bounds rl.Rectangle)
+	PopClip()
+}
+
+// Paint draws the box and everything in it on canvas, moved by offsetX and
+// offsetY. Lines that fall outside visible are skipped.
+func (b *LayoutBox) Paint(canvas Canvas, offsetX, offsetY float32, visible rl.Rectangle) {
+	bounds := b.Bounds
+	bounds.X += offsetX
+	bounds.Y += offsetY
+
+	if overlapsVertically(bounds, visible) {
+		if b.Background.A != 0 {
+			canvas.FillRect(bounds, b.Background)
+		}
+		if b.BorderColor.A != 0 && b.BorderWidth > 0 {
+			canvas.StrokeRect(bounds, b.BorderWidth, b.BorderColor)
+		}
+	}
+
+	for _, line := range b.Lines {
+		lineBounds := line.Bounds
+		lineBounds.Y += offsetY
+		if !overlapsVertically(lineBounds, visible) {
+			continue
+		}
+		for _, fragment := range line.Fragments {
+			paintFragment(canvas, fragment, offsetX, offsetY)
+		}
+	}
+
+	for i := range b.Children {
+		b.Children[i].Paint(canvas, offsetX, offsetY, visible)
+	}
+}
+
+func overlapsVertically(a, b rl.Rectangle) bool {
+	return a.Y <= b.Y+b.Height && a.Y+a.Height >= b.Y
+}
+
+// paintFragment draws a run of text with its background and its underline
+// or line through.
+func paintFragment(canvas Canvas, fragment TextFragment, offsetX, offsetY float32) {
+	x := fragment.Bounds.X + offsetX
+	y := fragment.Bounds.Y + offsetY
+	width := fragment.Bounds.Width
+	fontSize := fragment.Font.Size
+
+	if fragment.Background.A != 0 {
+		canvas.FillRect(rl.NewRectangle(x, y, width, fontSize), fragment.Background)
+	}
+	canvas.DrawText(fragment.Text, x, y, fragment.Font, fragment.Color)
+
+	if fragment.Href != "" || fragment.Underline {
+		canvas.DrawLine(x, y+fontSize, x+width, y+fontSize, 1, fragment.Color)
+	}
+
+	if fragment.LineThrough {
+		canvas.DrawLine(x, y+fontSize*0.55, x+width, y+fontSize*0.55, 1, fragment.Color)
+	}
+}
+
+// intersectRect returns the part of a that is also in b, which is empty when
+// they do not overlap.
+func intersectRect(a, b rl.Rectangle) rl.Rectangle {
+	left := max(a.X, b.X)
+	top := max(a.Y, b.Y)
+	right := min(a.X+a.Width, b.X+b.Width)
+	bottom := min(a.Y+a.Height, b.Y+b.Height)
+	if right < left {
+		right = left
+	}
+	if bottom < top {
+		bottom = top
+	}
+	return rl.NewRectangle(left, top, right-left, bottom-top)
+}
+
+// raylibCanvas paints on the raylib window. It is the widget's canvas, and
+// must only be used between BeginDrawing and EndDrawing on the main thread.
+type raylibCanvas struct {
+	fonts    *raylibFonts
+	clips    []rl.Rectangle
+	textures map[image.Image]rl.Texture2D
+}
+
+func newRaylibCanvas(fonts *raylibFonts) *raylibCanvas {
+	return &raylibCanvas{
+		fonts:    fonts,
+		textures: make(map[image.Image]rl.Texture2D),
+	}
+}
+
+func (c *raylibCanvas) DrawText(text string, x, y float32, font Font, color rl.Color) {
+	renderTextWithUnicode(text, x, y, c.fonts.font(font), font.Size, color)
+}
+
+func (c *raylibCanvas) FillRect(bounds rl.Rectangle, color rl.Color) {
+	rl.DrawRectangleRec(bounds, color)
+}
+
+func (c *raylibCanvas) StrokeRect(bounds rl.Rectangle, width float32, color rl.Color) {
+	rl.DrawRectangleLinesEx(bounds, width, color)
+}
+
+func (c *raylibCanvas) DrawLine(x1, y1, x2, y2, width float32, color rl.Color) {
+	rl.DrawLineEx(rl.NewVector2(x1, y1), rl.NewVector2(x2, y2), width, color)
+}
+
+// DrawImage uploads img as a texture the first time it is drawn and reuses
+// the texture after that, so the same image value should be passed each
+// frame.
+func (c *raylibCanvas) DrawImage(img image.Image, bounds rl.Rectangle) {
+	texture, exists := c.textures[img]
+	if !exists {
+		rlImage := rl.NewImageFromImage(img)
+		texture = rl.LoadTextureFromImage(rlImage)
+		rl.UnloadImage(rlImage)
+		c.textures[img] = texture
+	}
+
+	source := rl.NewRectangle(0, 0, float32(texture.Width), float32(texture.Height))
+	rl.DrawTexturePro(texture, source, bounds, rl.NewVector2(0, 0), 0, rl.White)
+}
+
+// PushClip sets the scissor rectangle. raylib has a single scissor, so
+// nested clips are intersected here.
+func (c *raylibCanvas) PushClip(bounds rl.Rectangle) {
+	if len(c.clips) > 0 {
+		bounds = intersectRect(bounds, c.clips[len(c.clips)-1])
+	}
+	c.clips = append(c.clips, bounds)
+	c.beginScissor(bounds)
+}
+
+func (c *raylibCanvas) PopClip() {
+	if len(c.clips) == 0 {
+		return
+	}
+	c.clips = c.clips[:len(c.clips)-1]
+	if len(c.clips) == 0 {
+		rl.EndScissorMode()
+		return
+	}
+	c.beginScissor(c.clips[len(c.clips)-1])
+}
+
+func (c *raylibCanvas) beginScissor(bounds rl.Rectangle) {
+	rl.BeginScissorMode(int32(bounds.X), int32(bounds.Y), int32(bounds.Width), int32(bounds.Height))
+}
+
+// unload releases the textures of the images drawn so far.
+func (c *raylibCanvas) unload() {
+	for _, texture := range c.textures {
+		rl.UnloadTexture(texture)
+	}
+	c.textures = make(map[image.Image]rl.Texture2D)
+}
+
+// DrawOpKind is the kind of call a RecordingCanvas recorded.
+type DrawOpKind int
+
+const (
+	OpText DrawOpKind = iota
+	OpFillRect
+	OpStrokeRect
+	OpLine
+	OpImage
+	OpPushClip
+	OpPopClip
+)
+
+// DrawOp is one call made on a RecordingCanvas. Only the fields that apply to
+// its kind are set: Text, X, Y and Font for text; Bounds for rectangles,
+// images and clips; X, Y, X2 and Y2 for lines; Width for strokes and lines.
+type DrawOp struct {
+	Kind   DrawOpKind
+	Text   string
+	Font   Font
+	X, Y   float32
+	X2, Y2 float32
+	Bounds rl.Rectangle
+	Width  float32
+	Color  rl.Color
+	Image  image.Image
+}
+
+// String describes the operation on one line, for comparing what was drawn
+// against what a test expects.
+func (op DrawOp) String() string {
+	color := fmt.Sprintf("#%02x%02x%02x%02x", op.Color.R, op.Color.G, op.Color.B, op.Color.A)
+	switch op.Kind {
+	case OpText:
+		return fmt.Sprintf("text %q at (%g,%g) size %g %s", op.Text, op.X, op.Y, op.Font.Size, color)
+	case OpFillRect:
+		return fmt.Sprintf("fill %s %s", formatRect(op.Bounds), color)
+	case OpStrokeRect:
+		return fmt.Sprintf("stroke %s width %g %s", formatRect(op.Bounds), op.Width, color)
+	case OpLine:
+		return fmt.Sprintf("line (%g,%g)-(%g,%g) width %g %s", op.X, op.Y, op.X2, op.Y2, op.Width, color)
+	case OpImage:
+		return fmt.Sprintf("image %s", formatRect(op.Bounds))
+	case OpPushClip:
+		return fmt.Sprintf("clip %s", formatRect(op.Bounds))
+	case OpPopClip:
+		return "unclip"
+	}
+	return "unknown"
+}
+
+func formatRect(r rl.Rectangle) string {
+	return fmt.Sprintf("(%g,%g %gx%g)", r.X, r.Y, r.Width, r.Height)
+}
+
+// RecordingCanvas is a Canvas that draws nothing and keeps a list of the
+// calls made on it, so painting can be checked without a window.
+type RecordingCanvas struct {
+	Ops []DrawOp
+}
+
+func (c *RecordingCanvas) DrawText(text string, x, y float32, font Font, color rl.Color) {
+	c.Ops = append(c.Ops, DrawOp{Kind: OpText, Text: text, X: x, Y: y, Font: font, Color: color})
+}
+
+func (c *RecordingCanvas) FillRect(bounds rl.Rectangle, color rl.Color) {
+	c.Ops = append(c.Ops, DrawOp{Kind: OpFillRect, Bounds: bounds, Color: color})
+}
+
+func (c *RecordingCanvas) StrokeRect(bounds rl.Rectangle, width float32, color rl.Color) {
+	c.Ops = append(c.Ops, DrawOp{Kind: OpStrokeRect, Bounds: bounds, Width: width, Color: color})
+}
+
+func (c *RecordingCanvas) DrawLine(x1, y1, x2, y2, width float32, color rl.Color) {
+	c.Ops = append(c.Ops, DrawOp{Kind: OpLine, X: x1, Y: y1, X2: x2, Y2: y2, Width: width, Color: color})
+}
+
+func (c *RecordingCanvas) DrawImage(img image.Image, bounds rl.Rectangle) {
+	c.Ops = append(c.Ops, DrawOp{Kind: OpImage, Bounds: bounds, Image: img})
+}
+
+func (c *RecordingCanvas) PushClip(bounds rl.Rectangle) {
+	c.Ops = append(c.Ops, DrawOp{Kind: OpPushClip, Bounds: bounds})
+}
+
+func (c *RecordingCanvas) PopClip() {
+	c.Ops = append(c.Ops, DrawOp{Kind: OpPopClip})
+}
+
+// Reset forgets the calls recorded so far.
+func (c *RecordingCanvas) Reset() {
+	c.Ops = c.Ops[:0]
+}
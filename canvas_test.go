@@ -0,0 +1,120 @@
+package marquee
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func recordPaint(layout *DocumentLayout, offsetX, offsetY float32, visible rl.Rectangle) []string {
+	canvas := &RecordingCanvas{}
+	layout.Root.Paint(canvas, offsetX, offsetY, visible)
+
+	ops := make([]string, len(canvas.Ops))
+	for i, op := range canvas.Ops {
+		ops[i] = op.String()
+	}
+	return ops
+}
+
+func TestPaintDrawOps(t *testing.T) {
+	layout := fixedLayout(t, `<div style="background: #eeeeee; border: 2px solid #ff0000; padding: 4px">`+
+		`<p>a<a href="/x">b</a><span style="background: yellow">c</span><s>d</s></p></div>`+
+		`<ul><li>one</li></ul><hr>`, 200)
+
+	want := []string{
+		"fill (10,20 200x37) #eeeeeeff",
+		"stroke (10,20 200x37) width 2 #ff0000ff",
+		`text "a" at (16,26) size 16 #000000ff`,
+		`text "b" at (24,26) size 16 #0079f1ff`,
+		"line (24,42)-(32,42) width 1 #0079f1ff",
+		"fill (32,26 8x16) #ffff00ff",
+		`text "c" at (32,26) size 16 #000000ff`,
+		`text "d" at (40,26) size 16 #000000ff`,
+		"line (40,34.8)-(48,34.8) width 1 #000000ff",
+		`text "•" at (20,67) size 18 #000000ff`,
+		`text "one" at (35,67) size 16 #000000ff`,
+		"fill (10,96 200x2) #828282ff",
+	}
+	if got := recordPaint(layout, 10, 20, rl.NewRectangle(0, 0, 500, 500)); !reflect.DeepEqual(got, want) {
+		t.Errorf("painted\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPaintSkipsLinesOutOfView(t *testing.T) {
+	layout := fixedLayout(t, "<p>one</p><p>two</p><p>three</p>", 200)
+
+	// Scrolled down by 30px, a view 10px tall only shows the second line.
+	want := []string{`text "two" at (0,-5) size 16 #000000ff`}
+	if got := recordPaint(layout, 0, -30, rl.NewRectangle(0, 0, 500, 10)); !reflect.DeepEqual(got, want) {
+		t.Errorf("painted %q, want %q", got, want)
+	}
+
+	if got := recordPaint(layout, 0, -1000, rl.NewRectangle(0, 0, 500, 500)); len(got) != 0 {
+		t.Errorf("painted %q with the document scrolled out of view", got)
+	}
+}
+
+func TestRecordingCanvas(t *testing.T) {
+	var canvas RecordingCanvas
+	canvas.PushClip(rl.NewRectangle(1, 2, 3, 4))
+	canvas.DrawLine(0, 0, 5, 5, 2, rl.NewColor(1, 2, 3, 255))
+	canvas.StrokeRect(rl.NewRectangle(0, 0, 10, 10), 1, rl.NewColor(0, 0, 0, 128))
+	canvas.PopClip()
+
+	want := []string{
+		"clip (1,2 3x4)",
+		"line (0,0)-(5,5) width 2 #010203ff",
+		"stroke (0,0 10x10) width 1 #00000080",
+		"unclip",
+	}
+	var got []string
+	for _, op := range canvas.Ops {
+		got = append(got, op.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recorded %q, want %q", got, want)
+	}
+
+	canvas.Reset()
+	if len(canvas.Ops) != 0 {
+		t.Errorf("Reset left %d operations", len(canvas.Ops))
+	}
+}
+
+func TestPaintHeadingInlineStyles(t *testing.T) {
+	layout := fixedLayout(t, `<h1 style="margin: 0">A <em>b</em><span style="font-size: 10px; background: yellow">c</span><a href="/x">d</a></h1>`, 400)
+
+	// Nested elements keep the size of the heading unless they set their
+	// own.
+	want := []string{
+		`text "A" at (0,0) size 32 #0052acff`,
+		`text " " at (16,0) size 32 #00752cff`,
+		`text "b" at (32,0) size 32 #00752cff`,
+		"fill (48,17.6 5x10) #ffff00ff",
+		`text "c" at (48,17.6) size 10 #0052acff`,
+		`text "d" at (53,0) size 32 #0079f1ff`,
+		"line (53,32)-(69,32) width 1 #0079f1ff",
+	}
+	if got := recordPaint(layout, 0, 0, rl.NewRectangle(0, 0, 500, 500)); !reflect.DeepEqual(got, want) {
+		t.Errorf("painted\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if want := []LinkArea{{Bounds: rl.NewRectangle(53, 0, 16, 32), URL: "/x"}}; !reflect.DeepEqual(layout.Links, want) {
+		t.Errorf("Links = %v, want %v", layout.Links, want)
+	}
+}
+
+func TestPaintStyledLink(t *testing.T) {
+	layout := fixedLayout(t, `<a href="/x" style="color: #ff0000; background: #eeeeee; font-size: 20px">link</a>`, 400)
+
+	want := []string{
+		"fill (0,0 40x20) #eeeeeeff",
+		`text "link" at (0,0) size 20 #ff0000ff`,
+		"line (0,20)-(40,20) width 1 #ff0000ff",
+	}
+	if got := recordPaint(layout, 0, 0, rl.NewRectangle(0, 0, 500, 500)); !reflect.DeepEqual(got, want) {
+		t.Errorf("painted\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
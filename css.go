@@ -0,0 +1,416 @@
+package marquee
+
+import (
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// This file parses the values of the CSS properties marquee understands:
+// colors, lengths and the shorthands built from them.
+
+// mediumFontSize is the size in pixels of the "medium" font-size keyword,
+// and the size relative font sizes start from when nothing sets one.
+const mediumFontSize = 16
+
+// LengthUnit says what a Length is measured in.
+type LengthUnit int
+
+const (
+	// UnitNone marks a Length that was not set.
+	UnitNone LengthUnit = iota
+	UnitPixels
+	UnitPercent
+)
+
+// Length is a CSS length. Units that depend on the font, such as em, are
+// converted to pixels when the style is computed; percentages are of the
+// width of the containing block and are resolved at layout. The zero Length
+// is not set.
+type Length struct {
+	Value float32
+	Unit  LengthUnit
+}
+
+// IsSet reports whether the length was given.
+func (l Length) IsSet() bool {
+	return l.Unit != UnitNone
+}
+
+// resolve returns the length in pixels, with percentages taken of base, or
+// fallback when it is not set.
+func (l Length) resolve(base, fallback float32) float32 {
+	switch l.Unit {
+	case UnitPixels:
+		return l.Value
+	case UnitPercent:
+		return base * l.Value / 100
+	}
+	return fallback
+}
+
+// Edges are the lengths of the four sides of a margin or padding.
+type Edges struct {
+	Top, Right, Bottom, Left Length
+}
+
+// isSet reports whether any side was given.
+func (e Edges) isSet() bool {
+	return e.Top.IsSet() || e.Right.IsSet() || e.Bottom.IsSet() || e.Left.IsSet()
+}
+
+// insets are the sides of a margin, border or padding in pixels.
+type insets struct {
+	top, right, bottom, left float32
+}
+
+// resolve returns the sides in pixels, with percentages taken of width, and
+// the sides that were not set taken from defaults.
+func (e Edges) resolve(width float32, defaults insets) insets {
+	return insets{
+		top:    e.Top.resolve(width, defaults.top),
+		right:  e.Right.resolve(width, defaults.right),
+		bottom: e.Bottom.resolve(width, defaults.bottom),
+		left:   e.Left.resolve(width, defaults.left),
+	}
+}
+
+// Border is the border drawn around a block. Its width is not set when the
+// style asks for no border, and a zero Color means the color of the text.
+type Border struct {
+	Width Length
+	Color rl.Color
+}
+
+// TextAlign is how the lines of a block are placed between its edges.
+type TextAlign int
+
+const (
+	AlignLeft TextAlign = iota
+	AlignCenter
+	AlignRight
+	AlignJustify
+)
+
+// parseLength parses a length such as "12px", "1.5em" or "50%". fontSize
+// is the size em is relative to. "auto" and anything not understood give
+// false.
+func parseLength(value string, fontSize float32) (Length, bool) {
+	if value == "0" {
+		return Length{Unit: UnitPixels}, true
+	}
+
+	number, unit := splitNumber(value)
+	n, err := strconv.ParseFloat(number, 32)
+	if number == "" || err != nil {
+		return Length{}, false
+	}
+	v := float32(n)
+
+	switch unit {
+	case "px":
+		return Length{Value: v, Unit: UnitPixels}, true
+	case "pt":
+		return Length{Value: v * 4 / 3, Unit: UnitPixels}, true
+	case "em":
+		return Length{Value: v * fontSize, Unit: UnitPixels}, true
+	case "rem":
+		return Length{Value: v * mediumFontSize, Unit: UnitPixels}, true
+	case "%":
+		return Length{Value: v, Unit: UnitPercent}, true
+	}
+	return Length{}, false
+}
+
+// splitNumber splits a value such as "1.5em" into its number and its unit.
+func splitNumber(value string) (number, unit string) {
+	i := 0
+	for i < len(value) && (value[i] >= '0' && value[i] <= '9' || value[i] == '.' || value[i] == '-' || value[i] == '+') {
+		i++
+	}
+	return value[:i], value[i:]
+}
+
+// fontSizeKeywords are the absolute font-size keywords, in pixels.
+var fontSizeKeywords = map[string]float32{
+	"xx-small": 9, "x-small": 10, "small": 13, "medium": mediumFontSize,
+	"large": 18, "x-large": 24, "xx-large": 32, "xxx-large": 48,
+}
+
+// parseFontSize parses a font-size relative to parent, the font size of the
+// parent element, and returns it in pixels.
+func parseFontSize(value string, parent float32) (float32, bool) {
+	if size, exists := fontSizeKeywords[value]; exists {
+		return size, true
+	}
+	switch value {
+	case "smaller":
+		return parent / 1.2, true
+	case "larger":
+		return parent * 1.2, true
+	}
+
+	length, ok := parseLength(value, parent)
+	if !ok || length.Value <= 0 {
+		return 0, false
+	}
+	return length.resolve(parent, 0), true
+}
+
+// parseEdges parses the one to four lengths of a margin or padding
+// shorthand, which give the sides in the order top, right, bottom, left.
+// negative says whether lengths below zero are allowed.
+func parseEdges(value string, fontSize float32, negative bool) (Edges, bool) {
+	var lengths []Length
+	for _, field := range strings.Fields(value) {
+		length, ok := parseEdge(field, fontSize, negative)
+		if !ok {
+			return Edges{}, false
+		}
+		lengths = append(lengths, length)
+	}
+
+	switch len(lengths) {
+	case 1:
+		return Edges{lengths[0], lengths[0], lengths[0], lengths[0]}, true
+	case 2:
+		return Edges{lengths[0], lengths[1], lengths[0], lengths[1]}, true
+	case 3:
+		return Edges{lengths[0], lengths[1], lengths[2], lengths[1]}, true
+	case 4:
+		return Edges{lengths[0], lengths[1], lengths[2], lengths[3]}, true
+	}
+	return Edges{}, false
+}
+
+// parseEdge parses one side of a margin or padding. An auto margin is
+// accepted and left to the renderer.
+func parseEdge(value string, fontSize float32, negative bool) (Length, bool) {
+	if value == "auto" && negative {
+		return Length{}, true
+	}
+	length, ok := parseLength(value, fontSize)
+	if !ok || (!negative && length.Value < 0) {
+		return Length{}, false
+	}
+	return length, true
+}
+
+// borderWidths are the border-width keywords, in pixels.
+var borderWidths = map[string]float32{"thin": 1, "medium": 3, "thick": 5}
+
+// borderStyles are the values of border-style. Every style but none and
+// hidden is drawn as a solid line.
+var borderStyles = map[string]bool{
+	"none": true, "hidden": true, "solid": true, "dashed": true,
+	"dotted": true, "double": true, "groove": true, "ridge": true,
+	"inset": true, "outset": true,
+}
+
+// borderParts are the width, style and color given by border properties;
+// a part is empty when it was not given.
+type borderParts struct {
+	width, style, color string
+}
+
+// parseBorder splits the value of the border shorthand into its parts,
+// which may come in any order.
+func parseBorder(value string) (borderParts, bool) {
+	var parts borderParts
+	for _, field := range fieldsOutsideParens(value) {
+		switch {
+		case borderStyles[field]:
+			parts.style = field
+		case borderWidths[field] != 0 || isLength(field):
+			parts.width = field
+		default:
+			if _, ok := parseColor(field); !ok {
+				return borderParts{}, false
+			}
+			parts.color = field
+		}
+	}
+	return parts, true
+}
+
+func isLength(value string) bool {
+	_, ok := parseLength(value, mediumFontSize)
+	return ok
+}
+
+// border returns the border the parts describe. As in CSS, there is no
+// border unless a style other than none is given, and the width is medium
+// unless one is given.
+func (parts borderParts) border(fontSize float32) Border {
+	var border Border
+	if parts.style == "" || parts.style == "none" || parts.style == "hidden" {
+		return border
+	}
+
+	border.Width = Length{Value: borderWidths["medium"], Unit: UnitPixels}
+	if width, exists := borderWidths[parts.width]; exists {
+		border.Width.Value = width
+	} else if length, ok := parseLength(parts.width, fontSize); ok && length.Unit == UnitPixels && length.Value >= 0 {
+		border.Width = length
+	}
+
+	if color, ok := parseColor(parts.color); ok {
+		border.Color = color
+	}
+	return border
+}
+
+// parseColor parses a color given by name, as #rgb, #rgba, #rrggbb or
+// #rrggbbaa, or with rgb(), rgba(), hsl() or hsla(). "transparent" is the
+// zero color.
+func parseColor(value string) (rl.Color, bool) {
+	switch {
+	case value == "transparent":
+		return rl.Color{}, true
+	case strings.HasPrefix(value, "#"):
+		return parseHexColor(value[1:])
+	case strings.HasPrefix(value, "rgb"):
+		return parseColorFunction(value, "rgb", rgbColor)
+	case strings.HasPrefix(value, "hsl"):
+		return parseColorFunction(value, "hsl", hslColor)
+	}
+
+	if rgb, exists := namedColors[value]; exists {
+		return rl.NewColor(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb), 255), true
+	}
+	return rl.Color{}, false
+}
+
+func parseHexColor(hex string) (rl.Color, bool) {
+	digits := make([]uint8, len(hex))
+	for i := 0; i < len(hex); i++ {
+		n, err := strconv.ParseUint(hex[i:i+1], 16, 8)
+		if err != nil {
+			return rl.Color{}, false
+		}
+		digits[i] = uint8(n)
+	}
+
+	switch len(digits) {
+	case 3, 4:
+		// Each digit is doubled: #f80 is #ff8800.
+		channels := [4]uint8{255, 255, 255, 255}
+		for i, d := range digits {
+			channels[i] = d*16 + d
+		}
+		return rl.NewColor(channels[0], channels[1], channels[2], channels[3]), true
+	case 6, 8:
+		channels := [4]uint8{255, 255, 255, 255}
+		for i := 0; i < len(digits); i += 2 {
+			channels[i/2] = digits[i]*16 + digits[i+1]
+		}
+		return rl.NewColor(channels[0], channels[1], channels[2], channels[3]), true
+	}
+	return rl.Color{}, false
+}
+
+// parseColorFunction parses rgb(...), rgba(...), hsl(...) or hsla(...),
+// with the arguments separated by commas or, in the newer syntax, by spaces
+// with the alpha after a slash.
+func parseColorFunction(value, name string, convert func(args [3]string) (rl.Color, bool)) (rl.Color, bool) {
+	inner, found := strings.CutPrefix(value, name+"a(")
+	if !found {
+		inner, found = strings.CutPrefix(value, name+"(")
+	}
+	inner, closed := strings.CutSuffix(inner, ")")
+	if !found || !closed {
+		return rl.Color{}, false
+	}
+
+	args := strings.Fields(strings.NewReplacer(",", " ", "/", " ").Replace(inner))
+	if len(args) != 3 && len(args) != 4 {
+		return rl.Color{}, false
+	}
+
+	color, ok := convert([3]string{args[0], args[1], args[2]})
+	if ok && len(args) == 4 {
+		alpha, valid := parseChannel(args[3], 1)
+		if !valid {
+			return rl.Color{}, false
+		}
+		color.A = uint8(alpha*255 + 0.5)
+	}
+	return color, ok
+}
+
+// parseChannel parses a number, or a percentage of limit, and clamps it to
+// between 0 and limit.
+func parseChannel(value string, limit float64) (float64, bool) {
+	percent := strings.HasSuffix(value, "%")
+	n, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, false
+	}
+	if percent {
+		n = n / 100 * limit
+	}
+	return min(limit, max(0, n)), true
+}
+
+func rgbColor(args [3]string) (rl.Color, bool) {
+	var channels [3]uint8
+	for i, arg := range args {
+		n, ok := parseChannel(arg, 255)
+		if !ok {
+			return rl.Color{}, false
+		}
+		channels[i] = uint8(n + 0.5)
+	}
+	return rl.NewColor(channels[0], channels[1], channels[2], 255), true
+}
+
+func hslColor(args [3]string) (rl.Color, bool) {
+	hue, err := strconv.ParseFloat(strings.TrimSuffix(args[0], "deg"), 64)
+	saturation, sOK := parseChannel(args[1], 1)
+	lightness, lOK := parseChannel(args[2], 1)
+	if err != nil || !sOK || !lOK {
+		return rl.Color{}, false
+	}
+
+	// The conversion from the CSS Color specification.
+	hue = hue - 360*float64(int(hue/360))
+	if hue < 0 {
+		hue += 360
+	}
+	a := saturation * min(lightness, 1-lightness)
+	channel := func(n float64) uint8 {
+		k := n + hue/30
+		k -= 12 * float64(int(k/12))
+		return uint8((lightness-a*max(-1, min(k-3, 9-k, 1)))*255 + 0.5)
+	}
+	return rl.NewColor(channel(0), channel(8), channel(4), 255), true
+}
+
+// fieldsOutsideParens splits value at spaces that are not inside
+// parentheses, so "1px solid rgb(0, 0, 0)" gives three fields.
+func fieldsOutsideParens(value string) []string {
+	var fields []string
+	depth, start := 0, -1
+	for i, r := range value {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case isHTMLSpace(r) && depth == 0:
+			if start >= 0 {
+				fields = append(fields, value[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, value[start:])
+	}
+	return fields
+}
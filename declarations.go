@@ -0,0 +1,166 @@
+package marquee
+
+import (
+	"strings"
+)
+
+// This file holds the tokenizer states for markup that is not a tag:
+// comments, DOCTYPE declarations, CDATA sections and processing
+// instructions.
+
+// startMarkupDeclaration is called on the "!" of "<!" and picks the state
+// for what follows.
+func (p *StateMachineParser) startMarkupDeclaration() {
+	p.markupBuffer.Reset()
+
+	switch {
+	case p.lookingAt(p.position+1, "--"):
+		p.position += len("--")
+		p.state = StateComment
+	case p.lookingAt(p.position+1, "doctype"):
+		p.position += len("doctype")
+		p.state = StateDoctype
+	case p.lookingAtExact(p.position+1, "[CDATA["):
+		p.position += len("[CDATA[")
+		p.state = StateCDATA
+	default:
+		p.addDiagnostic(SeverityWarning, DiagnosticBogusComment, p.tagStart, "",
+			"\"<!\" does not start a comment, DOCTYPE or CDATA section and is treated as a comment")
+		p.state = StateBogusComment
+	}
+}
+
+// handleCommentState reads "<!-- ... -->". As in HTML5, "<!-->" and
+// "<!--->" are empty comments and "--!>" also ends a comment.
+func (p *StateMachineParser) handleCommentState(char rune) {
+	if char == '>' {
+		content := p.markupBuffer.String()
+		switch {
+		case strings.HasSuffix(content, "--"):
+			p.finishComment(strings.TrimSuffix(content, "--"))
+			return
+		case strings.HasSuffix(content, "--!"):
+			p.finishComment(strings.TrimSuffix(content, "--!"))
+			return
+		case content == "" || content == "-":
+			p.finishComment("")
+			return
+		}
+	}
+
+	p.markupBuffer.WriteRune(char)
+}
+
+// handleBogusCommentState reads "<!...>" that is not a comment, DOCTYPE or
+// CDATA section. It ends at the first ">".
+func (p *StateMachineParser) handleBogusCommentState(char rune) {
+	if char == '>' {
+		p.finishComment(p.markupBuffer.String())
+		return
+	}
+
+	p.markupBuffer.WriteRune(char)
+}
+
+// finishComment ends the current comment and, if the parser keeps comments,
+// adds it to the current node.
+func (p *StateMachineParser) finishComment(content string) {
+	p.markupBuffer.Reset()
+	p.state = StateText
+
+	if !p.keepComments || len(p.nodeStack) == 0 {
+		return
+	}
+
+	parent := p.nodeStack[len(p.nodeStack)-1].Node
+	parent.Children = append(parent.Children, HTMLNode{
+		Type:    NodeTypeComment,
+		Content: content,
+		Context: ContextInline,
+		Parent:  parent,
+	})
+}
+
+// handleDoctypeState reads the rest of a "<!DOCTYPE ...>" declaration.
+func (p *StateMachineParser) handleDoctypeState(char rune) {
+	if char == '>' {
+		p.finishDoctype()
+		return
+	}
+
+	p.markupBuffer.WriteRune(char)
+}
+
+func (p *StateMachineParser) finishDoctype() {
+	p.docType = collapseWhitespace(strings.TrimFunc(p.markupBuffer.String(), isHTMLSpace))
+	p.markupBuffer.Reset()
+	p.state = StateText
+}
+
+// handleCDATAState reads "<![CDATA[ ... ]]>". The contents become text
+// exactly as written, without character reference decoding.
+func (p *StateMachineParser) handleCDATAState(char rune) {
+	if char == '>' && strings.HasSuffix(p.markupBuffer.String(), "]]") {
+		p.finishCDATA(strings.TrimSuffix(p.markupBuffer.String(), "]]"))
+		return
+	}
+
+	p.markupBuffer.WriteRune(char)
+}
+
+func (p *StateMachineParser) finishCDATA(content string) {
+	p.markupBuffer.Reset()
+	p.state = StateText
+
+	if content != "" {
+		p.insertText(content, false)
+	}
+}
+
+// handleProcessingInstructionState reads "<?target ...?>", such as an XML
+// prologue. Processing instructions have no meaning in HTML and are dropped.
+func (p *StateMachineParser) handleProcessingInstructionState(char rune) {
+	if char == '>' && strings.HasSuffix(p.markupBuffer.String(), "?") {
+		p.markupBuffer.Reset()
+		p.state = StateText
+		return
+	}
+
+	p.markupBuffer.WriteRune(char)
+}
+
+// finishMarkupAtEOF ends a comment, DOCTYPE, CDATA section or processing
+// instruction that is still open when the input runs out.
+func (p *StateMachineParser) finishMarkupAtEOF() {
+	switch p.state {
+	case StateComment, StateBogusComment:
+		p.addDiagnostic(SeverityWarning, DiagnosticUnterminatedComment, p.tagStart, "",
+			"comment is never closed; everything after it was treated as comment")
+		p.finishComment(p.markupBuffer.String())
+	case StateDoctype:
+		p.addDiagnostic(SeverityWarning, DiagnosticUnterminatedMarkup, p.tagStart, "",
+			"DOCTYPE is never closed")
+		p.finishDoctype()
+	case StateCDATA:
+		p.addDiagnostic(SeverityWarning, DiagnosticUnterminatedMarkup, p.tagStart, "",
+			"CDATA section is never closed; everything after it was treated as text")
+		p.finishCDATA(p.markupBuffer.String())
+	case StateProcessingInstruction:
+		p.addDiagnostic(SeverityWarning, DiagnosticUnterminatedMarkup, p.tagStart, "",
+			"processing instruction is never closed; everything after it was dropped")
+		p.markupBuffer.Reset()
+		p.state = StateText
+	}
+}
+
+// lookingAtExact reports whether the input at position starts with s,
+// matching case.
+func (p *StateMachineParser) lookingAtExact(position int, s string) bool {
+	for _, want := range s {
+		if position >= len(p.input) || p.input[position] != want {
+			return false
+		}
+		position++
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package marquee
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// DiagnosticSeverity says how much a parse problem affects the document.
+type DiagnosticSeverity int
+
+const (
+	// SeverityWarning marks markup the parser repaired the way a browser
+	// would, such as an unclosed <b>.
+	SeverityWarning DiagnosticSeverity = iota
+	// SeverityError marks input the parser had to drop or could not
+	// represent, such as content past the depth or length limits.
+	SeverityError
+)
+
+func (s DiagnosticSeverity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("DiagnosticSeverity(%d)", int(s))
+}
+
+// DiagnosticCode identifies the kind of parse problem. The values are stable
+// and safe to match on.
+type DiagnosticCode string
+
+const (
+	DiagnosticUnclosedTag         DiagnosticCode = "unclosed-tag"
+	DiagnosticStrayEndTag         DiagnosticCode = "stray-end-tag"
+	DiagnosticMaxDepthExceeded    DiagnosticCode = "max-depth-exceeded"
+	DiagnosticTruncatedInput      DiagnosticCode = "truncated-input"
+	DiagnosticInvalidTagName      DiagnosticCode = "invalid-tag-name"
+	DiagnosticUnterminatedComment DiagnosticCode = "unterminated-comment"
+	DiagnosticUnterminatedMarkup  DiagnosticCode = "unterminated-markup"
+	DiagnosticBogusComment        DiagnosticCode = "bogus-comment"
+	DiagnosticTooManyErrors       DiagnosticCode = "too-many-errors"
+)
+
+// ParseDiagnostic describes one problem found while parsing. Line and Column
+// are 1-based, Column counts characters, and Offset is the byte offset into
+// the string passed to Parse.
+type ParseDiagnostic struct {
+	Severity DiagnosticSeverity
+	Code     DiagnosticCode
+	Message  string
+	Tag      string
+	Line     int
+	Column   int
+	Offset   int
+}
+
+func (d ParseDiagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s [%s]", d.Line, d.Column, d.Severity, d.Message, d.Code)
+}
+
+// sourceCursor maps positions in the parser's normalized rune input back to
+// the original source. Diagnostics mostly arrive in source order, so the
+// cursor moves forward from the last lookup and only rescans from the start
+// when asked for an earlier position.
+type sourceCursor struct {
+	source   []byte
+	start    int
+	position int
+	offset   int
+	line     int
+	column   int
+}
+
+// reset points the cursor at the first input rune, which is start bytes into
+// the source because leading whitespace is trimmed before parsing.
+func (c *sourceCursor) reset(start int) {
+	*c = sourceCursor{source: c.source, start: start, line: 1, column: 1}
+	for c.offset < start {
+		c.step()
+	}
+}
+
+// step moves past one rune of source, treating "\r\n" and "\r" as the single
+// "\n" they were normalized to.
+func (c *sourceCursor) step() {
+	r, size := utf8.DecodeRune(c.source[c.offset:])
+	c.offset += size
+	if r == '\r' {
+		if c.offset < len(c.source) && c.source[c.offset] == '\n' {
+			c.offset++
+		}
+		r = '\n'
+	}
+
+	if r == '\n' {
+		c.line++
+		c.column = 1
+	} else {
+		c.column++
+	}
+}
+
+// locate returns the byte offset, line and column of rune position in the
+// normalized input.
+func (c *sourceCursor) locate(position int) (offset, line, column int) {
+	if position < c.position {
+		c.reset(c.start)
+	}
+
+	for c.position < position && c.offset < len(c.source) {
+		c.step()
+		c.position++
+	}
+
+	return c.offset, c.line, c.column
+}
+
+// addDiagnostic records a problem at rune position in the input. Once
+// maxErrors diagnostics have been recorded, one final too-many-errors entry
+// is added and later problems are only counted.
+func (p *StateMachineParser) addDiagnostic(severity DiagnosticSeverity, code DiagnosticCode, position int, tag, message string) {
+	p.errorCount++
+	if p.errorCount > p.maxErrors+1 {
+		return
+	}
+	if p.errorCount == p.maxErrors+1 {
+		severity = SeverityError
+		code = DiagnosticTooManyErrors
+		tag = ""
+		message = fmt.Sprintf("more than %d problems, further diagnostics suppressed", p.maxErrors)
+	}
+
+	offset, line, column := p.cursor.locate(position)
+	p.diagnostics = append(p.diagnostics, ParseDiagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  message,
+		Tag:      tag,
+		Line:     line,
+		Column:   column,
+		Offset:   offset,
+	})
+}
+
+// optionalEndTags are the elements whose end tag HTML5 allows to be left
+// out, so closing them implicitly is not worth a diagnostic.
+var optionalEndTags = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true,
+	"dt": true, "dd": true, "rt": true, "rp": true, "optgroup": true,
+	"option": true, "colgroup": true, "caption": true, "thead": true,
+	"tbody": true, "tfoot": true, "tr": true, "td": true, "th": true,
+}
+
+// reportUnclosed records an unclosed-tag diagnostic for every element from
+// stack index from upwards that is about to be closed without its end tag.
+func (p *StateMachineParser) reportUnclosed(from int) {
+	if from < 1 {
+		from = 1
+	}
+	for _, entry := range p.nodeStack[from:] {
+		if optionalEndTags[entry.OriginalTag] {
+			continue
+		}
+		p.addDiagnostic(SeverityWarning, DiagnosticUnclosedTag, entry.Position, entry.OriginalTag,
+			fmt.Sprintf("<%s> is never closed", entry.OriginalTag))
+	}
+}
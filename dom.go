@@ -0,0 +1,254 @@
+package marquee
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNodeNotInDocument is returned when a node passed to an HTMLDocument
+// method is not part of that document, usually because it was looked up
+// before a change that moved it.
+var ErrNodeNotInDocument = errors.New("marquee: node is not in the document")
+
+// The document tree stores children by value, so changing a node's Children
+// can move them in memory. The methods below keep every Parent pointer
+// correct after a change, but a pointer obtained before the change to a
+// child or descendant of the changed node may no longer point into the
+// tree. Look such nodes up again afterwards; the methods return
+// ErrNodeNotInDocument for a node that has gone stale.
+
+// NewElement returns an element node with the given tag, attributes and
+// children, ready to be added with AppendChild.
+func NewElement(tagName string, attributes map[string]string, children ...HTMLNode) HTMLNode {
+	node := HTMLNode{
+		Type:       NodeTypeElement,
+		Tag:        strings.ToLower(tagName),
+		Attributes: make(map[string]string, len(attributes)),
+		Children:   children,
+	}
+	for name, value := range attributes {
+		node.Attributes[strings.ToLower(name)] = value
+	}
+	return node
+}
+
+// NewText returns a text node. The text is used as is; character references
+// in it are not decoded.
+func NewText(text string) HTMLNode {
+	return HTMLNode{Type: NodeTypeText, Content: text, Context: ContextInline}
+}
+
+// SetText replaces the content of a text node, or replaces all children of
+// an element with a single text node.
+func (d *HTMLDocument) SetText(node *HTMLNode, text string) error {
+	if !d.contains(node) {
+		return ErrNodeNotInDocument
+	}
+
+	if node.Type == NodeTypeText {
+		node.Content = text
+	} else {
+		node.Children = []HTMLNode{NewText(text)}
+		node.Children[0].Parent = node
+	}
+
+	d.changed(node)
+	return nil
+}
+
+// SetAttribute sets an attribute of an element. The name is not
+// case-sensitive.
+func (d *HTMLDocument) SetAttribute(node *HTMLNode, name, value string) error {
+	if !d.contains(node) {
+		return ErrNodeNotInDocument
+	}
+
+	if node.Attributes == nil {
+		node.Attributes = make(map[string]string)
+	}
+	node.Attributes[strings.ToLower(name)] = value
+
+	d.changed(node)
+	return nil
+}
+
+// RemoveAttribute removes an attribute of an element, if it has it.
+func (d *HTMLDocument) RemoveAttribute(node *HTMLNode, name string) error {
+	if !d.contains(node) {
+		return ErrNodeNotInDocument
+	}
+
+	delete(node.Attributes, strings.ToLower(name))
+
+	d.changed(node)
+	return nil
+}
+
+// AppendChild adds child, and everything under it, as the last child of
+// parent and returns a pointer to the added node in the tree.
+func (d *HTMLDocument) AppendChild(parent *HTMLNode, child HTMLNode) (*HTMLNode, error) {
+	if !d.contains(parent) {
+		return nil, ErrNodeNotInDocument
+	}
+
+	parent.Children = append(parent.Children, child)
+	added := &parent.Children[len(parent.Children)-1]
+	assignContexts(parent, added)
+	linkParents(parent)
+
+	d.changed(parent)
+	return added, nil
+}
+
+// RemoveNode removes node, and everything under it, from the document.
+func (d *HTMLDocument) RemoveNode(node *HTMLNode) error {
+	if node == &d.Root || !d.contains(node) {
+		return ErrNodeNotInDocument
+	}
+
+	// The remaining children are copied to a new slice rather than shifted
+	// down, so that stale pointers to them are detected instead of pointing
+	// at a different sibling.
+	parent := node.Parent
+	children := make([]HTMLNode, 0, len(parent.Children)-1)
+	for i := range parent.Children {
+		if &parent.Children[i] != node {
+			children = append(children, parent.Children[i])
+		}
+	}
+	parent.Children = children
+	linkParents(parent)
+
+	d.changed(parent)
+	return nil
+}
+
+// ReplaceInnerHTML parses html as the content of node, the way innerHTML
+// does in a browser, and replaces node's children with the result.
+func (d *HTMLDocument) ReplaceInnerHTML(node *HTMLNode, html string) error {
+	if !d.contains(node) {
+		return ErrNodeNotInDocument
+	}
+
+	node.Children = NewStateMachineParser(ParserOptions{}).parseFragment(html, node)
+	linkParents(node)
+
+	d.changed(node)
+	return nil
+}
+
+// parseFragment parses html as if it were the content of an element like
+// context, so whitespace and block or inline context follow the same rules
+// as inside the document.
+func (p *StateMachineParser) parseFragment(html string, context *HTMLNode) []HTMLNode {
+	p.begin()
+	p.root.Type = NodeTypeElement
+	p.root.Tag = context.Tag
+	p.root.Context = context.Context
+	p.nodeStack[0].OriginalTag = context.Tag
+	if context.Type == NodeTypeDocument {
+		p.root.Type = NodeTypeDocument
+	}
+
+	p.write([]byte(html))
+	return p.end().Root.Children
+}
+
+// contains reports whether node is in the document, following its Parent
+// pointers up to the root and checking each step.
+func (d *HTMLDocument) contains(node *HTMLNode) bool {
+	if node == nil {
+		return false
+	}
+	d.linkRoot()
+
+	for node != &d.Root {
+		parent := node.Parent
+		if parent == nil || !hasChild(parent, node) {
+			return false
+		}
+		node = parent
+	}
+	return true
+}
+
+// topLevelIndex returns the index among the children of the root of the one
+// node is, or is under, or -1 when node is the root itself. node must be in
+// the document.
+func (d *HTMLDocument) topLevelIndex(node *HTMLNode) int {
+	for node.Parent != nil && node.Parent != &d.Root {
+		node = node.Parent
+	}
+	for i := range d.Root.Children {
+		if &d.Root.Children[i] == node {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasChild(parent, node *HTMLNode) bool {
+	for i := range parent.Children {
+		if &parent.Children[i] == node {
+			return true
+		}
+	}
+	return false
+}
+
+// linkRoot points the Parent of each of the root's children at the root.
+// A document is returned and passed by value, which moves its root but not
+// the nodes under it, so the methods of the document call this before
+// following Parent pointers or handing out nodes.
+func (d *HTMLDocument) linkRoot() {
+	for i := range d.Root.Children {
+		d.Root.Children[i].Parent = &d.Root
+	}
+}
+
+// linkParents points the Parent of every node under node at its parent.
+func linkParents(node *HTMLNode) {
+	for i := range node.Children {
+		node.Children[i].Parent = node
+		linkParents(&node.Children[i])
+	}
+}
+
+// assignContexts sets the block or inline context of node and everything
+// under it from its parent, as the parser would have.
+func assignContexts(parent, node *HTMLNode) {
+	if node.Type == NodeTypeElement {
+		node.Context = determineContext(node.Tag, parent)
+	} else {
+		node.Context = ContextInline
+	}
+	for i := range node.Children {
+		assignContexts(node, &node.Children[i])
+	}
+}
+
+// changed records that node, or something under it, was changed, and
+// refreshes the metadata in case the change touched the head.
+func (d *HTMLDocument) changed(node *HTMLNode) {
+	d.version++
+	d.changedNodes = append(d.changedNodes, node)
+
+	docType := d.Metadata.DocType
+	d.Metadata = collectMetadata(&d.Root)
+	d.Metadata.DocType = docType
+}
+
+// takeChanges returns the nodes changed since the last call, or reports
+// that the whole document should be treated as changed when one of them is
+// no longer in the tree.
+func (d *HTMLDocument) takeChanges() (nodes []*HTMLNode, all bool) {
+	nodes = d.changedNodes
+	d.changedNodes = nil
+
+	for _, node := range nodes {
+		if !d.contains(node) {
+			return nil, true
+		}
+	}
+	return nodes, false
+}
@@ -0,0 +1,58 @@
+package marquee
+
+import "testing"
+
+// pathToRoot returns the tags of node and its ancestors, following Parent
+// pointers, and whether they lead to the root of doc.
+func pathToRoot(doc *HTMLDocument, node *HTMLNode) ([]string, bool) {
+	var tags []string
+	for ; node != nil; node = node.Parent {
+		if node == &doc.Root {
+			return tags, true
+		}
+		tags = append(tags, node.Tag)
+	}
+	return tags, false
+}
+
+func TestParentsOfParsedDocument(t *testing.T) {
+	doc := parse(t, "<p>one</p><div><p>two <b>three</b></p></div>")
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"p", []string{"p"}},
+		{"div", []string{"div"}},
+		{"b", []string{"b", "p", "div"}},
+	}
+	check := func(doc *HTMLDocument, when string) {
+		t.Helper()
+		for _, test := range tests {
+			node, _ := doc.QuerySelector(test.selector)
+			if got, ok := pathToRoot(doc, node); !ok || !equalStrings(got, test.want) {
+				t.Errorf("%s: path from %s = %v (reaches root: %v), want %v", when, test.selector, got, ok, test.want)
+			}
+		}
+	}
+	check(&doc, "after parsing")
+
+	copied := doc
+	check(&copied, "after copying the document")
+
+	if _, err := doc.AppendChild(&doc.Root, NewElement("p", nil, NewText("four"))); err != nil {
+		t.Fatal(err)
+	}
+	check(&doc, "after appending to the root")
+
+	var top []string
+	doc.Walk(func(node *HTMLNode) WalkAction {
+		if node.Parent == &doc.Root {
+			top = append(top, node.Tag)
+		}
+		return WalkContinue
+	}, nil)
+	if want := []string{"p", "div", "p"}; !equalStrings(top, want) {
+		t.Errorf("Walk found %v whose Parent is the root, want %v", top, want)
+	}
+}
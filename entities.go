@@ -0,0 +1,158 @@
+package marquee
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// windows1252Replacements maps numeric references in the C1 control range
+// (0x80-0x9F) to the characters HTML5 substitutes for them.
+var windows1252Replacements = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+// decodeCharacterReferences replaces named, decimal and hexadecimal character
+// references with the runes they stand for, following the HTML5 tokenizer
+// rules. References that do not resolve are left as written. In attribute
+// values a legacy reference without ';' that is followed by '=' or an
+// alphanumeric character is not decoded, so query strings such as
+// "?a=1&copy=2" survive intact.
+func decodeCharacterReferences(s string, inAttribute bool) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+
+	var out strings.Builder
+	out.Grow(len(s))
+
+	for i := 0; i < len(s); {
+		if s[i] != '&' {
+			out.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		var replacement string
+		var consumed int
+		if i+1 < len(s) && s[i+1] == '#' {
+			replacement, consumed = decodeNumericReference(s[i:])
+		} else {
+			replacement, consumed = decodeNamedReference(s[i:], inAttribute)
+		}
+
+		if consumed == 0 {
+			out.WriteByte('&')
+			i++
+			continue
+		}
+
+		out.WriteString(replacement)
+		i += consumed
+	}
+
+	return out.String()
+}
+
+// decodeNumericReference decodes "&#NNN;" or "&#xHHH;" at the start of s and
+// returns the replacement text and the number of bytes consumed, or 0 if s
+// does not start with a numeric reference.
+func decodeNumericReference(s string) (string, int) {
+	i := 2
+	hex := false
+	if i < len(s) && (s[i] == 'x' || s[i] == 'X') {
+		hex = true
+		i++
+	}
+
+	start := i
+	value := rune(0)
+	for i < len(s) {
+		c := s[i]
+		var digit rune
+		switch {
+		case '0' <= c && c <= '9':
+			digit = rune(c - '0')
+		case hex && 'a' <= c && c <= 'f':
+			digit = rune(c-'a') + 10
+		case hex && 'A' <= c && c <= 'F':
+			digit = rune(c-'A') + 10
+		default:
+			digit = -1
+		}
+		if digit < 0 {
+			break
+		}
+
+		if value <= utf8.MaxRune {
+			if hex {
+				value = value*16 + digit
+			} else {
+				value = value*10 + digit
+			}
+		}
+		i++
+	}
+
+	if i == start {
+		return "", 0
+	}
+
+	if i < len(s) && s[i] == ';' {
+		i++
+	}
+
+	switch {
+	case value == 0 || value > utf8.MaxRune || (0xD800 <= value && value <= 0xDFFF):
+		value = utf8.RuneError
+	case 0x80 <= value && value <= 0x9F:
+		value = windows1252Replacements[value-0x80]
+	}
+
+	return string(value), i
+}
+
+// decodeNamedReference decodes the longest named reference at the start of s
+// and returns the replacement text and the number of bytes consumed, or 0 if
+// no entity name matches.
+func decodeNamedReference(s string, inAttribute bool) (string, int) {
+	end := 1
+	for end < len(s) && isASCIIAlphanumeric(s[end]) {
+		end++
+	}
+	name := s[1:end]
+	if name == "" {
+		return "", 0
+	}
+
+	if end < len(s) && s[end] == ';' {
+		if value, ok := namedEntities[name+";"]; ok {
+			return value, end + 1
+		}
+	}
+
+	maxLen := len(name)
+	if maxLen > longestLegacyEntity {
+		maxLen = longestLegacyEntity
+	}
+	for n := maxLen; n > 0; n-- {
+		value, ok := namedEntities[name[:n]]
+		if !ok {
+			continue
+		}
+
+		next := 1 + n
+		if inAttribute && next < len(s) && (s[next] == '=' || isASCIIAlphanumeric(s[next])) {
+			return "", 0
+		}
+		return value, next
+	}
+
+	return "", 0
+}
+
+func isASCIIAlphanumeric(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
@@ -24,6 +24,18 @@ type HTMLViewApp struct {
 	fileList        []string
 	selectedFileIdx int
 	searchPath      string
+	diagnosticIdx   int
+	themeIdx        int
+}
+
+// Themes cycled through with F7
+var themes = []struct {
+	name  string
+	theme func() marquee.Theme
+}{
+	{"Light", marquee.LightTheme},
+	{"Dark", marquee.DarkTheme},
+	{"High contrast", marquee.HighContrastTheme},
 }
 
 // Get list of HTML files in current directory
@@ -99,37 +111,17 @@ func (app *HTMLViewApp) loadFile(filename string) {
 			<p>Press <b>Ctrl+O</b> to open a different file or drag & drop an HTML file</p>
 		`, filename, err.Error())
 		
-		if app.widget != nil {
-			app.widget.Unload()
-		}
-		app.widget = marquee.NewHTMLWidget(errorHTML)
+		app.showHTML(errorHTML, marquee.RestoreTop)
 		app.statusMessage = fmt.Sprintf("Error: %s", err.Error())
 		return
 	}
 	
-	// Create widget with HTML content
-	if app.widget != nil {
-		app.widget.Unload()
-	}
-	app.widget = marquee.NewHTMLWidget(string(content))
-	
-	// Set up link click handler for external URLs
-	app.widget.OnLinkClick = func(url string) {
-		fmt.Printf("Link clicked: %s\n", url) // Debug: see if callback is called
-		if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
-			fmt.Printf("Opening external URL: %s\n", url) // Debug: confirm URL detection
-			err := openURL(url)
-			if err != nil {
-				fmt.Printf("Error opening URL: %v\n", err) // Debug: show errors
-				app.statusMessage = fmt.Sprintf("Error opening URL: %s", err.Error())
-			} else {
-				fmt.Printf("Successfully launched browser\n") // Debug: confirm success
-				app.statusMessage = fmt.Sprintf("Opened in browser: %s", url)
-			}
-		} else {
-			app.statusMessage = fmt.Sprintf("Local link clicked: %s", url)
-		}
+	// Keep the reading position when the same file is reloaded
+	restore := marquee.RestoreTop
+	if filename == app.currentFile {
+		restore = marquee.RestoreByAnchor
 	}
+	app.showHTML(string(content), restore)
 	
 	// Update tracking info
 	app.currentFile = filename
@@ -139,6 +131,67 @@ func (app *HTMLViewApp) loadFile(filename string) {
 	}
 	
 	app.statusMessage = fmt.Sprintf("Loaded: %s (%d bytes)", filepath.Base(filename), len(content))
+	app.diagnosticIdx = 0
+}
+
+// Show HTML content, creating the widget the first time and replacing its
+// content in place afterwards
+func (app *HTMLViewApp) showHTML(html string, restore marquee.ScrollRestore) {
+	if app.widget == nil {
+		app.widget = marquee.NewHTMLWidget(html)
+		app.widget.OnLinkClick = app.handleLinkClick
+		return
+	}
+	app.widget.ScrollRestore = restore
+	app.widget.SetContent(html)
+}
+
+// Handle a click on a link in the document
+func (app *HTMLViewApp) handleLinkClick(url string) {
+	fmt.Printf("Link clicked: %s\n", url) // Debug: see if callback is called
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		fmt.Printf("Opening external URL: %s\n", url) // Debug: confirm URL detection
+		err := openURL(url)
+		if err != nil {
+			fmt.Printf("Error opening URL: %v\n", err) // Debug: show errors
+			app.statusMessage = fmt.Sprintf("Error opening URL: %s", err.Error())
+		} else {
+			fmt.Printf("Successfully launched browser\n") // Debug: confirm success
+			app.statusMessage = fmt.Sprintf("Opened in browser: %s", url)
+		}
+	} else if strings.HasPrefix(url, "#") {
+		if !app.widget.ScrollToAnchor(url[1:]) {
+			app.statusMessage = fmt.Sprintf("No anchor named: %s", url[1:])
+		}
+	} else {
+		app.statusMessage = fmt.Sprintf("Local link clicked: %s", url)
+	}
+}
+
+// Render the parse diagnostic currently selected with F6, if there are any
+func (app *HTMLViewApp) renderDiagnostics(y float32) {
+	if app.widget == nil {
+		return
+	}
+	
+	diagnostics := app.widget.Diagnostics()
+	if len(diagnostics) == 0 {
+		rl.DrawText("No parse problems", 10, int32(y), 10, rl.DarkGreen)
+		return
+	}
+	
+	if app.diagnosticIdx >= len(diagnostics) {
+		app.diagnosticIdx = 0
+	}
+	d := diagnostics[app.diagnosticIdx]
+	
+	color := rl.Orange
+	if d.Severity == marquee.SeverityError {
+		color = rl.Red
+	}
+	
+	text := fmt.Sprintf("[%d/%d] %s (F6: next)", app.diagnosticIdx+1, len(diagnostics), d.String())
+	rl.DrawText(text, 10, int32(y), 10, color)
 }
 
 // Render file selection dialog
@@ -250,29 +303,12 @@ func main() {
 				<li>Press <b>Ctrl+O</b> to open an HTML file</li>
 				<li>Or drag & drop an <i>.html</i> file into this window</li>
 				<li>Press <b>F5</b> to refresh the current file</li>
+				<li>Press <b>F7</b> to switch between light, dark and high-contrast themes</li>
 				<li>Press <b>Esc</b> to quit</li>
 			</ol>
 			<p>Perfect for viewing documentation, help files, or any simple HTML content!</p>
 		`
-		app.widget = marquee.NewHTMLWidget(welcomeHTML)
-		
-		// Set up link click handler for welcome screen too
-		app.widget.OnLinkClick = func(url string) {
-			fmt.Printf("Welcome screen link clicked: %s\n", url) // Debug
-			if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
-				fmt.Printf("Opening external URL from welcome: %s\n", url) // Debug
-				err := openURL(url)
-				if err != nil {
-					fmt.Printf("Error opening URL: %v\n", err) // Debug
-					app.statusMessage = fmt.Sprintf("Error opening URL: %s", err.Error())
-				} else {
-					fmt.Printf("Successfully launched browser from welcome\n") // Debug
-					app.statusMessage = fmt.Sprintf("Opened in browser: %s", url)
-				}
-			} else {
-				app.statusMessage = fmt.Sprintf("Local link clicked: %s", url)
-			}
-		}
+		app.showHTML(welcomeHTML, marquee.RestoreTop)
 	}
 	
 	defer func() {
@@ -305,6 +341,20 @@ func main() {
 			}
 		}
 		
+		if rl.IsKeyPressed(rl.KeyF6) && app.widget != nil {
+			// Show the next parse diagnostic in the status bar
+			if count := len(app.widget.Diagnostics()); count > 0 {
+				app.diagnosticIdx = (app.diagnosticIdx + 1) % count
+			}
+		}
+		
+		if rl.IsKeyPressed(rl.KeyF7) && app.widget != nil {
+			// Switch to the next theme
+			app.themeIdx = (app.themeIdx + 1) % len(themes)
+			app.widget.SetTheme(themes[app.themeIdx].theme())
+			app.statusMessage = fmt.Sprintf("Theme: %s", themes[app.themeIdx].name)
+		}
+		
 		if rl.IsKeyPressed(rl.KeyEscape) {
 			if app.showFileDialog {
 				app.showFileDialog = false
@@ -357,12 +407,15 @@ func main() {
 		rl.DrawLine(0, int32(statusBarY), 900, int32(statusBarY), rl.Gray)
 		
 		// Status text
-		rl.DrawText(app.statusMessage, 10, int32(statusBarY+15), 10, rl.DarkGray)
+		rl.DrawText(app.statusMessage, 10, int32(statusBarY+12), 10, rl.DarkGray)
+		
+		// Parse diagnostics for the current document
+		app.renderDiagnostics(statusBarY + 30)
 		
 		// Keyboard shortcuts hint
-		hintsText := "Ctrl+O: Open | F5: Refresh | Esc: Quit | Drag & Drop supported"
+		hintsText := "Ctrl+O: Open | F5: Refresh | F7: Theme | Esc: Quit | Drag & Drop supported"
 		hintsWidth := rl.MeasureText(hintsText, 10)
-		rl.DrawText(hintsText, 900-hintsWidth-10, int32(statusBarY+15), 10, rl.DarkGray)
+		rl.DrawText(hintsText, 900-hintsWidth-10, int32(statusBarY+12), 10, rl.DarkGray)
 		
 		// Render file dialog on top
 		if app.showFileDialog {
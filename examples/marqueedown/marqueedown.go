@@ -202,10 +202,7 @@ func (app *MarqueeDownApp) loadFile(filename string) {
 			<p>Press <b>Ctrl+O</b> to open a different file</p>
 		`, filename, err.Error())
 		
-		if app.widget != nil {
-			app.widget.Unload()
-		}
-		app.widget = marquee.NewHTMLWidget(errorHTML)
+		app.showHTML(errorHTML, marquee.RestoreTop)
 		app.statusMessage = fmt.Sprintf("Error: %s", err.Error())
 		return
 	}
@@ -213,30 +210,12 @@ func (app *MarqueeDownApp) loadFile(filename string) {
 	// Convert markdown to HTML
 	htmlContent := markdownToHTML(string(content))
 	
-	// Update widget
-	if app.widget != nil {
-		app.widget.Unload()
-	}
-	app.widget = marquee.NewHTMLWidget(htmlContent)
-	
-	// IMPORTANT: Set up link click handler for EVERY new widget
-	app.widget.OnLinkClick = func(url string) {
-		// Check if it's a local file (not a web URL)
-		if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
-			fmt.Printf("External link clicked: %s\n", url)
-			return
-		}
-		
-		// Try to load as local markdown file
-		if _, err := os.Stat(url); err == nil {
-			// File exists, load it
-			app.loadFile(url)
-			app.statusMessage = fmt.Sprintf("Navigated to: %s", url)
-		} else {
-			// File doesn't exist
-			app.statusMessage = fmt.Sprintf("Local file not found: %s", url)
-		}
+	// Keep the reading position when the same file is reloaded
+	restore := marquee.RestoreTop
+	if filename == app.currentFile {
+		restore = marquee.RestoreByAnchor
 	}
+	app.showHTML(htmlContent, restore)
 	
 	// Update tracking info
 	app.currentFile = filename
@@ -248,6 +227,50 @@ func (app *MarqueeDownApp) loadFile(filename string) {
 	app.statusMessage = fmt.Sprintf("Loaded: %s (%d bytes)", filepath.Base(filename), len(content))
 }
 
+// Show HTML content, creating the widget the first time and replacing its
+// content in place afterwards
+func (app *MarqueeDownApp) showHTML(html string, restore marquee.ScrollRestore) {
+	if app.widget == nil {
+		app.widget = marquee.NewHTMLWidget(html)
+		app.widget.OnLinkClick = app.handleLinkClick
+		return
+	}
+	app.widget.ScrollRestore = restore
+	app.widget.SetContent(html)
+}
+
+// Handle a click on a link in the document
+func (app *MarqueeDownApp) handleLinkClick(url string) {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		fmt.Printf("External link clicked: %s\n", url)
+	} else if strings.HasPrefix(url, "#") {
+		app.widget.ScrollToAnchor(url[1:])
+	} else if _, err := os.Stat(url); err == nil {
+		app.loadFile(url)
+		app.statusMessage = fmt.Sprintf("Navigated to: %s", url)
+	} else {
+		app.statusMessage = fmt.Sprintf("Local file not found: %s", url)
+	}
+}
+
+// Save the converted document as HTML next to the Markdown file
+func (app *MarqueeDownApp) saveHTML() {
+	if app.currentFile == "" || app.widget == nil {
+		app.statusMessage = "Nothing to save - open a markdown file first"
+		return
+	}
+	
+	outputFile := strings.TrimSuffix(app.currentFile, filepath.Ext(app.currentFile)) + ".html"
+	html := app.widget.GetDocument().Serialize(marquee.SerializeOptions{})
+	
+	if err := os.WriteFile(outputFile, []byte(html+"\n"), 0644); err != nil {
+		app.statusMessage = fmt.Sprintf("Error saving HTML: %s", err.Error())
+		return
+	}
+	
+	app.statusMessage = fmt.Sprintf("Saved HTML: %s", filepath.Base(outputFile))
+}
+
 // Render file selection dialog
 func (app *MarqueeDownApp) renderFileDialog() {
 	// Semi-transparent overlay
@@ -336,6 +359,7 @@ func main() {
 			<ul>
 				<li>Press <b>Ctrl+O</b> to open a markdown file</li>
 				<li>Press <b>F5</b> to refresh the current file</li>
+				<li>Press <b>Ctrl+S</b> to save the converted HTML</li>
 				<li>Press <b>Esc</b> to quit</li>
 			</ul>
 			<h3>Features</h3>
@@ -347,19 +371,7 @@ func main() {
 			</ul>
 			<p>Drop a <i>.md</i> file here or use <b>Ctrl+O</b> to browse files!</p>
 		`
-		app.widget = marquee.NewHTMLWidget(welcomeHTML)
-		
-		// Set up link click handler for welcome screen too
-		app.widget.OnLinkClick = func(url string) {
-			if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
-				fmt.Printf("External link clicked: %s\n", url)
-			} else if _, err := os.Stat(url); err == nil {
-				app.loadFile(url)
-				app.statusMessage = fmt.Sprintf("Navigated to: %s", url)
-			} else {
-				app.statusMessage = fmt.Sprintf("Local file not found: %s", url)
-			}
-		}
+		app.showHTML(welcomeHTML, marquee.RestoreTop)
 	}
 	
 	defer func() {
@@ -385,6 +397,10 @@ func main() {
 					app.statusMessage = "No markdown files found in current directory"
 				}
 			}
+			if rl.IsKeyPressed(rl.KeyS) {
+				// Save the converted HTML
+				app.saveHTML()
+			}
 		}
 		
 		if rl.IsKeyPressed(rl.KeyF5) {
@@ -449,7 +465,7 @@ func main() {
 		rl.DrawText(app.statusMessage, 10, int32(statusBarY+15), 10, rl.DarkGray)
 		
 		// Keyboard shortcuts hint
-		hintsText := "Ctrl+O: Open | F5: Refresh | Esc: Quit"
+		hintsText := "Ctrl+O: Open | Ctrl+S: Save HTML | F5: Refresh | Esc: Quit"
 		hintsWidth := rl.MeasureText(hintsText, 10)
 		rl.DrawText(hintsText, 900-hintsWidth-10, int32(statusBarY+15), 10, rl.DarkGray)
 		
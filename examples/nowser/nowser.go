@@ -8,6 +8,7 @@
 package main
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
@@ -455,6 +456,55 @@ type Tab struct {
 	PendingTitle  string  // Title waiting to be processed on main thread
 	PendingURL    string  // URL waiting to be processed on main thread
 	HasPending    bool    // Flag to indicate pending content
+
+	PendingStream *marquee.StreamParser // Page still arriving, shown while it loads
+	Streaming     bool                  // Widget is showing a page that is still loading
+	StreamError   string                // Set if the connection failed part way through
+
+	// mutex guards the Pending fields, HasPending and StreamError, which
+	// the goroutine loading a page sets while the main thread reads them
+	mutex sync.Mutex
+}
+
+// setPending hands a page to the main thread, which shows it on the next
+// frame. It may be called from any goroutine.
+func (tab *Tab) setPending(html, title, pageURL string, stream *marquee.StreamParser) {
+	tab.mutex.Lock()
+	defer tab.mutex.Unlock()
+	tab.PendingHTML = html
+	tab.PendingTitle = title
+	tab.PendingURL = pageURL
+	tab.PendingStream = stream
+	tab.HasPending = true
+}
+
+// takePending returns the page handed to the main thread since the last
+// call, if there is one.
+func (tab *Tab) takePending() (html, title, pageURL string, stream *marquee.StreamParser, ok bool) {
+	tab.mutex.Lock()
+	defer tab.mutex.Unlock()
+	if !tab.HasPending {
+		return "", "", "", nil, false
+	}
+	html, title, pageURL, stream = tab.PendingHTML, tab.PendingTitle, tab.PendingURL, tab.PendingStream
+	tab.PendingHTML, tab.PendingStream, tab.HasPending = "", nil, false
+	return html, title, pageURL, stream, true
+}
+
+// setStreamError records why a page stopped arriving part way through.
+func (tab *Tab) setStreamError(message string) {
+	tab.mutex.Lock()
+	defer tab.mutex.Unlock()
+	tab.StreamError = message
+}
+
+// takeStreamError returns and clears the error set by setStreamError.
+func (tab *Tab) takeStreamError() string {
+	tab.mutex.Lock()
+	defer tab.mutex.Unlock()
+	message := tab.StreamError
+	tab.StreamError = ""
+	return message
 }
 
 // BrowserApp represents the main browser application
@@ -902,6 +952,11 @@ func (tab *Tab) setupLinkHandler(app *BrowserApp) {
 	}
 
 	tab.Widget.OnLinkClick = func(clickedURL string) {
+		// Links within the page only move the view
+		if strings.HasPrefix(clickedURL, "#") {
+			tab.Widget.ScrollToAnchor(clickedURL[1:])
+			return
+		}
 		if !strings.HasPrefix(clickedURL, "http://") && !strings.HasPrefix(clickedURL, "https://") && !strings.HasPrefix(clickedURL, "file://") {
 			if tab.URL != "" {
 				base, err := url.Parse(tab.URL)
@@ -948,11 +1003,6 @@ func (app *BrowserApp) currentTab() *Tab {
 
 // Simple HTML sanitizer and converter
 func sanitizeAndConvertHTML(html string) string {
-	scriptRe := regexp.MustCompile(`(?i)<script[^>]*>.*?</script>`)
-	html = scriptRe.ReplaceAllString(html, "")
-	styleRe := regexp.MustCompile(`(?i)<style[^>]*>.*?</style>`)
-	html = styleRe.ReplaceAllString(html, "")
-
 	unsupportedTags := []string{"div", "span", "section", "article", "nav", "header", "footer", "main", "aside", "meta", "link"}
 	for _, tag := range unsupportedTags {
 		openRe := regexp.MustCompile(fmt.Sprintf(`(?i)<%s[^>]*>`, tag))
@@ -961,21 +1011,6 @@ func sanitizeAndConvertHTML(html string) string {
 		html = closeRe.ReplaceAllString(html, "")
 	}
 
-	entities := map[string]string{
-		"&amp;":    "&",
-		"&lt;":     "<",
-		"&gt;":     ">",
-		"&quot;":   "\"",
-		"&nbsp;":   " ",
-		"&mdash;":  "â€”", // EM DASH (long dash)
-		"&ndash;":  "â€“", // EN DASH (medium dash)
-		"&hellip;": "â€¦", // HORIZONTAL ELLIPSIS (three dots)
-	}
-
-	for entity, replacement := range entities {
-		html = strings.ReplaceAll(html, entity, replacement)
-	}
-
 	bodyRe := regexp.MustCompile(`(?i)<body[^>]*>(.*?)</body>`)
 	if matches := bodyRe.FindStringSubmatch(html); len(matches) > 1 {
 		html = matches[1]
@@ -986,23 +1021,43 @@ func sanitizeAndConvertHTML(html string) string {
 
 // Extract page title from HTML
 func extractTitle(html string) string {
-	titleRe := regexp.MustCompile(`(?i)<title[^>]*>(.*?)</title>`)
-	if matches := titleRe.FindStringSubmatch(html); len(matches) > 1 {
-		title := strings.TrimSpace(matches[1])
-		if title != "" {
-			return title
-		}
+	metadata := marquee.NewStateMachineParser(marquee.ParserOptions{}).Parse(html).Metadata
+	if metadata.Title != "" {
+		return metadata.Title
 	}
 	return "Untitled"
 }
 
-// NEW: Enhanced HTTP client with caching
-func (app *BrowserApp) fetchWithCache(targetURL string) ([]byte, string, error) {
+// cachingReader passes a response body through and stores it in the cache
+// once it has been read to the end
+type cachingReader struct {
+	body   io.ReadCloser
+	buffer bytes.Buffer
+	store  func(data []byte)
+}
+
+func (cr *cachingReader) Read(p []byte) (int, error) {
+	n, err := cr.body.Read(p)
+	cr.buffer.Write(p[:n])
+	if err == io.EOF && cr.store != nil {
+		cr.store(cr.buffer.Bytes())
+		cr.store = nil
+	}
+	return n, err
+}
+
+func (cr *cachingReader) Close() error {
+	return cr.body.Close()
+}
+
+// NEW: Enhanced HTTP client with caching. The page is returned as a reader
+// so it can be parsed while it downloads; it is cached once fully read.
+func (app *BrowserApp) fetchWithCache(targetURL string) (io.ReadCloser, error) {
 	// Check cache first
 	if entry, found := app.cacheManager.Get(targetURL, ResourceTypePage); found {
 		// Check if cache entry is still fresh (1 hour for pages)
 		if time.Since(entry.CachedAt) < time.Hour {
-			return entry.Data, entry.ContentType, nil
+			return io.NopCloser(bytes.NewReader(entry.Data)), nil
 		}
 	}
 
@@ -1013,17 +1068,12 @@ func (app *BrowserApp) fetchWithCache(targetURL string) ([]byte, string, error)
 
 	resp, err := client.Get(targetURL)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, "", err
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
 
 	// Extract content type
@@ -1032,7 +1082,6 @@ func (app *BrowserApp) fetchWithCache(targetURL string) ([]byte, string, error)
 		contentType = "text/html"
 	}
 
-	// Store in cache
 	headers := make(map[string]string)
 	for key, values := range resp.Header {
 		if len(values) > 0 {
@@ -1040,9 +1089,12 @@ func (app *BrowserApp) fetchWithCache(targetURL string) ([]byte, string, error)
 		}
 	}
 
-	app.cacheManager.Store(targetURL, body, contentType, headers, ResourceTypePage)
-
-	return body, contentType, nil
+	return &cachingReader{
+		body: resp.Body,
+		store: func(data []byte) {
+			app.cacheManager.Store(targetURL, data, contentType, headers, ResourceTypePage)
+		},
+	}, nil
 }
 
 // Load URL in current tab (SIGNATURE UNCHANGED)
@@ -1070,23 +1122,28 @@ func (app *BrowserApp) loadURL(targetURL string) {
 	tab.StatusMessage = "Loading..."
 	tab.AddressBar = targetURL
 
+	// The goroutine only touches the tab through setPending and
+	// setStreamError; the main thread clears Loading once it has the page
 	go func() {
 		// NEW: Use cached HTTP fetching
-		body, _, err := app.fetchWithCache(targetURL)
+		body, err := app.fetchWithCache(targetURL)
 		if err != nil {
-			app.handleLoadError(tab, fmt.Sprintf("Failed to load: %s", err.Error()))
+			tab.setPending(loadErrorHTML(targetURL, fmt.Sprintf("Failed to load: %s", err.Error())), "Error", "", nil)
 			return
 		}
+		defer body.Close()
 
-		html := string(body)
-		title := extractTitle(html)
-		sanitizedHTML := sanitizeAndConvertHTML(html)
+		// Hand the page to the main thread straight away and keep parsing
+		// as it arrives; the widget shows what has been read so far. Pages
+		// are not passed through sanitizeAndConvertHTML, which needs the
+		// whole page, since the widget lays out the tags it removes.
+		stream := marquee.NewStreamParser(marquee.DefaultParserOptions())
+		tab.setPending("", "Loading...", targetURL, stream)
 
-		tab.PendingHTML = sanitizedHTML
-		tab.PendingTitle = title
-		tab.PendingURL = targetURL
-		tab.HasPending = true
-		tab.Loading = false
+		if _, err := stream.ReadFrom(body); err != nil {
+			tab.setStreamError(err.Error())
+		}
+		stream.Close()
 	}()
 }
 
@@ -1117,16 +1174,19 @@ func (app *BrowserApp) loadLocalFile(fileURL string) {
 
 	sanitizedHTML := sanitizeAndConvertHTML(html)
 
-	tab.PendingHTML = sanitizedHTML
-	tab.PendingTitle = title
-	tab.PendingURL = fileURL
-	tab.HasPending = true
+	tab.setPending(sanitizedHTML, title, fileURL, nil)
 	tab.Loading = false
 }
 
 // Handle loading errors (SIGNATURE UNCHANGED)
 func (app *BrowserApp) handleLoadError(tab *Tab, errorMsg string) {
-	errorHTML := fmt.Sprintf(`
+	tab.setPending(loadErrorHTML(tab.AddressBar, errorMsg), "Error", "", nil)
+	tab.Loading = false
+}
+
+// loadErrorHTML returns the page shown when address could not be loaded
+func loadErrorHTML(address, errorMsg string) string {
+	return fmt.Sprintf(`
 		<h1>Failed to Load Page</h1>
 		<p><b>URL:</b> %s</p>
 		<p><b>Error:</b> %s</p>
@@ -1139,13 +1199,7 @@ func (app *BrowserApp) handleLoadError(tab *Tab, errorMsg string) {
 			<li>Some sites may block minimal browsers</li>
 		</ul>
 		<p><i>Remember: Nowser is designed for simplicity, not complexity</i></p>
-	`, tab.AddressBar, errorMsg)
-
-	tab.PendingHTML = errorHTML
-	tab.PendingTitle = "Error"
-	tab.PendingURL = ""
-	tab.HasPending = true
-	tab.Loading = false
+	`, address, errorMsg)
 }
 
 // Handle file drops (SIGNATURE UNCHANGED)
@@ -1648,25 +1702,62 @@ func (app *BrowserApp) update() {
 
 	// Process pending content updates
 	for _, tab := range app.tabs {
-		if tab.HasPending {
-			if tab.Widget != nil {
-				tab.Widget.Unload()
+		if html, title, pageURL, stream, ok := tab.takePending(); ok {
+			// A new page replaces the content of the tab's widget, starting
+			// at the top, so fonts are only loaded once per tab
+			switch {
+			case tab.Widget == nil && stream != nil:
+				tab.Widget = marquee.NewHTMLWidgetFromStream(stream)
+				tab.setupLinkHandler(app)
+			case tab.Widget == nil:
+				tab.Widget = marquee.NewHTMLWidget(html)
+				tab.setupLinkHandler(app)
+			case stream != nil:
+				tab.Widget.ScrollRestore = marquee.RestoreTop
+				tab.Widget.SetStream(stream)
+			default:
+				tab.Widget.ScrollRestore = marquee.RestoreTop
+				tab.Widget.SetContent(html)
 			}
-			tab.Widget = marquee.NewHTMLWidget(tab.PendingHTML)
-			tab.setupLinkHandler(app)
+			tab.Streaming = stream != nil
+			tab.Loading = tab.Streaming
 
-			tab.URL = tab.PendingURL
-			tab.Title = tab.PendingTitle
+			tab.URL = pageURL
+			tab.Title = title
 
-			if tab.PendingURL != "" && (len(app.history) == 0 || app.history[len(app.history)-1] != tab.PendingURL) {
-				app.history = append(app.history, tab.PendingURL)
+			if pageURL != "" && (len(app.history) == 0 || app.history[len(app.history)-1] != pageURL) {
+				app.history = append(app.history, pageURL)
 				app.historyIndex = len(app.history) - 1
 			}
 
-			elapsed := time.Since(tab.LoadingStart)
-			tab.StatusMessage = fmt.Sprintf("Loaded in %dms", elapsed.Milliseconds())
-			tab.HasPending = false
-			app.saveSession()
+			if !tab.Streaming {
+				elapsed := time.Since(tab.LoadingStart)
+				tab.StatusMessage = fmt.Sprintf("Loaded in %dms", elapsed.Milliseconds())
+				app.saveSession()
+			}
+		}
+
+		// Pages that are still arriving take their title from the
+		// document as soon as it has been read
+		if tab.Streaming && tab.Widget != nil {
+			loading := tab.Widget.Loading()
+			if title := tab.Widget.Title(); title != "" {
+				tab.Title = title
+			}
+			if !loading {
+				if tab.Title == "Loading..." {
+					tab.Title = "Untitled"
+				}
+				elapsed := time.Since(tab.LoadingStart)
+				if streamError := tab.takeStreamError(); streamError != "" {
+					tab.StatusMessage = fmt.Sprintf("Load interrupted after %dms: %s", elapsed.Milliseconds(), streamError)
+				} else {
+					tab.StatusMessage = fmt.Sprintf("Loaded in %dms", elapsed.Milliseconds())
+				}
+				tab.Streaming = false
+				tab.Loading = false
+				app.saveSession()
+			}
 		}
 	}
 
@@ -2,12 +2,14 @@ package marquee
 
 import (
 	"fmt"
+	"os"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
+	"golang.org/x/image/font/sfnt"
 )
 
 type GlobalFontManager struct {
@@ -18,6 +20,10 @@ type GlobalFontManager struct {
 	monoFontPaths map[string]string
 	initialized   bool
 
+	// loadedPaths are the files the fonts in use were loaded from, by key.
+	// Fonts that fell back to raylib's default have none.
+	loadedPaths map[string]string
+
 	fontStatus map[string]bool
 }
 
@@ -31,6 +37,7 @@ func getFontManager() *GlobalFontManager {
 			refCounts:     make(map[string]int),
 			fontPaths:     make(map[string]string),
 			monoFontPaths: make(map[string]string),
+			loadedPaths:   make(map[string]string),
 			fontStatus:    make(map[string]bool),
 		}
 		fontManager.initializePlatformPaths()
@@ -43,6 +50,7 @@ func (fm *GlobalFontManager) initializePlatformPaths() {
 		fm.fontPaths["arial"] = "/System/Library/Fonts/Supplemental/Arial.ttf"
 		fm.fontPaths["arial-bold"] = "/System/Library/Fonts/Supplemental/Arial Bold.ttf"
 		fm.fontPaths["arial-italic"] = "/System/Library/Fonts/Supplemental/Arial Italic.ttf"
+		fm.fontPaths["arial-bold-italic"] = "/System/Library/Fonts/Supplemental/Arial Bold Italic.ttf"
 		fm.monoFontPaths["monaco"] = "/System/Library/Fonts/Monaco.ttf"
 		fm.monoFontPaths["menlo"] = "/System/Library/Fonts/Menlo.ttc"
 		fm.monoFontPaths["courier"] = "/System/Library/Fonts/Courier.ttc"
@@ -50,6 +58,7 @@ func (fm *GlobalFontManager) initializePlatformPaths() {
 		fm.fontPaths["arial"] = "C:/Windows/Fonts/arial.ttf"
 		fm.fontPaths["arial-bold"] = "C:/Windows/Fonts/arialbd.ttf"
 		fm.fontPaths["arial-italic"] = "C:/Windows/Fonts/ariali.ttf"
+		fm.fontPaths["arial-bold-italic"] = "C:/Windows/Fonts/arialbi.ttf"
 		fm.monoFontPaths["consolas"] = "C:/Windows/Fonts/consola.ttf"
 		fm.monoFontPaths["cascadia"] = "C:/Windows/Fonts/CascadiaCode.ttf"
 		fm.monoFontPaths["courier"] = "C:/Windows/Fonts/cour.ttf"
@@ -58,6 +67,7 @@ func (fm *GlobalFontManager) initializePlatformPaths() {
 		fm.fontPaths["arial"] = "/usr/share/fonts/truetype/liberation/LiberationSans-Regular.ttf"
 		fm.fontPaths["arial-bold"] = "/usr/share/fonts/truetype/liberation/LiberationSans-Bold.ttf"
 		fm.fontPaths["arial-italic"] = "/usr/share/fonts/truetype/liberation/LiberationSans-Italic.ttf"
+		fm.fontPaths["arial-bold-italic"] = "/usr/share/fonts/truetype/liberation/LiberationSans-BoldItalic.ttf"
 		fm.monoFontPaths["dejavu-mono"] = "/usr/share/fonts/truetype/dejavu/DejaVuSansMono.ttf"
 		fm.monoFontPaths["liberation-mono"] = "/usr/share/fonts/truetype/liberation/LiberationMono-Regular.ttf"
 		fm.monoFontPaths["ubuntu-mono"] = "/usr/share/fonts/truetype/ubuntu/UbuntuMono-R.ttf"
@@ -66,6 +76,53 @@ func (fm *GlobalFontManager) initializePlatformPaths() {
 	fm.initialized = true
 }
 
+// monospaceFontOrder lists the monospaced fonts of the platform in the order
+// they are tried.
+func monospaceFontOrder() []string {
+	if runtime.GOOS == "darwin" {
+		return []string{"monaco", "menlo", "sf-mono", "courier"}
+	} else if runtime.GOOS == "windows" {
+		return []string{"consolas", "cascadia", "courier", "lucida-console"}
+	}
+	return []string{"dejavu-mono", "liberation-mono", "ubuntu-mono", "courier"}
+}
+
+// systemFonts reads the font files the widget draws with: the four faces of
+// Arial or its stand-in, and the first monospaced font in the platform's
+// order that can be read. It reports false when the regular face cannot be.
+func (fm *GlobalFontManager) systemFonts() (TTFFonts, bool) {
+	read := func(path string) []byte {
+		if path == "" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		// A collection such as Menlo.ttc is read as its first font.
+		if data, err = singleFont(data); err != nil {
+			return nil
+		}
+		if _, err := sfnt.Parse(data); err != nil {
+			return nil
+		}
+		return data
+	}
+
+	fonts := TTFFonts{
+		Regular:    read(fm.fontPaths["arial"]),
+		Bold:       read(fm.fontPaths["arial-bold"]),
+		Italic:     read(fm.fontPaths["arial-italic"]),
+		BoldItalic: read(fm.fontPaths["arial-bold-italic"]),
+	}
+	for _, name := range monospaceFontOrder() {
+		if fonts.Monospace = read(fm.monoFontPaths[name]); fonts.Monospace != nil {
+			break
+		}
+	}
+	return fonts, fonts.Regular != nil
+}
+
 func (fm *GlobalFontManager) GetMonospaceFont(size int32) rl.Font {
 	key := fmt.Sprintf("monospace:%d", size)
 
@@ -87,24 +144,16 @@ func (fm *GlobalFontManager) GetMonospaceFont(size int32) rl.Font {
 		return font
 	}
 
-	var fontOrder []string
-	if runtime.GOOS == "darwin" {
-		fontOrder = []string{"monaco", "menlo", "sf-mono", "courier"}
-	} else if runtime.GOOS == "windows" {
-		fontOrder = []string{"consolas", "cascadia", "courier", "lucida-console"}
-	} else {
-		fontOrder = []string{"dejavu-mono", "liberation-mono", "ubuntu-mono", "courier"}
-	}
-
 	var loadedFont rl.Font
 
-	for _, fontName := range fontOrder {
+	for _, fontName := range monospaceFontOrder() {
 		if fontPath, exists := fm.monoFontPaths[fontName]; exists {
 			testFont := rl.LoadFontEx(fontPath, size, essentialCodepoints)
 
 			if testFont.BaseSize > 0 && testFont.Texture.ID > 0 {
 				loadedFont = testFont
 				fm.fontStatus[key] = true
+				fm.loadedPaths[key] = fontPath
 				break
 			}
 		}
@@ -155,6 +204,7 @@ func (fm *GlobalFontManager) GetFont(fontName string, size int32) rl.Font {
 
 	if font.BaseSize > 0 && font.Texture.ID > 0 && font.Texture.Width > 0 {
 		fm.fontStatus[key] = true
+		fm.loadedPaths[key] = fontPath
 	} else {
 		font = rl.GetFontDefault()
 		fm.fontStatus[key] = false
@@ -173,6 +223,16 @@ func (fm *GlobalFontManager) GetFontStatus(fontName string, size int32) bool {
 	return fm.fontStatus[key]
 }
 
+// loadedPath returns the file the font fontName was loaded from at size, or
+// "" if it fell back to raylib's default font or is not loaded. The
+// monospaced font is named "monospace".
+func (fm *GlobalFontManager) loadedPath(fontName string, size int32) string {
+	key := fmt.Sprintf("%s:%d", fontName, size)
+	fm.mutex.RLock()
+	defer fm.mutex.RUnlock()
+	return fm.loadedPaths[key]
+}
+
 func (fm *GlobalFontManager) ReleaseFont(fontName string, size int32) {
 	key := fmt.Sprintf("%s:%d", fontName, size)
 
@@ -191,6 +251,7 @@ func (fm *GlobalFontManager) ReleaseFont(fontName string, size int32) {
 			delete(fm.fonts, key)
 			delete(fm.refCounts, key)
 			delete(fm.fontStatus, key)
+			delete(fm.loadedPaths, key)
 		} else {
 			fm.refCounts[key] = count
 		}
@@ -215,6 +276,7 @@ func (fm *GlobalFontManager) ReleaseMonospaceFont(size int32) {
 			delete(fm.fonts, key)
 			delete(fm.refCounts, key)
 			delete(fm.fontStatus, key)
+			delete(fm.loadedPaths, key)
 		} else {
 			fm.refCounts[key] = count
 		}
@@ -316,12 +378,7 @@ func (tmc *TextMeasureCache) Clear() {
 	tmc.fontTextures = make(map[string]uint32)
 }
 
-func renderTextWithUnicode(text string, x, y float32, font rl.Font, color rl.Color) {
-	fontSize := float32(font.BaseSize)
-	if fontSize == 0 {
-		fontSize = 16
-	}
-
+func renderTextWithUnicode(text string, x, y float32, font rl.Font, fontSize float32, color rl.Color) {
 	hasUnicode := false
 	for _, r := range text {
 		if r >= 128 {
@@ -375,7 +432,136 @@ func calculateUnicodeCharWidth(r rune, fontSize float32) float32 {
 	case r == 0x2022 || r == 0x25CF:
 		return fontSize * 0.4
 
+	case r == 0x00A0:
+		return fontSize * 0.28
+
 	default:
 		return fontSize * 0.6
 	}
 }
+
+// raylibFonts loads the raylib fonts that Font descriptors name and measures
+// text with them, so the widget lays text out with the same fonts it draws.
+// Fonts are loaded the first time they are asked for and kept until unload.
+type raylibFonts struct {
+	loaded map[Font]rl.Font
+	cache  *TextMeasureCache
+
+	// metrics are the vertical metrics in ems of the font files read so
+	// far, by path; a file that could not be read has none.
+	metrics map[string]*FontMetrics
+}
+
+func newRaylibFonts() *raylibFonts {
+	return &raylibFonts{
+		loaded:  make(map[Font]rl.Font),
+		cache:   NewTextMeasureCache(1000),
+		metrics: make(map[string]*FontMetrics),
+	}
+}
+
+// font returns the raylib font for f, loading it if needed.
+func (rf *raylibFonts) font(f Font) rl.Font {
+	if font, exists := rf.loaded[f]; exists {
+		return font
+	}
+
+	fm := getFontManager()
+	var font rl.Font
+	if f.Family == FamilyMonospace {
+		font = fm.GetMonospaceFont(fontPixelSize(f))
+	} else {
+		font = fm.GetFont(raylibFontName(f), fontPixelSize(f))
+	}
+
+	rf.loaded[f] = font
+	return font
+}
+
+// raylibFontName returns the font manager's name for a font.
+func raylibFontName(f Font) string {
+	switch {
+	case f.Family == FamilyMonospace:
+		return "monospace"
+	case f.Bold && f.Italic:
+		return "arial-bold-italic"
+	case f.Bold:
+		return "arial-bold"
+	case f.Italic:
+		return "arial-italic"
+	}
+	return "arial"
+}
+
+func fontPixelSize(f Font) int32 {
+	return int32(f.Size + 0.5)
+}
+
+func (rf *raylibFonts) MeasureText(font Font, text string) float32 {
+	return rf.cache.GetTextWidth(rf.font(font), text, font.Size)
+}
+
+// Metrics returns the vertical metrics of the file the font was loaded
+// from, which raylib does not expose. raylib's default font, or a file sfnt
+// cannot read, gets an approximation.
+func (rf *raylibFonts) Metrics(font Font) FontMetrics {
+	rf.font(font)
+	path := getFontManager().loadedPath(raylibFontName(font), fontPixelSize(font))
+	metrics, exists := rf.metrics[path]
+	if !exists {
+		metrics = readFontMetrics(path)
+		rf.metrics[path] = metrics
+	}
+
+	if metrics == nil {
+		return FontMetrics{Ascent: font.Size * 0.8, Descent: font.Size * 0.2}
+	}
+	return FontMetrics{
+		Ascent:  metrics.Ascent * font.Size,
+		Descent: metrics.Descent * font.Size,
+		LineGap: metrics.LineGap * font.Size,
+	}
+}
+
+// readFontMetrics returns the vertical metrics in ems of the font file at
+// path, the first font of a collection, or nil if it cannot be read.
+func readFontMetrics(path string) *FontMetrics {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	parsed, err := sfnt.Parse(data)
+	if err != nil {
+		collection, err := sfnt.ParseCollection(data)
+		if err != nil {
+			return nil
+		}
+		if parsed, err = collection.Font(0); err != nil {
+			return nil
+		}
+	}
+
+	var buffer sfnt.Buffer
+	metrics, err := emMetrics(parsed, &buffer)
+	if err != nil {
+		return nil
+	}
+	return &metrics
+}
+
+// unload releases every font loaded so far.
+func (rf *raylibFonts) unload() {
+	fm := getFontManager()
+	for f := range rf.loaded {
+		if f.Family == FamilyMonospace {
+			fm.ReleaseMonospaceFont(fontPixelSize(f))
+		} else {
+			fm.ReleaseFont(raylibFontName(f), fontPixelSize(f))
+		}
+	}
+	rf.loaded = make(map[Font]rl.Font)
+	rf.cache.Clear()
+}
@@ -0,0 +1,181 @@
+package marquee
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestSystemFonts(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	order := monospaceFontOrder()
+	fm := &GlobalFontManager{
+		fontPaths: map[string]string{
+			"arial":             write("regular.ttf", goregular.TTF),
+			"arial-bold":        write("bold.ttf", gobold.TTF),
+			"arial-italic":      filepath.Join(dir, "missing.ttf"),
+			"arial-bold-italic": write("broken.ttf", []byte("not a font")),
+		},
+		monoFontPaths: map[string]string{
+			order[0]: filepath.Join(dir, "missing-mono.ttf"),
+			order[1]: write("mono.ttf", gomono.TTF),
+		},
+	}
+
+	fonts, ok := fm.systemFonts()
+	if !ok {
+		t.Fatal("systemFonts reports the regular font missing")
+	}
+	if !bytes.Equal(fonts.Regular, goregular.TTF) || !bytes.Equal(fonts.Bold, gobold.TTF) {
+		t.Error("the regular and bold files were not read")
+	}
+	if fonts.Italic != nil || fonts.BoldItalic != nil {
+		t.Error("a missing or unreadable file gave font data")
+	}
+	if !bytes.Equal(fonts.Monospace, gomono.TTF) {
+		t.Errorf("monospaced font was not the first one found in %v", order)
+	}
+
+	if _, ok := (&GlobalFontManager{}).systemFonts(); ok {
+		t.Error("systemFonts reports fonts with no paths at all")
+	}
+}
+
+func TestReadFontMetrics(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "regular.ttf")
+	if err := os.WriteFile(path, goregular.TTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := GoFontMeasurer().Metrics(Font{Size: 1})
+	if got := readFontMetrics(path); got == nil || *got != want {
+		t.Errorf("readFontMetrics = %v, want %v", got, want)
+	}
+	for _, path := range []string{"", filepath.Join(dir, "missing.ttf")} {
+		if got := readFontMetrics(path); got != nil {
+			t.Errorf("readFontMetrics(%q) = %v, want nil", path, got)
+		}
+	}
+}
+
+func TestRaylibFontsMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regular.ttf")
+	if err := os.WriteFile(path, goregular.TTF, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stand in for fonts raylib loaded: the regular one from the file, and
+	// the bold one falling back to raylib's default.
+	fm := getFontManager()
+	fm.mutex.Lock()
+	fm.fonts["arial:16"], fm.loadedPaths["arial:16"] = rl.Font{}, path
+	fm.fonts["arial-bold:16"] = rl.Font{}
+	fm.mutex.Unlock()
+	defer func() {
+		fm.mutex.Lock()
+		for _, key := range []string{"arial:16", "arial-bold:16"} {
+			delete(fm.fonts, key)
+			delete(fm.refCounts, key)
+			delete(fm.loadedPaths, key)
+		}
+		fm.mutex.Unlock()
+	}()
+
+	rf := newRaylibFonts()
+	if got, want := rf.Metrics(Font{Size: 16}), GoFontMeasurer().Metrics(Font{Size: 16}); got != want {
+		t.Errorf("metrics of the regular font = %v, want %v from its file", got, want)
+	}
+	if got, want := rf.Metrics(Font{Bold: true, Size: 16}), (FontMetrics{Ascent: 12.8, Descent: 3.2}); got != want {
+		t.Errorf("metrics of the default font = %v, want %v", got, want)
+	}
+}
+
+// fontCollection returns a font collection, as in a .ttc file, of fonts.
+func fontCollection(fonts ...[]byte) []byte {
+	header := 12 + 4*len(fonts)
+	collection := make([]byte, header)
+	copy(collection, "ttcf\x00\x01\x00\x00")
+	binary.BigEndian.PutUint32(collection[8:], uint32(len(fonts)))
+
+	for i, font := range fonts {
+		start := len(collection)
+		binary.BigEndian.PutUint32(collection[12+4*i:], uint32(start))
+		collection = append(collection, font...)
+
+		// Table offsets count from the start of the collection.
+		numTables := int(binary.BigEndian.Uint16(font[4:]))
+		for record := start + 12; record < start+12+16*numTables; record += 16 {
+			offset := binary.BigEndian.Uint32(collection[record+8:])
+			binary.BigEndian.PutUint32(collection[record+8:], offset+uint32(start))
+		}
+	}
+	return collection
+}
+
+func TestFontCollections(t *testing.T) {
+	collection := fontCollection(goregular.TTF, gobold.TTF)
+
+	single, err := singleFont(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := sfnt.Parse(single)
+	if err != nil {
+		t.Fatalf("the first font of the collection does not parse on its own: %v", err)
+	}
+	if name, _ := parsed.Name(nil, sfnt.NameIDFull); name != "Go Regular" {
+		t.Errorf("first font of the collection is %q, want Go Regular", name)
+	}
+	if got, err := singleFont(goregular.TTF); err != nil || !bytes.Equal(got, goregular.TTF) {
+		t.Errorf("a single font was changed (%v)", err)
+	}
+	for _, data := range [][]byte{collection[:12], collection[:20], fontCollection()} {
+		if _, err := singleFont(data); err == nil {
+			t.Errorf("no error for a malformed collection of %d bytes", len(data))
+		}
+	}
+
+	measurer, err := NewTTFMeasurer(TTFFonts{Regular: collection})
+	if err != nil {
+		t.Fatal(err)
+	}
+	font := Font{Size: 16}
+	if got, want := measurer.MeasureText(font, "Hello"), GoFontMeasurer().MeasureText(font, "Hello"); got != want {
+		t.Errorf("text measured %v in the collection, want %v", got, want)
+	}
+
+	// The PDF embeds the one font, not the whole collection.
+	r := NewHTMLRenderer()
+	r.Measurer = measurer
+	pdf := renderPDF(t, r, "<p>Hello</p>")
+	if want := fmt.Sprintf("/Length1 %d", len(single)); !bytes.Contains(pdf, []byte(want)) {
+		t.Errorf("PDF does not embed the %d-byte font on its own", len(single))
+	}
+
+	path := filepath.Join(t.TempDir(), "fonts.ttc")
+	if err := os.WriteFile(path, collection, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fm := &GlobalFontManager{fontPaths: map[string]string{"arial": path}}
+	if fonts, ok := fm.systemFonts(); !ok || !bytes.Equal(fonts.Regular, single) {
+		t.Error("systemFonts did not read the first font of the collection")
+	}
+}
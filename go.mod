@@ -2,10 +2,14 @@ module github.com/ha1tch/marquee
 
 go 1.24.4
 
-require github.com/gen2brain/raylib-go/raylib v0.55.1
+require (
+	github.com/gen2brain/raylib-go/raylib v0.55.1
+	golang.org/x/image v0.25.0
+)
 
 require (
 	github.com/ebitengine/purego v0.7.1 // indirect
 	golang.org/x/exp v0.0.0-20240506185415-9bf2ced13842 // indirect
 	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
 )
@@ -0,0 +1,114 @@
+package marquee
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+)
+
+// ImageDiff describes how two images differ.
+type ImageDiff struct {
+	// SizeMismatch is set when the images are not the same size, in which
+	// case nothing else is compared.
+	SizeMismatch bool
+
+	// DifferentPixels counts the pixels where a channel differs by more
+	// than the tolerance, and Bounds is the smallest rectangle holding them.
+	DifferentPixels int
+	Bounds          image.Rectangle
+
+	// MaxDelta is the largest difference in any channel of any pixel, on a
+	// scale of 0 to 255.
+	MaxDelta uint8
+}
+
+// Match reports whether the images were found to be the same.
+func (d ImageDiff) Match() bool {
+	return !d.SizeMismatch && d.DifferentPixels == 0
+}
+
+func (d ImageDiff) String() string {
+	if d.SizeMismatch {
+		return "images differ in size"
+	}
+	if d.Match() {
+		return "images match"
+	}
+	return fmt.Sprintf("%d pixels differ within %v, by up to %d", d.DifferentPixels, d.Bounds, d.MaxDelta)
+}
+
+// CompareImages compares got with want pixel by pixel. A pixel counts as
+// different when one of its channels differs by more than tolerance, which
+// absorbs the small changes in antialiasing between font rasterizers and
+// platforms.
+func CompareImages(got, want image.Image, tolerance uint8) ImageDiff {
+	var diff ImageDiff
+
+	gotBounds, wantBounds := got.Bounds(), want.Bounds()
+	if gotBounds.Dx() != wantBounds.Dx() || gotBounds.Dy() != wantBounds.Dy() {
+		diff.SizeMismatch = true
+		return diff
+	}
+
+	for y := 0; y < gotBounds.Dy(); y++ {
+		for x := 0; x < gotBounds.Dx(); x++ {
+			r1, g1, b1, a1 := got.At(gotBounds.Min.X+x, gotBounds.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := want.At(wantBounds.Min.X+x, wantBounds.Min.Y+y).RGBA()
+
+			delta := max(channelDelta(r1, r2), channelDelta(g1, g2), channelDelta(b1, b2), channelDelta(a1, a2))
+			diff.MaxDelta = max(diff.MaxDelta, delta)
+			if delta > tolerance {
+				diff.DifferentPixels++
+				diff.Bounds = diff.Bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	return diff
+}
+
+// channelDelta returns the difference between two 16-bit channel values on
+// a scale of 0 to 255.
+func channelDelta(a, b uint32) uint8 {
+	if a < b {
+		a, b = b, a
+	}
+	return uint8((a - b) >> 8)
+}
+
+// CompareGolden compares got with the PNG at path, such as a screenshot made
+// with RenderToImage in an earlier run, and returns an error describing the
+// difference if they do not match within tolerance.
+func CompareGolden(path string, got image.Image, tolerance uint8) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	want, err := png.Decode(file)
+	if err != nil {
+		return fmt.Errorf("marquee: reading golden image %s: %w", path, err)
+	}
+
+	if diff := CompareImages(got, want, tolerance); !diff.Match() {
+		return fmt.Errorf("marquee: image does not match %s: %v", path, diff)
+	}
+	return nil
+}
+
+// WriteGolden saves img as a PNG at path, to create or update the image
+// CompareGolden checks against.
+func WriteGolden(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -13,6 +13,23 @@ func (h *DefinitionListRenderHandler) CanRender(node HTMLNode) bool {
 	return node.Tag == "dl" || node.Tag == "dt" || node.Tag == "dd"
 }
 
+// Margins leaves space around the list, a small gap after each term that
+// has text and a larger one after each definition.
+func (h *DefinitionListRenderHandler) Margins(node HTMLNode, ctx RenderContext) VerticalMargins {
+	spacing := ctx.Renderer.Theme.Spacing
+	switch node.Tag {
+	case "dl":
+		return VerticalMargins{Top: spacing.DefinitionListMargin, Bottom: spacing.DefinitionListMargin}
+	case "dt":
+		if h.termText(node) == "" {
+			return VerticalMargins{}
+		}
+		return VerticalMargins{Bottom: spacing.BlockSpacing}
+	default:
+		return VerticalMargins{Bottom: spacing.BlockSpacing + spacing.DefinitionSpacing}
+	}
+}
+
 func (h *DefinitionListRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
 	switch node.Tag {
 	case "dl":
@@ -27,80 +44,92 @@ func (h *DefinitionListRenderHandler) Render(node HTMLNode, ctx RenderContext) R
 }
 
 func (h *DefinitionListRenderHandler) renderDefinitionList(node HTMLNode, ctx RenderContext) RenderResult {
-	result := RenderResult{NextY: ctx.Y + 10} // Small top margin
+	result := RenderResult{NextY: ctx.Y}
+	result.Box.Tag = node.Tag
 
-	currentY := result.NextY
+	childCtx := ctx
 	for _, child := range node.Children {
 		if child.Tag == "dt" || child.Tag == "dd" {
-			childCtx := ctx
-			childCtx.Y = currentY
-			childCtx.ParentFont = ctx.ParentFont
-			childCtx.ParentColor = ctx.ParentColor
-
-			childResult := ctx.Widget.renderer.RenderNode(child, childCtx)
-			currentY = childResult.NextY
+			childResult := ctx.Renderer.RenderNode(child, childCtx)
+			childCtx.advance(childResult)
 			result.NextY = childResult.NextY
-			result.LinkAreas = append(result.LinkAreas, childResult.LinkAreas...)
+			result.Box.appendChild(childResult.Box)
 		}
 	}
 
-	result.NextY += 10 // Bottom margin
+	result.MarginBottom = childCtx.MarginAbove
 	result.Height = result.NextY - ctx.Y
+	result.Box.Bounds = rl.NewRectangle(ctx.X, ctx.Y, ctx.Width, result.Height)
 	return result
 }
 
-func (h *DefinitionListRenderHandler) renderDefinitionTerm(node HTMLNode, ctx RenderContext) RenderResult {
-	// Collect text content
+// termText returns the text of a term, with its whitespace collapsed.
+func (h *DefinitionListRenderHandler) termText(node HTMLNode) string {
 	var content strings.Builder
 	for _, child := range node.Children {
 		if child.Type == NodeTypeText {
 			content.WriteString(child.Content)
 		}
 	}
+	return collapseWhitespace(strings.TrimFunc(content.String(), isHTMLSpace))
+}
 
-	if content.Len() == 0 {
+func (h *DefinitionListRenderHandler) renderDefinitionTerm(node HTMLNode, ctx RenderContext) RenderResult {
+	text := h.termText(node)
+	if text == "" {
 		return RenderResult{NextY: ctx.Y}
 	}
 
 	// Render terms in bold, slightly larger font
-	font := ctx.Widget.Fonts.Bold
-	fontSize := float32(font.BaseSize)
-	if fontSize == 0 {
-		fontSize = 18
+	font := ctx.Renderer.Theme.Fonts.Bold
+	if font.Size == 0 {
+		font.Size = 18
 	}
+	font = node.Style.ownFont(font)
 
-	text := content.String()
-	ctx.Widget.renderTextWithUnicode(text, ctx.X, ctx.Y, font, rl.DarkBlue)
+	color := ctx.Renderer.Theme.Colors.Term
+	if node.Style.HasColor() {
+		color = node.Style.Color
+	}
 
-	textSize := ctx.Widget.measureText(font, text, fontSize)
+	segments := []inlineSegment{{text: text, font: font, color: color}}
+	lines := ctx.Renderer.layoutInlineLines(segments, ctx.X, ctx.Y, ctx.Width-ctx.RightMargin, ctx.Renderer.lineHeight(font), node.Style.TextAlign)
+
+	nextY := linesBottom(lines, ctx.Y)
 	return RenderResult{
-		NextY:  ctx.Y + textSize.Y + 5, // Small gap before definition
-		Height: textSize.Y + 5,
+		Box: LayoutBox{
+			Tag:    node.Tag,
+			Bounds: rl.NewRectangle(ctx.X, ctx.Y, ctx.Width, nextY-ctx.Y),
+			Lines:  lines,
+		},
+		NextY:  nextY,
+		Height: nextY - ctx.Y,
 	}
 }
 
 func (h *DefinitionListRenderHandler) renderDefinitionDescription(node HTMLNode, ctx RenderContext) RenderResult {
 	// Indent definition descriptions
+	spacing := ctx.Renderer.Theme.Spacing
 	indentedCtx := ctx
-	indentedCtx.X = ctx.X + 30 // Indent by 30px
-	indentedCtx.Width = ctx.Width - 30
+	indentedCtx.X = ctx.X + spacing.DefinitionIndent
+	indentedCtx.Width = ctx.Width - spacing.DefinitionIndent
 	indentedCtx.CurrentX = indentedCtx.X
 
 	// Use paragraph-style rendering for rich content
-	if indentedCtx.ParentFont.BaseSize == 0 {
-		indentedCtx.ParentFont = ctx.Widget.Fonts.Regular
+	if indentedCtx.ParentFont.Size == 0 {
+		indentedCtx.ParentFont = ctx.Renderer.Theme.Fonts.Regular
 	}
 	if indentedCtx.ParentColor.R == 0 && indentedCtx.ParentColor.G == 0 && indentedCtx.ParentColor.B == 0 && indentedCtx.ParentColor.A == 0 {
-		indentedCtx.ParentColor = rl.Black
+		indentedCtx.ParentColor = ctx.Renderer.Theme.Colors.Text
 	}
+	indentedCtx.ParentFont = ctx.Renderer.styledFont(node.Style, indentedCtx.ParentFont)
+	indentedCtx.ParentColor = ctx.Renderer.styledColor(node.Style, indentedCtx.ParentColor)
 
 	// Build inline segments like paragraphs do
 	ph := &ParagraphRenderHandler{}
 	segments := h.buildDefinitionSegments(node, indentedCtx)
-	result := ph.renderSegmentsWithWrapping(segments, indentedCtx)
-
-	result.NextY += 8 // Gap after definition
-	result.Height += 8
+	result := ph.renderSegmentsWithWrapping(segments, indentedCtx, node.Style.TextAlign)
+	result.Box.Tag = node.Tag
 	return result
 }
 
@@ -110,9 +139,12 @@ func (h *DefinitionListRenderHandler) buildDefinitionSegments(node HTMLNode, ctx
 	for _, child := range node.Children {
 		if child.Type == NodeTypeText {
 			segments = append(segments, inlineSegment{
-				text:  child.Content,
-				font:  ctx.ParentFont,
-				color: ctx.ParentColor,
+				text:        child.Content,
+				font:        ctx.ParentFont,
+				color:       ctx.ParentColor,
+				background:  ctx.ParentBackground,
+				underline:   node.Style.Underline,
+				lineThrough: node.Style.LineThrough,
 			})
 		} else if child.Type == NodeTypeElement && child.Context == ContextInline {
 			childSegments := h.getDefinitionSegmentsFromElement(child, ctx)
@@ -124,24 +156,17 @@ func (h *DefinitionListRenderHandler) buildDefinitionSegments(node HTMLNode, ctx
 }
 
 func (h *DefinitionListRenderHandler) getDefinitionSegmentsFromElement(node HTMLNode, ctx RenderContext) []inlineSegment {
-	font := ctx.ParentFont
-	color := ctx.ParentColor
+	if node.Tag == "br" {
+		return []inlineSegment{{lineBreak: true}}
+	}
+	if isMetadataElement(node.Tag) || node.Style.Hidden {
+		return nil
+	}
 
 	// Handle formatting like paragraphs do
-	if node.Tag == "span" {
-		if style, exists := node.Attributes["style"]; exists {
-			if strings.Contains(style, "font-weight: bold") {
-				font = ctx.Widget.Fonts.Bold
-				color = rl.DarkBlue
-			}
-			if strings.Contains(style, "font-style: italic") {
-				font = ctx.Widget.Fonts.Italic
-				color = rl.DarkGreen
-			}
-		}
-	} else if node.Tag == "a" {
-		color = rl.Blue
-	}
+	font := ctx.Renderer.styledFont(node.Style, ctx.ParentFont)
+	color := ctx.Renderer.styledColor(node.Style, ctx.ParentColor)
+	background := styledBackground(node.Style, ctx.ParentBackground)
 
 	var segments []inlineSegment
 	href, _ := node.Attributes["href"]
@@ -149,15 +174,19 @@ func (h *DefinitionListRenderHandler) getDefinitionSegmentsFromElement(node HTML
 	for _, child := range node.Children {
 		if child.Type == NodeTypeText {
 			segments = append(segments, inlineSegment{
-				text:  child.Content,
-				font:  font,
-				color: color,
-				href:  href,
+				text:        child.Content,
+				font:        font,
+				color:       color,
+				background:  background,
+				href:        href,
+				underline:   node.Style.Underline,
+				lineThrough: node.Style.LineThrough,
 			})
 		} else if child.Type == NodeTypeElement && child.Context == ContextInline {
 			nestedCtx := ctx
 			nestedCtx.ParentFont = font
 			nestedCtx.ParentColor = color
+			nestedCtx.ParentBackground = background
 			nestedSegments := h.getDefinitionSegmentsFromElement(child, nestedCtx)
 			segments = append(segments, nestedSegments...)
 		}
@@ -190,6 +219,10 @@ func (h *CalloutBoxRenderHandler) CanRender(node HTMLNode) bool {
 	return false
 }
 
+func (h *CalloutBoxRenderHandler) Margins(node HTMLNode, ctx RenderContext) VerticalMargins {
+	return VerticalMargins{Bottom: ctx.Renderer.Theme.Spacing.CalloutMargin}
+}
+
 func (h *CalloutBoxRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
 	class, _ := node.Attributes["class"]
 	calloutType := h.getCalloutType(class)
@@ -209,93 +242,69 @@ func (h *CalloutBoxRenderHandler) getCalloutType(class string) string {
 
 func (h *CalloutBoxRenderHandler) renderCalloutBox(node HTMLNode, ctx RenderContext, calloutType string) RenderResult {
 	// Get colors and icon for callout type
-	bgColor, borderColor, textColor, icon := h.getCalloutStyle(calloutType)
+	theme := ctx.Renderer.Theme
+	style := theme.Callouts.style(calloutType)
+	spacing := theme.Spacing
+	boxPadding := spacing.CalloutPadding
 
-	// Calculate content first to determine box height
+	// Lay out the content first to determine box height
 	contentCtx := ctx
-	contentCtx.X = ctx.X + 50         // Leave space for icon
-	contentCtx.Y = ctx.Y + 15         // Top padding
-	contentCtx.Width = ctx.Width - 70 // Account for padding and icon
-	contentCtx.ParentFont = ctx.Widget.Fonts.Regular
-	contentCtx.ParentColor = textColor
+	contentCtx.X = ctx.X + spacing.CalloutIconWidth                      // Leave space for icon
+	contentCtx.Y = ctx.Y + boxPadding                                    // Top padding
+	contentCtx.Width = ctx.Width - spacing.CalloutIconWidth - boxPadding // Account for padding and icon
+	contentCtx.ParentFont = theme.Fonts.Regular
+	if node.Style.FontSize > 0 {
+		contentCtx.ParentFont.Size = node.Style.FontSize
+	}
+	contentCtx.ParentColor = style.Text
+	if node.Style.HasColor() {
+		contentCtx.ParentColor = node.Style.Color
+	}
 	contentCtx.CurrentX = contentCtx.X
 
 	// Build content segments
 	segments := h.buildCalloutSegments(node, contentCtx)
 
-	// Render content to measure height
 	ph := &ParagraphRenderHandler{}
-	contentResult := ph.renderSegmentsWithWrapping(segments, contentCtx)
+	contentResult := ph.renderSegmentsWithWrapping(segments, contentCtx, node.Style.TextAlign)
 
 	// Calculate total box dimensions
-	boxPadding := float32(15)
 	boxHeight := contentResult.Height + 2*boxPadding
-	boxWidth := ctx.Width - ctx.Widget.BodyMargin - ctx.Widget.BodyPadding
-
-	// Draw box background
-	boxRect := rl.NewRectangle(ctx.X, ctx.Y, boxWidth, boxHeight)
-	rl.DrawRectangleRec(boxRect, bgColor)
-
-	// Draw left border (thicker for callout effect)
-	borderRect := rl.NewRectangle(ctx.X, ctx.Y, 4, boxHeight)
-	rl.DrawRectangleRec(borderRect, borderColor)
-
-	// Draw subtle outline
-	rl.DrawRectangleLinesEx(boxRect, 1, rl.Color{R: 200, G: 200, B: 200, A: 100})
+	boxWidth := ctx.Width - ctx.RightMargin
+
+	// Box background with a subtle outline, unless the node's style gives
+	// it its own
+	box := LayoutBox{
+		Tag:         node.Tag,
+		Bounds:      rl.NewRectangle(ctx.X, ctx.Y, boxWidth, boxHeight),
+		Background:  style.Background,
+		BorderColor: theme.Colors.CalloutOutline,
+		BorderWidth: 1,
+	}
+	if node.Style.Background.A != 0 {
+		box.Background = rl.Color{}
+	}
+	if node.Style.Border.Width.IsSet() {
+		box.BorderWidth = 0
+	}
 
-	// Draw icon
-	iconFont := ctx.Widget.Fonts.Regular
+	// Icon
+	iconFont := theme.Fonts.Regular
+	iconFont.Size = 18
 	iconY := ctx.Y + boxPadding
-	rl.DrawTextEx(iconFont, icon, rl.NewVector2(ctx.X+12, iconY), 18, 1, borderColor)
+	box.Lines = []LineBox{ctx.Renderer.textLine(style.Icon, ctx.X+12, iconY, iconFont, style.Accent)}
 
-	// The content was already rendered during measurement, so we need to render it again
-	// at the correct position (this is a limitation of our current approach)
-	h.renderCalloutContent(segments, contentCtx, ctx.Widget)
+	// Left border (thicker for callout effect)
+	box.appendChild(LayoutBox{
+		Bounds:     rl.NewRectangle(ctx.X, ctx.Y, spacing.CalloutAccentWidth, boxHeight),
+		Background: style.Accent,
+	})
+	box.appendChild(contentResult.Box)
 
 	return RenderResult{
-		NextY:     ctx.Y + boxHeight + 15, // Bottom margin
-		Height:    boxHeight + 15,
-		LinkAreas: contentResult.LinkAreas,
-	}
-}
-
-func (h *CalloutBoxRenderHandler) getCalloutStyle(calloutType string) (rl.Color, rl.Color, rl.Color, string) {
-	switch calloutType {
-	case "warning":
-		return rl.Color{R: 255, G: 248, B: 220, A: 255}, // Light yellow background
-			rl.Color{R: 255, G: 193, B: 7, A: 255}, // Orange border
-			rl.Color{R: 133, G: 77, B: 14, A: 255}, // Dark orange text
-			"⚠️"
-
-	case "danger":
-		return rl.Color{R: 253, G: 237, B: 237, A: 255}, // Light red background
-			rl.Color{R: 220, G: 38, B: 127, A: 255}, // Red border
-			rl.Color{R: 114, G: 28, B: 36, A: 255}, // Dark red text
-			"🚫"
-
-	case "success":
-		return rl.Color{R: 230, G: 245, B: 233, A: 255}, // Light green background
-			rl.Color{R: 40, G: 167, B: 69, A: 255}, // Green border
-			rl.Color{R: 21, G: 87, B: 36, A: 255}, // Dark green text
-			"✅"
-
-	case "info":
-		return rl.Color{R: 217, G: 237, B: 247, A: 255}, // Light blue background
-			rl.Color{R: 52, G: 144, B: 220, A: 255}, // Blue border
-			rl.Color{R: 12, G: 84, B: 96, A: 255}, // Dark blue text
-			"ℹ️"
-
-	case "tip":
-		return rl.Color{R: 230, G: 245, B: 233, A: 255}, // Light green background
-			rl.Color{R: 40, G: 167, B: 69, A: 255}, // Green border
-			rl.Color{R: 21, G: 87, B: 36, A: 255}, // Dark green text
-			"💡"
-
-	default: // "note"
-		return rl.Color{R: 248, G: 249, B: 250, A: 255}, // Light gray background
-			rl.Color{R: 108, G: 117, B: 125, A: 255}, // Gray border
-			rl.Color{R: 33, G: 37, B: 41, A: 255}, // Dark gray text
-			"📝"
+		Box:    box,
+		NextY:  ctx.Y + boxHeight,
+		Height: boxHeight,
 	}
 }
 
@@ -305,9 +314,12 @@ func (h *CalloutBoxRenderHandler) buildCalloutSegments(node HTMLNode, ctx Render
 	for _, child := range node.Children {
 		if child.Type == NodeTypeText {
 			segments = append(segments, inlineSegment{
-				text:  child.Content,
-				font:  ctx.ParentFont,
-				color: ctx.ParentColor,
+				text:        child.Content,
+				font:        ctx.ParentFont,
+				color:       ctx.ParentColor,
+				background:  ctx.ParentBackground,
+				underline:   node.Style.Underline,
+				lineThrough: node.Style.LineThrough,
 			})
 		} else if child.Type == NodeTypeElement && child.Context == ContextInline {
 			childSegments := h.getCalloutSegmentsFromElement(child, ctx)
@@ -319,24 +331,20 @@ func (h *CalloutBoxRenderHandler) buildCalloutSegments(node HTMLNode, ctx Render
 }
 
 func (h *CalloutBoxRenderHandler) getCalloutSegmentsFromElement(node HTMLNode, ctx RenderContext) []inlineSegment {
-	font := ctx.ParentFont
-	color := ctx.ParentColor
+	if node.Tag == "br" {
+		return []inlineSegment{{lineBreak: true}}
+	}
+	if isMetadataElement(node.Tag) || node.Style.Hidden {
+		return nil
+	}
 
-	// Handle formatting
-	if node.Tag == "span" {
-		if style, exists := node.Attributes["style"]; exists {
-			if strings.Contains(style, "font-weight: bold") {
-				font = ctx.Widget.Fonts.Bold
-				// Keep callout text color, don't override with blue
-			}
-			if strings.Contains(style, "font-style: italic") {
-				font = ctx.Widget.Fonts.Italic
-				// Keep callout text color, don't override with green
-			}
-		}
-	} else if node.Tag == "a" {
-		color = rl.Blue // Links can still be blue in callouts
+	// Handle formatting, keeping the callout text color for emphasis
+	font := ctx.Renderer.styledFont(node.Style, ctx.ParentFont)
+	color := ctx.ParentColor
+	if node.Tag == "a" {
+		color = ctx.Renderer.styledColor(node.Style, color) // Links keep the link color in callouts
 	}
+	background := styledBackground(node.Style, ctx.ParentBackground)
 
 	var segments []inlineSegment
 	href, _ := node.Attributes["href"]
@@ -344,15 +352,19 @@ func (h *CalloutBoxRenderHandler) getCalloutSegmentsFromElement(node HTMLNode, c
 	for _, child := range node.Children {
 		if child.Type == NodeTypeText {
 			segments = append(segments, inlineSegment{
-				text:  child.Content,
-				font:  font,
-				color: color,
-				href:  href,
+				text:        child.Content,
+				font:        font,
+				color:       color,
+				background:  background,
+				href:        href,
+				underline:   node.Style.Underline,
+				lineThrough: node.Style.LineThrough,
 			})
 		} else if child.Type == NodeTypeElement && child.Context == ContextInline {
 			nestedCtx := ctx
 			nestedCtx.ParentFont = font
 			nestedCtx.ParentColor = color
+			nestedCtx.ParentBackground = background
 			nestedSegments := h.getCalloutSegmentsFromElement(child, nestedCtx)
 			segments = append(segments, nestedSegments...)
 		}
@@ -360,68 +372,3 @@ func (h *CalloutBoxRenderHandler) getCalloutSegmentsFromElement(node HTMLNode, c
 
 	return segments
 }
-
-func (h *CalloutBoxRenderHandler) renderCalloutContent(segments []inlineSegment, ctx RenderContext, widget *HTMLWidget) {
-	currentY := ctx.Y
-	lineHeight := float32(20)
-	currentLineSegments := []inlineSegment{}
-	currentLineWidth := float32(0)
-
-	rightMargin := widget.BodyMargin + widget.BodyPadding
-
-	for _, segment := range segments {
-		ph := &ParagraphRenderHandler{}
-		words := ph.intelligentWordSplit(segment.text)
-
-		for _, word := range words {
-			wordSegment := inlineSegment{
-				text:  word,
-				font:  segment.font,
-				color: segment.color,
-				href:  segment.href,
-			}
-
-			wordWidth := widget.measureTextWidth(segment.font, word, float32(segment.font.BaseSize))
-
-			availableWidth := ctx.Width - rightMargin
-			if currentLineWidth+wordWidth > availableWidth && len(currentLineSegments) > 0 {
-				h.renderCalloutInlineSegments(currentLineSegments, ctx.X, currentY, widget)
-
-				currentY += lineHeight
-				currentLineSegments = []inlineSegment{wordSegment}
-				currentLineWidth = wordWidth
-			} else {
-				if len(currentLineSegments) > 0 {
-					spaceSegment := inlineSegment{
-						text:  " ",
-						font:  segment.font,
-						color: segment.color,
-					}
-					currentLineSegments = append(currentLineSegments, spaceSegment)
-					currentLineWidth += widget.measureTextWidth(segment.font, " ", float32(segment.font.BaseSize))
-				}
-
-				currentLineSegments = append(currentLineSegments, wordSegment)
-				currentLineWidth += wordWidth
-			}
-		}
-	}
-
-	if len(currentLineSegments) > 0 {
-		h.renderCalloutInlineSegments(currentLineSegments, ctx.X, currentY, widget)
-	}
-}
-
-func (h *CalloutBoxRenderHandler) renderCalloutInlineSegments(segments []inlineSegment, x, y float32, widget *HTMLWidget) {
-	currentX := x
-
-	for _, segment := range segments {
-		widget.renderTextWithUnicode(segment.text, currentX, y, segment.font, segment.color)
-		segmentWidth := widget.measureTextWidth(segment.font, segment.text, float32(segment.font.BaseSize))
-
-		// Note: We're not handling link areas here for simplicity
-		// Could be added if needed by collecting them and returning
-
-		currentX += segmentWidth
-	}
-}
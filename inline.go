@@ -0,0 +1,329 @@
+package marquee
+
+import (
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// inlineToken is an unbreakable piece of inline text. spaceBefore records
+// whether the source had whitespace in front of it, and breakBefore whether a
+// line may be wrapped there. Long URLs are split into pieces that may wrap
+// but are drawn without a space between them.
+type inlineToken struct {
+	segment     inlineSegment
+	spaceBefore bool
+	breakBefore bool
+}
+
+// inlineLine is one wrapped line of inline content. broken records that a
+// <br> ends it.
+type inlineLine struct {
+	segments []inlineSegment
+	width    float32
+	broken   bool
+}
+
+// tokenizeInlineSegments splits segments into tokens, keeping the whitespace
+// between them significant: "<b>foo</b> <i>bar</i>" yields two tokens with a
+// space between them, while "foo<b>bar</b>" yields two glued tokens.
+func tokenizeInlineSegments(segments []inlineSegment) []inlineToken {
+	var tokens []inlineToken
+	pendingSpace := false
+
+	for _, segment := range segments {
+		if segment.lineBreak {
+			tokens = append(tokens, inlineToken{segment: segment, breakBefore: true})
+			pendingSpace = false
+			continue
+		}
+
+		text := segment.text
+		if text == "" {
+			continue
+		}
+
+		words := splitHTMLWords(text)
+		if len(words) == 0 {
+			pendingSpace = true
+			continue
+		}
+
+		if isHTMLSpace(rune(text[0])) {
+			pendingSpace = true
+		}
+
+		for i, word := range words {
+			if i > 0 {
+				pendingSpace = true
+			}
+
+			for j, piece := range breakLongWord(word) {
+				pieceSegment := segment
+				pieceSegment.text = piece
+				tokens = append(tokens, inlineToken{
+					segment:     pieceSegment,
+					spaceBefore: pendingSpace && j == 0,
+					breakBefore: pendingSpace || j > 0,
+				})
+				pendingSpace = false
+			}
+		}
+
+		if isHTMLSpace(rune(text[len(text)-1])) {
+			pendingSpace = true
+		}
+	}
+
+	return tokens
+}
+
+// wrapInlineSegments lays segments out into lines no wider than maxWidth,
+// breaking only where the source allows it and wherever a <br> forces it.
+func (r *HTMLRenderer) wrapInlineSegments(segments []inlineSegment, maxWidth float32) []inlineLine {
+	tokens := tokenizeInlineSegments(segments)
+
+	var lines []inlineLine
+	var current inlineLine
+
+	for i := 0; i < len(tokens); {
+		if tokens[i].segment.lineBreak {
+			current.broken = true
+			lines = append(lines, current)
+			current = inlineLine{}
+			i++
+			continue
+		}
+
+		// Gather the cluster of glued tokens that has to stay on one line.
+		end := i + 1
+		for end < len(tokens) && !tokens[end].breakBefore {
+			end++
+		}
+
+		clusterWidth := float32(0)
+		for _, token := range tokens[i:end] {
+			clusterWidth += r.MeasureText(token.segment.font, token.segment.text)
+		}
+
+		spaceWidth := float32(0)
+		if tokens[i].spaceBefore && len(current.segments) > 0 {
+			spaceWidth = r.MeasureText(tokens[i].segment.font, " ")
+		}
+
+		if len(current.segments) > 0 && current.width+spaceWidth+clusterWidth > maxWidth {
+			lines = append(lines, current)
+			current = inlineLine{}
+			spaceWidth = 0
+		}
+
+		if spaceWidth > 0 {
+			// The space is highlighted only inside a highlighted run.
+			space := tokens[i].segment
+			space.text = " "
+			space.href = ""
+			if current.segments[len(current.segments)-1].background != space.background {
+				space.background = rl.Color{}
+			}
+			current.segments = append(current.segments, space)
+			current.width += spaceWidth
+		}
+
+		for _, token := range tokens[i:end] {
+			current.segments = append(current.segments, token.segment)
+		}
+		current.width += clusterWidth
+
+		i = end
+	}
+
+	if len(current.segments) > 0 {
+		lines = append(lines, current)
+	}
+
+	return lines
+}
+
+// layoutInlineLines wraps segments into lines no wider than maxWidth and
+// places them one under another, starting at x and y, as align says. Lines
+// are lineHeight apart, or further when they hold text whose own line
+// height is greater, and text of different sizes on a line shares its
+// baseline.
+func (r *HTMLRenderer) layoutInlineLines(segments []inlineSegment, x, y, maxWidth, lineHeight float32, align TextAlign) []LineBox {
+	var lines []LineBox
+	measurer := r.measurer()
+
+	wrapped := r.wrapInlineSegments(segments, maxWidth)
+	for i, line := range wrapped {
+		height := lineHeight
+		ascent := float32(0)
+		for _, segment := range line.segments {
+			height = max(height, r.lineHeight(segment.font))
+			ascent = max(ascent, measurer.Metrics(segment.font).Ascent)
+		}
+		lineBox := LineBox{Bounds: rl.NewRectangle(x, y, line.width, height)}
+
+		currentX := x
+		for _, segment := range line.segments {
+			width := r.MeasureText(segment.font, segment.text)
+			top := y + ascent - measurer.Metrics(segment.font).Ascent
+			lineBox.Fragments = append(lineBox.Fragments, segment.fragment(currentX, top, width))
+			currentX += width
+		}
+
+		// The last line of a paragraph, and a line ended by <br>, are not
+		// justified.
+		last := i == len(wrapped)-1 || line.broken
+		alignLine(&lineBox, x, maxWidth, align, last)
+		lines = append(lines, lineBox)
+		y += height
+	}
+
+	return lines
+}
+
+// alignLine moves a line laid out from x to where align places it in a
+// block width wide. A justified line is stretched to the full width by
+// sharing the space left over between the spaces between its words, unless
+// it is the last line of its paragraph, which stays flush left.
+func alignLine(line *LineBox, x, width float32, align TextAlign, last bool) {
+	slack := width - line.Bounds.Width
+	if slack <= 0 {
+		return
+	}
+
+	if align == AlignJustify {
+		if !last {
+			justifyLine(line, slack)
+		}
+		return
+	}
+
+	var shift float32
+	switch align {
+	case AlignCenter:
+		shift = slack / 2
+	case AlignRight:
+		shift = slack
+	default:
+		return
+	}
+
+	line.Bounds.X = x + shift
+	for i := range line.Fragments {
+		line.Fragments[i].Bounds.X += shift
+	}
+}
+
+// justifyLine widens the spaces between the words of line, which the
+// wrapper puts in fragments of their own, to take up slack between them.
+func justifyLine(line *LineBox, slack float32) {
+	spaces := 0
+	for _, fragment := range line.Fragments {
+		if fragment.Text == " " {
+			spaces++
+		}
+	}
+	if spaces == 0 {
+		return
+	}
+
+	extra := slack / float32(spaces)
+	var shift float32
+	for i := range line.Fragments {
+		fragment := &line.Fragments[i]
+		fragment.Bounds.X += shift
+		if fragment.Text == " " {
+			fragment.Bounds.Width += extra
+			shift += extra
+		}
+	}
+	line.Bounds.Width += slack
+}
+
+// linesBottom returns the bottom of the last of lines, or top when there
+// are none.
+func linesBottom(lines []LineBox, top float32) float32 {
+	if len(lines) == 0 {
+		return top
+	}
+	last := lines[len(lines)-1].Bounds
+	return last.Y + last.Height
+}
+
+// fragment returns the segment as a text fragment placed at x and y.
+func (s inlineSegment) fragment(x, y, width float32) TextFragment {
+	return TextFragment{
+		Text:        s.text,
+		Bounds:      rl.NewRectangle(x, y, width, s.font.Size),
+		Font:        s.font,
+		Color:       s.color,
+		Background:  s.background,
+		Href:        s.href,
+		Underline:   s.underline,
+		LineThrough: s.lineThrough,
+	}
+}
+
+// textLine returns a line holding a single run of text at x and y.
+func (r *HTMLRenderer) textLine(text string, x, y float32, font Font, color rl.Color) LineBox {
+	bounds := rl.NewRectangle(x, y, r.MeasureText(font, text), font.Size)
+	return LineBox{
+		Bounds: bounds,
+		Fragments: []TextFragment{{
+			Text:   text,
+			Bounds: bounds,
+			Font:   font,
+			Color:  color,
+		}},
+	}
+}
+
+// breakLongWord splits very long words, such as URLs, into pieces that can
+// be wrapped across lines.
+func breakLongWord(word string) []string {
+	if len(word) > 40 && (strings.Contains(word, "://") || strings.Contains(word, ".com") || strings.Contains(word, ".org") || strings.Contains(word, "/")) {
+		breakPoints := []string{"/", "?", "&", "=", ".", "-"}
+		return splitAtBreakPoints(word, breakPoints)
+	}
+
+	runes := []rune(word)
+	if len(runes) > 30 {
+		var pieces []string
+		for i := 0; i < len(runes); i += 25 {
+			end := i + 25
+			if end > len(runes) {
+				end = len(runes)
+			}
+			pieces = append(pieces, string(runes[i:end]))
+		}
+		return pieces
+	}
+
+	return []string{word}
+}
+
+func splitAtBreakPoints(text string, breakPoints []string) []string {
+	segments := []string{text}
+
+	for _, breakPoint := range breakPoints {
+		var newSegments []string
+		for _, segment := range segments {
+			if len(segment) > 30 && strings.Contains(segment, breakPoint) {
+				parts := strings.Split(segment, breakPoint)
+				for i, part := range parts {
+					if i > 0 {
+						newSegments = append(newSegments, breakPoint+part)
+					} else if part != "" {
+						newSegments = append(newSegments, part)
+					}
+				}
+			} else {
+				newSegments = append(newSegments, segment)
+			}
+		}
+		segments = newSegments
+	}
+
+	return segments
+}
@@ -0,0 +1,246 @@
+package marquee
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// DocumentLayout is a document laid out for one content width: a tree of
+// positioned boxes and lines of text that can be painted as often as needed
+// without measuring or wrapping anything again. Coordinates are relative to
+// the top left of the content area, so scrolling only moves where the tree
+// is painted.
+type DocumentLayout struct {
+	Root   LayoutBox
+	Width  float32
+	Height float32
+
+	// Links are the areas of the layout that follow a link when clicked.
+	Links []LinkArea
+
+	// Anchors maps the id of each element, and the name of each <a name>, to
+	// the Y offset where the element starts. The first element with a given
+	// id wins.
+	Anchors map[string]float32
+
+	// ctx is the context the document was laid out in, and blocks what its
+	// top-level nodes were laid out to, so that relayout can lay out one of
+	// them again without the rest.
+	ctx    RenderContext
+	blocks []layoutBlock
+}
+
+// layoutBlock is a top-level node of a laid out document: its box and
+// anchors, the position and margin above it it was laid out at, and the
+// position and margin it left for the node after it.
+type layoutBlock struct {
+	box     LayoutBox
+	anchors []layoutAnchor
+
+	y, marginAbove     float32
+	nextY, marginBelow float32
+}
+
+// layoutAnchor is an anchor found while laying out a block, in the order
+// they were found.
+type layoutAnchor struct {
+	name string
+	y    float32
+}
+
+// LayoutBox is a rectangle placed by the layout pass, with what is drawn in
+// it: a background, a border, lines of text and the boxes nested inside it.
+type LayoutBox struct {
+	// Tag is the element the box was made for, or "" for a box that only
+	// holds text or decoration, such as a list marker.
+	Tag    string
+	Bounds rl.Rectangle
+
+	// Background and BorderColor are not drawn when their alpha is zero.
+	Background  rl.Color
+	BorderColor rl.Color
+	BorderWidth float32
+
+	Lines    []LineBox
+	Children []LayoutBox
+}
+
+// LineBox is a line of text within a box.
+type LineBox struct {
+	Bounds    rl.Rectangle
+	Fragments []TextFragment
+}
+
+// TextFragment is a run of text in a single font and color. Bounds holds its
+// position and measured width; its height is the font size.
+type TextFragment struct {
+	Text   string
+	Bounds rl.Rectangle
+	Font   Font
+	Color  rl.Color
+
+	// Background is filled behind the text unless its alpha is zero.
+	Background rl.Color
+
+	// Href is the target of the link the text belongs to, if any. Linked
+	// text is underlined.
+	Href        string
+	Underline   bool
+	LineThrough bool
+}
+
+// isEmpty reports whether the box draws nothing at all.
+func (b LayoutBox) isEmpty() bool {
+	return len(b.Lines) == 0 && len(b.Children) == 0 &&
+		b.Background.A == 0 && (b.BorderColor.A == 0 || b.BorderWidth == 0)
+}
+
+// appendChild adds child to the box unless it draws nothing.
+func (b *LayoutBox) appendChild(child LayoutBox) {
+	if !child.isEmpty() {
+		b.Children = append(b.Children, child)
+	}
+}
+
+// LayoutDocument lays out the document at ctx.Width, starting at ctx.X and
+// ctx.Y, which are normally zero. Text is measured with r.Measurer, so
+// nothing needs a window: with the Go fonts or FixedMetrics the layout is
+// the same on every machine and can be checked in tests. The text starts in
+// the regular font and text color of r.Theme unless ctx says otherwise.
+func (r *HTMLRenderer) LayoutDocument(document HTMLDocument, ctx RenderContext) *DocumentLayout {
+	layout := &DocumentLayout{
+		Width:   ctx.Width,
+		Anchors: make(map[string]float32),
+	}
+	ctx.Renderer = r
+
+	// The widget keeps the computed styles of its document up to date as it
+	// changes; a document laid out on its own is styled here.
+	if ctx.Widget == nil {
+		document = styledCopy(document, r.StyleSheet, r.ColorScheme.resolve(ColorSchemeLight))
+	}
+	if ctx.ParentFont.Size == 0 {
+		ctx.ParentFont = r.Theme.Fonts.Regular
+	}
+	if ctx.ParentColor.A == 0 {
+		ctx.ParentColor = r.Theme.Colors.Text
+	}
+
+	layout.ctx = ctx
+	for _, child := range document.Root.Children {
+		layout.blocks = append(layout.blocks, r.layoutBlock(child, &ctx))
+	}
+	layout.assemble()
+	return layout
+}
+
+// layoutBlock lays out a top-level node at the position in ctx and moves
+// ctx past it.
+func (r *HTMLRenderer) layoutBlock(node HTMLNode, ctx *RenderContext) layoutBlock {
+	block := layoutBlock{y: ctx.Y, marginAbove: ctx.MarginAbove}
+
+	nodeCtx := *ctx
+	nodeCtx.anchors = &block.anchors
+	result := r.RenderNode(node, nodeCtx)
+	ctx.advance(result)
+
+	block.box = result.Box
+	block.nextY, block.marginBelow = ctx.Y, ctx.MarginAbove
+	return block
+}
+
+// assemble puts the root box, the anchors and the links of the layout
+// together from its blocks.
+func (l *DocumentLayout) assemble() {
+	root := LayoutBox{Bounds: rl.NewRectangle(l.ctx.X, l.ctx.Y, l.ctx.Width, 0)}
+	l.Anchors = make(map[string]float32)
+	end := l.ctx.Y
+
+	for _, block := range l.blocks {
+		root.appendChild(block.box)
+		for _, anchor := range block.anchors {
+			if _, exists := l.Anchors[anchor.name]; !exists {
+				l.Anchors[anchor.name] = anchor.y
+			}
+		}
+		end = block.nextY
+	}
+	root.Bounds.Height = end - root.Bounds.Y
+
+	l.Root = root
+	l.Height = root.Bounds.Height
+	l.Links = root.collectLinks(nil)
+}
+
+// relayout brings layout up to date after a change to the top-level nodes
+// of document at the indexes in changed, laying out only those again. The
+// nodes after them are moved by the change in height instead, unless the
+// margin above one of them changed as well, in which case it is laid out
+// again too. It reports false, leaving layout as it was, when document no
+// longer has the top-level nodes the layout was made from.
+func (r *HTMLRenderer) relayout(layout *DocumentLayout, document HTMLDocument, changed []int) bool {
+	if len(document.Root.Children) != len(layout.blocks) {
+		return false
+	}
+
+	dirty := make(map[int]bool, len(changed))
+	for _, index := range changed {
+		dirty[index] = true
+	}
+
+	ctx := layout.ctx
+	for i := range layout.blocks {
+		block := &layout.blocks[i]
+		if dirty[i] || block.marginAbove != ctx.MarginAbove {
+			*block = r.layoutBlock(document.Root.Children[i], &ctx)
+			continue
+		}
+		if dy := ctx.Y - block.y; dy != 0 {
+			block.shift(dy)
+		}
+		ctx.Y, ctx.MarginAbove = block.nextY, block.marginBelow
+	}
+
+	layout.assemble()
+	return true
+}
+
+// shift moves the block and everything in it down by dy.
+func (b *layoutBlock) shift(dy float32) {
+	b.box.shift(dy)
+	for i := range b.anchors {
+		b.anchors[i].y += dy
+	}
+	b.y += dy
+	b.nextY += dy
+}
+
+// shift moves the box and everything in it down by dy.
+func (b *LayoutBox) shift(dy float32) {
+	b.Bounds.Y += dy
+	for i := range b.Lines {
+		line := &b.Lines[i]
+		line.Bounds.Y += dy
+		for j := range line.Fragments {
+			line.Fragments[j].Bounds.Y += dy
+		}
+	}
+	for i := range b.Children {
+		b.Children[i].shift(dy)
+	}
+}
+
+// collectLinks appends the areas of the linked text in the box and its
+// children to links.
+func (b *LayoutBox) collectLinks(links []LinkArea) []LinkArea {
+	for _, line := range b.Lines {
+		for _, fragment := range line.Fragments {
+			if fragment.Href != "" {
+				links = append(links, LinkArea{Bounds: fragment.Bounds, URL: fragment.Href})
+			}
+		}
+	}
+	for i := range b.Children {
+		links = b.Children[i].collectLinks(links)
+	}
+	return links
+}
@@ -0,0 +1,238 @@
+package marquee
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// fixedLayout lays html out at width with FixedMetrics, where a character
+// of the 16px regular font is 8px wide and a line of it 20px tall.
+func fixedLayout(t *testing.T, html string, width float32) *DocumentLayout {
+	t.Helper()
+	r := NewHTMLRenderer()
+	r.Measurer = FixedMetrics{}
+	return r.LayoutDocument(parse(t, html), RenderContext{Width: width})
+}
+
+// lineTexts returns the text of each line in the box and its children.
+func lineTexts(b LayoutBox) []string {
+	var texts []string
+	for _, line := range b.Lines {
+		var text strings.Builder
+		for _, fragment := range line.Fragments {
+			text.WriteString(fragment.Text)
+		}
+		texts = append(texts, text.String())
+	}
+	for _, child := range b.Children {
+		texts = append(texts, lineTexts(child)...)
+	}
+	return texts
+}
+
+func TestLayoutWrapsLines(t *testing.T) {
+	layout := fixedLayout(t, "<p>one two three four five six seven</p>", 100)
+
+	p := layout.Root.Children[0]
+	if got, want := lineTexts(p), []string{"one two", "three four", "five six", "seven"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+	for i, line := range p.Lines {
+		if want := rl.NewRectangle(0, float32(i*20), line.Bounds.Width, 20); line.Bounds != want {
+			t.Errorf("line %d bounds = %v, want %v", i, line.Bounds, want)
+		}
+	}
+
+	last := p.Lines[2].Fragments
+	if got, want := last[2].Bounds, rl.NewRectangle(40, 40, 24, 16); got != want {
+		t.Errorf("bounds of %q = %v, want %v", last[2].Text, got, want)
+	}
+	if layout.Height != 85 {
+		t.Errorf("Height = %v, want 85", layout.Height)
+	}
+}
+
+func TestLayoutCollapsesMargins(t *testing.T) {
+	layout := fixedLayout(t, `<p>one</p><p style="margin-top: 30px">two</p><p>three</p>`, 200)
+
+	var tops []float32
+	for _, box := range layout.Root.Children {
+		tops = append(tops, box.Bounds.Y)
+	}
+	// The 5px margins between paragraphs collapse with each other and
+	// with the 30px margin, rather than adding up.
+	if want := []float32{0, 50, 75}; !reflect.DeepEqual(tops, want) {
+		t.Errorf("paragraph tops = %v, want %v", tops, want)
+	}
+}
+
+func TestLayoutAlignsText(t *testing.T) {
+	tests := []struct {
+		align string
+		want  []float32
+	}{
+		{"left", []float32{0, 24, 48}},
+		{"center", []float32{24, 48, 72}},
+		{"right", []float32{48, 72, 96}},
+		// The last line of a justified paragraph is not stretched, so a
+		// second line is needed to see the difference.
+		{"justify", []float32{0, 48, 96}},
+	}
+
+	for _, test := range tests {
+		html := `<p style="text-align: ` + test.align + `">ab cd efg hijklmnopqr</p>`
+		layout := fixedLayout(t, html, 120)
+
+		var got []float32
+		for _, fragment := range layout.Root.Children[0].Lines[0].Fragments {
+			if fragment.Text != " " {
+				got = append(got, fragment.Bounds.X)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: word positions = %v, want %v", test.align, got, test.want)
+		}
+	}
+}
+
+func TestLayoutAnchorsAndLinks(t *testing.T) {
+	layout := fixedLayout(t, `<h1 id="top">Title</h1><p>next <a href="#top">link</a></p><p id="top">again</p>`, 200)
+
+	if got := layout.Anchors["top"]; got != 0 {
+		t.Errorf("anchor top = %v, want 0: the first element with an id wins", got)
+	}
+
+	want := []LinkArea{{Bounds: rl.NewRectangle(40, 80, 32, 16), URL: "#top"}}
+	if !reflect.DeepEqual(layout.Links, want) {
+		t.Errorf("Links = %v, want %v", layout.Links, want)
+	}
+}
+
+func TestLayoutSkipsHiddenAndMetadata(t *testing.T) {
+	layout := fixedLayout(t, `<title>T</title><style>p { color: red }</style><p style="display: none">y</p><p>z</p>`, 200)
+
+	if got := lineTexts(layout.Root); !reflect.DeepEqual(got, []string{"z"}) {
+		t.Errorf("lines = %q, want only the visible paragraph", got)
+	}
+	if layout.Height != 25 {
+		t.Errorf("Height = %v, want 25", layout.Height)
+	}
+}
+
+func TestInlineCodeStyle(t *testing.T) {
+	doc := parse(t, `<p>x <code style="color: #00ff00; background: #000000; font-size: 20px">code</code></p>`)
+	r := NewHTMLRenderer()
+	r.Measurer = FixedMetrics{}
+	styleDocument(&doc, r.StyleSheet, ColorSchemeLight)
+
+	code := doc.Root.Children[0].Children[1]
+	ctx := RenderContext{Renderer: r, CurrentX: 16, ParentFont: r.Theme.Fonts.Regular, ParentColor: r.Theme.Colors.Text}
+	box := (&CodeRenderHandler{}).Render(code, ctx).Box
+
+	if want := rl.NewColor(0, 0, 0, 255); box.Background != want {
+		t.Errorf("Background = %v, want %v", box.Background, want)
+	}
+	fragment := box.Lines[0].Fragments[0]
+	if fragment.Font.Size != 20 || fragment.Font.Family != FamilyMonospace {
+		t.Errorf("Font = %+v, want 20px monospace", fragment.Font)
+	}
+	if want := rl.NewColor(0, 0xff, 0, 0xff); fragment.Color != want {
+		t.Errorf("Color = %v, want %v", fragment.Color, want)
+	}
+}
+
+func TestLayoutLineHeight(t *testing.T) {
+	tests := []struct {
+		lineHeight, scale float32
+		want              float32
+	}{
+		{20, 1.25, 20},
+		{30, 1.25, 20},
+		{0, 1.5, 24},
+		{20, 1, 16},
+		{20, 0.75, 12},
+		{30, 0, 30},
+	}
+
+	for _, test := range tests {
+		r := NewHTMLRenderer()
+		r.Measurer = FixedMetrics{}
+		r.Theme.Spacing.LineHeight = test.lineHeight
+		r.Theme.Spacing.LineHeightScale = test.scale
+		layout := r.LayoutDocument(parse(t, "<p>one two</p>"), RenderContext{Width: 40})
+
+		lines := layout.Root.Children[0].Lines
+		if got := lines[1].Bounds.Y - lines[0].Bounds.Y; got != test.want {
+			t.Errorf("LineHeight %v, LineHeightScale %v: lines %v apart, want %v", test.lineHeight, test.scale, got, test.want)
+		}
+	}
+}
+
+func TestLayoutPreLineHeight(t *testing.T) {
+	tests := []struct {
+		lineHeight, scale float32
+		want              float32
+	}{
+		{18, 1.25, 20},
+		{18, 0.75, 12},
+		{18, 0, 18},
+	}
+
+	for _, test := range tests {
+		r := NewHTMLRenderer()
+		r.Measurer = FixedMetrics{}
+		r.Theme.Spacing.PreLineHeight = test.lineHeight
+		r.Theme.Spacing.PreLineHeightScale = test.scale
+		layout := r.LayoutDocument(parse(t, "<pre>one\ntwo</pre>"), RenderContext{Width: 200})
+
+		lines := layout.Root.Children[0].Lines
+		if got := lines[1].Bounds.Y - lines[0].Bounds.Y; got != test.want {
+			t.Errorf("PreLineHeight %v, PreLineHeightScale %v: lines %v apart, want %v", test.lineHeight, test.scale, got, test.want)
+		}
+	}
+}
+
+func TestLayoutListMarkers(t *testing.T) {
+	red := rl.Color{R: 255, A: 255}
+	tests := []struct {
+		html   string
+		marker string
+		size   float32
+		color  rl.Color
+	}{
+		{`<ul><li>a</li></ul>`, "•", 18, rl.Black},
+		{`<ul><li style="font-size: 32px">a</li></ul>`, "•", 36, rl.Black},
+		{`<ol><li>a</li></ol>`, "1.", 16, rl.Black},
+		{`<ol style="font-size: 24px"><li>a</li></ol>`, "1.", 24, rl.Black},
+		{`<ul><li style="color: red">a</li></ul>`, "•", 18, red},
+	}
+
+	for _, test := range tests {
+		layout := fixedLayout(t, test.html, 200)
+		item := layout.Root.Children[0].Children[0]
+		marker, text := item.Lines[0].Fragments[0], item.Lines[1].Fragments[0]
+
+		if marker.Text != test.marker || marker.Font.Size != test.size || marker.Color != test.color {
+			t.Errorf("%s: marker %q size %v color %v, want %q size %v color %v",
+				test.html, marker.Text, marker.Font.Size, marker.Color, test.marker, test.size, test.color)
+		}
+		gap := text.Bounds.X - (marker.Bounds.X + marker.Bounds.Width)
+		if want := LightTheme().Spacing.ListMarkerGap; gap != want {
+			t.Errorf("%s: marker ends %v before the text, want %v", test.html, gap, want)
+		}
+	}
+}
+
+func TestLayoutHeadingSizeFallback(t *testing.T) {
+	r := NewHTMLRenderer()
+	r.Measurer = FixedMetrics{}
+	r.Theme.Fonts.H2 = Font{}
+	layout := r.LayoutDocument(parse(t, "<h2>a</h2>"), RenderContext{Width: 200})
+
+	if got := layout.Root.Children[0].Lines[0].Fragments[0].Font.Size; got != 28 {
+		t.Errorf("h2 without a theme size is %v pixels, want 28", got)
+	}
+}
@@ -2,6 +2,7 @@ package marquee
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -26,16 +27,83 @@ type HTMLWidget struct {
 	BodyPadding    float32
 	OnLinkClick    func(string)
 
-	document  HTMLDocument
-	parser    *StateMachineParser
-	renderer  *HTMLRenderer
-	textCache *TextMeasureCache
+	// ScrollRestore says where SetContent, SetDocument and SetStream leave
+	// the view.
+	ScrollRestore ScrollRestore
+
+	// Canvas is what Render paints the document on. When it is nil, the
+	// document is painted on the raylib window.
+	Canvas Canvas
+
+	// Theme is how the document looks in the light color scheme. Its fonts
+	// are replaced by Fonts when the document is laid out; SetTheme sets
+	// both.
+	Theme Theme
+
+	// DarkTheme takes the place of Theme when the color scheme is dark.
+	DarkTheme Theme
+
+	document HTMLDocument
+	parser   *StateMachineParser
+	renderer *HTMLRenderer
+	fonts    *raylibFonts
+	window   *raylibCanvas
+
+	stream           *StreamParser
+	streamGeneration int
+	documentVersion  int
+
+	// styleSheet is the style sheet given to SetStyleSheet, or nil.
+	styleSheet *StyleSheet
+
+	// colorScheme is the scheme given to SetColorScheme, and
+	// hostColorScheme the one given to SetHostColorScheme.
+	colorScheme     ColorScheme
+	hostColorScheme ColorScheme
+
+	// layout is the document laid out for the current content width, or
+	// nil when it has to be laid out again. changedBlocks holds the indexes
+	// of the top-level nodes that changed since it was.
+	layout        *DocumentLayout
+	changedBlocks []int
+
+	// documentStyles are the <style> elements the document was last styled
+	// with as a whole.
+	documentStyles []StyleInfo
+
+	anchors       map[string]float32
+	pendingAnchor string
+	pendingOffset float32
 
 	linkAreaPool []LinkArea
 	poolCapacity int
 }
 
 func NewHTMLWidget(content string) *HTMLWidget {
+	return NewHTMLWidgetWithOptions(content, DefaultParserOptions())
+}
+
+// NewHTMLWidgetWithOptions creates a widget whose parser enforces the limits
+// in options.
+func NewHTMLWidgetWithOptions(content string, options ParserOptions) *HTMLWidget {
+	widget := newHTMLWidget(content, options)
+	widget.parseHTML(content)
+
+	return widget
+}
+
+// NewHTMLWidgetFromStream creates a widget that shows the document stream is
+// parsing. Until the stream is closed, each Render picks up whatever has
+// arrived since the last one, so a page can be read while it loads.
+func NewHTMLWidgetFromStream(stream *StreamParser) *HTMLWidget {
+	widget := newHTMLWidget("", stream.Options())
+	widget.stream = stream
+	widget.syncStream()
+
+	return widget
+}
+
+func newHTMLWidget(content string, options ParserOptions) *HTMLWidget {
 	widget := &HTMLWidget{
 		Content:        content,
 		LinkAreas:      make([]LinkArea, 0),
@@ -44,66 +112,145 @@ func NewHTMLWidget(content string) *HTMLWidget {
 		BodyMargin:     10.0,
 		BodyBorder:     1.0,
 		BodyPadding:    15.0,
-		textCache:      NewTextMeasureCache(1000),
-		parser:         NewStateMachineParser(),
+		Fonts:          DefaultFonts(),
+		Theme:          LightTheme(),
+		DarkTheme:      DarkTheme(),
+		parser:         NewStateMachineParser(options),
 		renderer:       NewHTMLRenderer(),
+		fonts:          newRaylibFonts(),
 
 		linkAreaPool: make([]LinkArea, 100),
 		poolCapacity: 100,
 	}
 
+	widget.window = newRaylibCanvas(widget.fonts)
+	widget.renderer.Measurer = widget.fonts
 	widget.loadFonts()
-	widget.parseHTML(content)
 
 	return widget
 }
 
+// loadFonts loads the fonts in Fonts up front, so that missing ones are
+// reported when the widget is created rather than on the first frame.
 func (w *HTMLWidget) loadFonts() {
 	fm := getFontManager()
 
-	w.Fonts = FontSet{
-		Regular:        fm.GetFont("arial", 16),
-		Bold:           fm.GetFont("arial-bold", 16),
-		Italic:         fm.GetFont("arial-italic", 16),
-		BoldItalic:     fm.GetFont("arial-bold", 16),
-		H1:             fm.GetFont("arial", 32),
-		H2:             fm.GetFont("arial", 28),
-		H3:             fm.GetFont("arial", 24),
-		H4:             fm.GetFont("arial", 20),
-		H5:             fm.GetFont("arial", 18),
-		H6:             fm.GetFont("arial", 16),
-		Monospace:      fm.GetMonospaceFont(14),
-		MonospaceLarge: fm.GetMonospaceFont(16),
-	}
-
-	if !fm.GetFontStatus("arial", 16) {
+	for _, font := range []Font{
+		w.Fonts.Regular, w.Fonts.Bold, w.Fonts.Italic, w.Fonts.BoldItalic,
+		w.Fonts.H1, w.Fonts.H2, w.Fonts.H3, w.Fonts.H4, w.Fonts.H5, w.Fonts.H6,
+		w.Fonts.Monospace, w.Fonts.MonospaceLarge,
+	} {
+		w.fonts.font(font)
+	}
+
+	if !fm.GetFontStatus(raylibFontName(w.Fonts.Regular), fontPixelSize(w.Fonts.Regular)) {
 		fmt.Printf("Warning: Regular font failed to load, using system default\n")
 	}
-	if !fm.GetFontStatus("arial-bold", 16) {
+	if !fm.GetFontStatus(raylibFontName(w.Fonts.Bold), fontPixelSize(w.Fonts.Bold)) {
 		fmt.Printf("Warning: Bold font failed to load, formatting may be limited\n")
 	}
-	if !fm.GetFontStatus("arial-italic", 16) {
+	if !fm.GetFontStatus(raylibFontName(w.Fonts.Italic), fontPixelSize(w.Fonts.Italic)) {
 		fmt.Printf("Warning: Italic font failed to load, formatting may be limited\n")
 	}
 }
 
-func (w *HTMLWidget) measureText(font rl.Font, text string, fontSize float32) rl.Vector2 {
-	return w.textCache.GetTextSize(font, text, fontSize)
+func (w *HTMLWidget) parseHTML(html string) {
+	w.setDocument(w.parser.Parse(html))
+}
+
+func (w *HTMLWidget) setDocument(document HTMLDocument) {
+	w.document = document
+	w.document.changedNodes = nil
+	w.documentVersion = w.document.version
+	linkParents(&w.document.Root)
+	w.styleWholeDocument()
+
+	w.Elements = w.createLegacyElementsForAPI()
+	w.layout = nil
 }
 
-func (w *HTMLWidget) measureTextWidth(font rl.Font, text string, fontSize float32) float32 {
-	return w.textCache.GetTextWidth(font, text, fontSize)
+// styleWholeDocument resolves the computed style of every node of the
+// document, noting the <style> elements it was styled with.
+func (w *HTMLWidget) styleWholeDocument() {
+	styleDocument(&w.document, w.styleSheet, w.ColorScheme())
+	w.documentStyles = append(w.documentStyles[:0], w.document.Metadata.StyleSheets...)
 }
 
-func (w *HTMLWidget) parseHTML(html string) {
-	w.document = w.parser.Parse(html)
+// applyDocumentChanges brings the widget up to date with changes made
+// through the HTMLDocument methods since the last frame. Only the changed
+// subtrees are restyled, and only the top-level nodes they are in laid out
+// again, unless a change moved one of them or changed a <style> element, in
+// which case the whole document is.
+func (w *HTMLWidget) applyDocumentChanges() {
+	if w.document.version == w.documentVersion {
+		return
+	}
+	w.documentVersion = w.document.version
 
+	nodes, all := w.document.takeChanges()
 	w.Elements = w.createLegacyElementsForAPI()
+
+	if all || !slices.Equal(w.documentStyles, w.document.Metadata.StyleSheets) {
+		w.styleWholeDocument()
+		w.layout = nil
+		return
+	}
+
+	// A selector only depends on an element, its ancestors and its position
+	// among its siblings, and those only change for the children of a
+	// changed node, so restyling the changed subtrees is enough.
+	rules := newCascade(&w.document, w.styleSheet, w.ColorScheme())
+	for _, node := range nodes {
+		rules.computeStyles(node, inheritedStyle(node), elementPath(node))
+
+		if index := w.document.topLevelIndex(node); index >= 0 {
+			w.changedBlocks = append(w.changedBlocks, index)
+		} else {
+			w.layout = nil
+		}
+	}
+}
+
+// inheritedStyle returns the computed style node inherits from its parent.
+func inheritedStyle(node *HTMLNode) ComputedStyle {
+	if node.Parent != nil && node.Parent.Type == NodeTypeElement {
+		return node.Parent.Style
+	}
+	return ComputedStyle{}
+}
+
+// syncStream takes the latest document from the stream the widget was
+// created with, if it has changed, and lets go of the stream once it is
+// closed.
+func (w *HTMLWidget) syncStream() {
+	if w.stream == nil {
+		return
+	}
+
+	closed := w.stream.Closed()
+	generation := w.stream.Generation()
+	if generation != w.streamGeneration {
+		w.setDocument(w.stream.Document())
+		w.streamGeneration = generation
+	}
+	if closed {
+		w.stream = nil
+	}
+}
+
+// Loading reports whether the widget is still showing a stream that has not
+// been closed.
+func (w *HTMLWidget) Loading() bool {
+	w.syncStream()
+	return w.stream != nil
 }
 
 func (w *HTMLWidget) createLegacyElementsForAPI() []HTMLElement {
 	var elements []HTMLElement
 	for _, node := range w.document.Root.Children {
+		if node.Type == NodeTypeComment {
+			continue
+		}
 		elements = append(elements, w.nodeToLegacyElement(node))
 	}
 	return elements
@@ -122,63 +269,19 @@ func (w *HTMLWidget) nodeToLegacyElement(node HTMLNode) HTMLElement {
 		}
 	}
 
-	if node.Tag == "span" {
-		if style, exists := node.Attributes["style"]; exists {
-			element.Bold = strings.Contains(style, "font-weight: bold")
-			element.Italic = strings.Contains(style, "font-style: italic")
-		}
-	}
+	element.Bold = node.Style.Bold
+	element.Italic = node.Style.Italic
 
 	for _, child := range node.Children {
+		if child.Type == NodeTypeComment {
+			continue
+		}
 		element.Children = append(element.Children, w.nodeToLegacyElement(child))
 	}
 
 	return element
 }
 
-func (w *HTMLWidget) renderTextWithUnicode(text string, x, y float32, font rl.Font, color rl.Color) {
-	renderTextWithUnicode(text, x, y, font, color)
-}
-
-func (w *HTMLWidget) renderText(text string, x, y, width float32, font rl.Font, color rl.Color) float32 {
-	if text == "" {
-		return y
-	}
-
-	ph := &ParagraphRenderHandler{}
-	words := ph.intelligentWordSplit(text)
-	currentLine := ""
-	lineHeight := float32(20)
-	currentY := y
-
-	rightMargin := w.BodyMargin + w.BodyPadding
-
-	for _, word := range words {
-		testLine := currentLine
-		if testLine != "" {
-			testLine += " "
-		}
-		testLine += word
-
-		textWidth := w.measureTextWidth(font, testLine, float32(font.BaseSize))
-
-		if textWidth > width-rightMargin && currentLine != "" {
-			w.renderTextWithUnicode(currentLine, x, currentY, font, color)
-			currentY += lineHeight
-			currentLine = word
-		} else {
-			currentLine = testLine
-		}
-	}
-
-	if currentLine != "" {
-		w.renderTextWithUnicode(currentLine, x, currentY, font, color)
-		currentY += lineHeight
-	}
-
-	return currentY + 5
-}
-
 func (w *HTMLWidget) Update() {
 	rl.SetMouseCursor(rl.MouseCursorDefault)
 
@@ -218,11 +321,16 @@ func (w *HTMLWidget) Update() {
 			} else {
 				fmt.Printf("Clicked link: %s\n", area.URL)
 			}
+			// The handler may have replaced the content, leaving the
+			// remaining link areas stale until the next Render.
+			return
 		}
 	}
 }
 
 func (w *HTMLWidget) Render(x, y, width, height float32) {
+	w.syncStream()
+	w.applyDocumentChanges()
 
 	if cap(w.LinkAreas) < w.poolCapacity {
 		w.LinkAreas = make([]LinkArea, 0, w.poolCapacity)
@@ -231,56 +339,285 @@ func (w *HTMLWidget) Render(x, y, width, height float32) {
 	}
 	w.WidgetHeight = height
 
-	rl.DrawRectangle(int32(x), int32(y), int32(width), int32(height), rl.White)
+	canvas := w.canvas()
+	bounds := rl.NewRectangle(x, y, width, height)
+	theme := w.theme()
+	canvas.FillRect(bounds, theme.Colors.Background)
 
 	if w.BodyBorder > 0 {
-		borderColor := rl.Color{R: 200, G: 200, B: 200, A: 255}
-		rl.DrawRectangleLinesEx(
-			rl.NewRectangle(x, y, width, height),
-			w.BodyBorder,
-			borderColor)
+		canvas.StrokeRect(bounds, w.BodyBorder, theme.Colors.Border)
 	}
 
+	contentWidth := width - 2*(w.BodyMargin+w.BodyPadding)
+	layout := w.layoutFor(contentWidth)
+
+	w.anchors = layout.Anchors
+	w.TotalHeight = layout.Height + 2*(w.BodyMargin+w.BodyPadding)
+	w.restorePendingAnchor()
+
 	contentX := x + w.BodyMargin + w.BodyPadding
 	contentY := y + w.BodyMargin + w.BodyPadding - w.ScrollY
-	contentWidth := width - 2*(w.BodyMargin+w.BodyPadding)
 
-	rl.BeginScissorMode(int32(x), int32(y), int32(width), int32(height))
+	for _, linkArea := range layout.Links {
+		screenArea := linkArea
+		screenArea.Bounds.X += contentX
+		screenArea.Bounds.Y += contentY + w.ScrollY
+		w.LinkAreas = append(w.LinkAreas, screenArea)
+	}
+
+	canvas.PushClip(bounds)
+	layout.Root.Paint(canvas, contentX, contentY, bounds)
+	canvas.PopClip()
+
+	if w.TotalHeight > height {
+		w.drawScrollbar(canvas, x, y, width, height)
+	}
+}
+
+func (w *HTMLWidget) canvas() Canvas {
+	if w.Canvas != nil {
+		return w.Canvas
+	}
+	return w.window
+}
+
+// layoutFor returns the document laid out for width, reusing the last
+// layout as long as neither has changed.
+func (w *HTMLWidget) layoutFor(width float32) *DocumentLayout {
+	changed := w.changedBlocks
+	w.changedBlocks = nil
+	if w.layout != nil && w.layout.Width == width {
+		if len(changed) == 0 || w.renderer.relayout(w.layout, w.document, changed) {
+			return w.layout
+		}
+	}
 
+	w.renderer.Theme = w.theme()
+	w.renderer.Theme.Fonts = w.Fonts
 	ctx := RenderContext{
-		X:           contentX,
-		Y:           contentY,
-		Width:       contentWidth,
-		ParentFont:  w.Fonts.Regular,
-		ParentColor: rl.Black,
+		Width:       width,
+		RightMargin: w.BodyMargin + w.BodyPadding,
 		Widget:      w,
-		CurrentX:    contentX,
 	}
+	w.layout = w.renderer.LayoutDocument(w.document, ctx)
+	return w.layout
+}
 
-	result := w.renderer.RenderDocument(w.document, ctx)
+// InvalidateLayout makes the next Render lay the document out again. Changes
+// to the content and the width are picked up on their own; call it after
+// changing Fonts, Theme, DarkTheme or a registered handler's settings.
+func (w *HTMLWidget) InvalidateLayout() {
+	w.layout = nil
+}
 
-	w.TotalHeight = result.NextY + w.ScrollY - contentY + 2*(w.BodyMargin+w.BodyPadding)
+// SetTheme switches the widget to theme, fonts included, from the next
+// Render. It replaces the theme of the color scheme in use: Theme, or
+// DarkTheme when the scheme is dark. The document is laid out again but not
+// parsed or styled again, and the scroll position is kept.
+func (w *HTMLWidget) SetTheme(theme Theme) {
+	if w.ColorScheme() == ColorSchemeDark {
+		w.DarkTheme = theme
+	} else {
+		w.Theme = theme
+	}
+	w.Fonts = theme.Fonts
+	w.layout = nil
+}
 
-	for _, linkArea := range result.LinkAreas {
+// theme returns the theme of the color scheme in use.
+func (w *HTMLWidget) theme() Theme {
+	if w.ColorScheme() == ColorSchemeDark {
+		return w.DarkTheme
+	}
+	return w.Theme
+}
 
-		screenArea := linkArea
-		screenArea.Bounds.Y += w.ScrollY
-		w.LinkAreas = append(w.LinkAreas, screenArea)
+// ColorScheme returns the color scheme in use, light or dark.
+func (w *HTMLWidget) ColorScheme() ColorScheme {
+	return w.colorScheme.resolve(w.hostColorScheme)
+}
+
+// SetColorScheme makes the widget light or dark, which chooses between
+// Theme and DarkTheme and decides which @media (prefers-color-scheme) rules
+// apply. ColorSchemeAuto, the default, follows SetHostColorScheme. When the
+// scheme in use changes, the document is styled and laid out again from the
+// next Render, but not parsed again, and the scroll position is kept.
+func (w *HTMLWidget) SetColorScheme(scheme ColorScheme) {
+	w.switchColorScheme(scheme, w.hostColorScheme)
+}
+
+// SetHostColorScheme tells the widget whether the host prefers light or
+// dark, such as the system's dark mode setting or the game's own, for
+// ColorSchemeAuto. Call it again whenever the preference changes.
+func (w *HTMLWidget) SetHostColorScheme(scheme ColorScheme) {
+	w.switchColorScheme(w.colorScheme, scheme)
+}
+
+func (w *HTMLWidget) switchColorScheme(scheme, host ColorScheme) {
+	before := w.ColorScheme()
+	w.colorScheme, w.hostColorScheme = scheme, host
+	if w.ColorScheme() == before {
+		return
 	}
 
-	rl.EndScissorMode()
+	w.styleWholeDocument()
+	w.Elements = w.createLegacyElementsForAPI()
+	w.layout = nil
+}
+
+// SetStyleSheet gives the widget style rules, in CSS, for every document it
+// shows. They apply before the rules of the document's own <style>
+// elements, so that a document can still override them. The document is
+// styled and laid out again, but not parsed again, and the scroll position
+// is kept. An empty css removes the rules.
+func (w *HTMLWidget) SetStyleSheet(css string) {
+	w.styleSheet = nil
+	if strings.TrimSpace(css) != "" {
+		w.styleSheet = ParseStyleSheet(css)
+	}
+	w.styleWholeDocument()
+	w.Elements = w.createLegacyElementsForAPI()
+	w.layout = nil
+}
 
-	if w.TotalHeight > height {
-		w.drawScrollbar(x, y, width, height)
+// ScrollRestore says where the view is left when SetContent, SetDocument or
+// SetStream replaces the document.
+type ScrollRestore int
+
+const (
+	// RestoreByAnchor keeps the element with an id that is nearest above
+	// the top of the view in the same place, so a reloaded page stays where
+	// the reader was even if content above it changed. Without such an
+	// element the scroll offset is kept.
+	RestoreByAnchor ScrollRestore = iota
+	// RestoreOffset keeps the scroll offset.
+	RestoreOffset
+	// RestoreTop scrolls back to the top.
+	RestoreTop
+)
+
+// SetContent replaces the document with html, parsed in place. Fonts,
+// callbacks and settings are kept, and the view is left according to
+// ScrollRestore.
+func (w *HTMLWidget) SetContent(html string) {
+	w.prepareForContent()
+	w.Content = html
+	w.parseHTML(html)
+}
+
+// SetDocument replaces the document with one parsed or built elsewhere, as
+// SetContent does for HTML.
+func (w *HTMLWidget) SetDocument(document HTMLDocument) {
+	w.prepareForContent()
+	w.Content = ""
+	w.setDocument(document)
+}
+
+// SetStream replaces the document with the one stream is parsing, which is
+// picked up as it arrives, as with NewHTMLWidgetFromStream.
+func (w *HTMLWidget) SetStream(stream *StreamParser) {
+	w.prepareForContent()
+	w.Content = ""
+	w.stream = stream
+	w.streamGeneration = stream.Generation()
+	w.setDocument(stream.Document())
+	w.syncStream()
+}
+
+// prepareForContent stops showing a stream and records what is needed to
+// restore the view once the new document has been laid out.
+func (w *HTMLWidget) prepareForContent() {
+	w.stream = nil
+	w.pendingAnchor = ""
+
+	switch w.ScrollRestore {
+	case RestoreTop:
+		w.ScrollY = 0
+		w.TargetScrollY = 0
+	case RestoreByAnchor:
+		best, bestY := "", float32(0)
+		for id, anchorY := range w.anchors {
+			if anchorY > w.ScrollY {
+				continue
+			}
+			if best == "" || anchorY > bestY || anchorY == bestY && id < best {
+				best, bestY = id, anchorY
+			}
+		}
+		if best != "" {
+			w.pendingAnchor = best
+			w.pendingOffset = w.ScrollY - bestY
+		}
+	}
+}
+
+// ScrollToAnchor scrolls to the element whose id, or whose name for <a>, is
+// id, as following a "#id" link does. It reports whether the document has
+// such an element; the view moves on the next Render.
+func (w *HTMLWidget) ScrollToAnchor(id string) bool {
+	found := false
+	w.document.Walk(func(node *HTMLNode) WalkAction {
+		if node.Type == NodeTypeElement && anchorName(*node) == id {
+			found = true
+			return WalkStop
+		}
+		return WalkContinue
+	}, nil)
+
+	if found {
+		w.pendingAnchor = id
+		w.pendingOffset = 0
+	}
+	return found
+}
+
+// anchorName returns the name a "#fragment" link can use to reach node: its
+// id, or the name of an <a name="...">.
+func anchorName(node HTMLNode) string {
+	if id := node.Attributes["id"]; id != "" {
+		return id
+	}
+	if node.Tag == "a" {
+		return node.Attributes["name"]
 	}
+	return ""
 }
 
-func (w *HTMLWidget) drawScrollbar(x, y, width, height float32) {
+// restorePendingAnchor moves the view to the anchor waiting to be restored,
+// now that the layout has placed it.
+func (w *HTMLWidget) restorePendingAnchor() {
+	if w.pendingAnchor == "" {
+		return
+	}
+
+	anchorY, exists := w.anchors[w.pendingAnchor]
+	if !exists {
+		// A page that is still loading may not have reached it yet.
+		if w.stream == nil {
+			w.pendingAnchor = ""
+		}
+		return
+	}
+	w.pendingAnchor = ""
+
+	scrollY := anchorY + w.pendingOffset
+	if maxScroll := w.TotalHeight - w.WidgetHeight; scrollY > maxScroll {
+		scrollY = maxScroll
+	}
+	if scrollY < 0 {
+		scrollY = 0
+	}
+	w.ScrollY = scrollY
+	w.TargetScrollY = scrollY
+}
+
+func (w *HTMLWidget) drawScrollbar(canvas Canvas, x, y, width, height float32) {
 	if w.TotalHeight <= height || w.ScrollbarAlpha <= 0.01 {
 		return
 	}
 
-	scrollbarWidth := float32(10)
+	style := w.theme().Scrollbar
+	scrollbarWidth := style.Width
 
 	contentMargin := w.BodyMargin + w.BodyPadding
 	scrollbarX := x + width - scrollbarWidth - contentMargin
@@ -289,8 +626,8 @@ func (w *HTMLWidget) drawScrollbar(x, y, width, height float32) {
 	trackY := y + contentMargin
 	thumbHeight := contentArea * 0.2
 
-	if thumbHeight < 40 {
-		thumbHeight = 40
+	if thumbHeight < style.MinThumbHeight {
+		thumbHeight = style.MinThumbHeight
 	}
 	if thumbHeight > contentArea*0.8 {
 		thumbHeight = contentArea * 0.8
@@ -312,49 +649,49 @@ func (w *HTMLWidget) drawScrollbar(x, y, width, height float32) {
 	trackHeight := contentArea - thumbHeight
 	thumbY := trackY + scrollProgress*trackHeight
 
-	alpha := uint8(w.ScrollbarAlpha * 120)
-	thumbColor := rl.Color{R: 60, G: 60, B: 60, A: alpha}
+	thumbColor := style.Color
+	thumbColor.A = uint8(w.ScrollbarAlpha * float32(style.Color.A))
 
-	rl.DrawRectangle(
-		int32(scrollbarX),
-		int32(thumbY),
-		int32(scrollbarWidth),
-		int32(thumbHeight),
-		thumbColor)
+	canvas.FillRect(rl.NewRectangle(scrollbarX, thumbY, scrollbarWidth, thumbHeight), thumbColor)
 }
 
 func (w *HTMLWidget) Unload() {
-	fm := getFontManager()
-
-	fm.ReleaseFont("arial", 16)
-	fm.ReleaseFont("arial-bold", 16)
-	fm.ReleaseFont("arial-italic", 16)
-	fm.ReleaseFont("arial-bold", 16)
-	fm.ReleaseFont("arial", 32)
-	fm.ReleaseFont("arial", 28)
-	fm.ReleaseFont("arial", 24)
-	fm.ReleaseFont("arial", 20)
-	fm.ReleaseFont("arial", 18)
-	fm.ReleaseFont("arial", 16)
-
-	fm.ReleaseMonospaceFont(14)
-	fm.ReleaseMonospaceFont(16)
-
-	if w.textCache != nil {
-		w.textCache.Clear()
-	}
+	w.window.unload()
+	w.fonts.unload()
+	w.layout = nil
 }
 
 func (w *HTMLWidget) RegisterRenderHandler(elementType string, handler RenderHandler) {
 	w.renderer.RegisterHandler(elementType, handler)
+	w.layout = nil
 }
 
 func (w *HTMLWidget) GetRenderer() *HTMLRenderer {
 	return w.renderer
 }
 
-func (w *HTMLWidget) GetDocument() HTMLDocument {
-	return w.document
+// GetDocument returns the document the widget shows. Changes made through
+// its methods, such as SetText and AppendChild, appear from the next Render
+// without reparsing or reloading fonts, and the scroll position is kept.
+func (w *HTMLWidget) GetDocument() *HTMLDocument {
+	return &w.document
+}
+
+// Title returns the text of the document's <title>, or "" if it has none.
+func (w *HTMLWidget) Title() string {
+	return w.document.Metadata.Title
+}
+
+// Diagnostics returns the problems the parser found in the content, in the
+// order it found them. An empty result means the markup was well formed.
+func (w *HTMLWidget) Diagnostics() []ParseDiagnostic {
+	return w.document.Diagnostics
+}
+
+// Metadata returns what the document declares about itself: its title,
+// DOCTYPE, meta tags, stylesheets and scripts.
+func (w *HTMLWidget) Metadata() DocumentMetadata {
+	return w.document.Metadata
 }
 
 func (w *HTMLWidget) DebugDocument() {
@@ -371,6 +708,8 @@ func (w *HTMLWidget) debugNode(node HTMLNode, depth int) {
 		if content != "" {
 			fmt.Printf("%sTEXT: '%s'\n", indent, content)
 		}
+	} else if node.Type == NodeTypeComment {
+		fmt.Printf("%sCOMMENT: '%s'\n", indent, node.Content)
 	} else {
 		fmt.Printf("%s<%s", indent, node.Tag)
 		for k, v := range node.Attributes {
@@ -392,7 +731,7 @@ func (w *HTMLWidget) DebugFonts() {
 		name string
 		size int32
 	}{
-		{"arial", 16}, {"arial-bold", 16}, {"arial-italic", 16},
+		{"arial", 16}, {"arial-bold", 16}, {"arial-italic", 16}, {"arial-bold-italic", 16},
 		{"arial", 32}, {"arial", 28}, {"arial", 24}, {"arial", 20}, {"arial", 18},
 	}
 
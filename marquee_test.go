@@ -0,0 +1,259 @@
+package marquee
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// newTestWidget returns a widget showing html that lays text out with
+// FixedMetrics, without the fonts of a raylib window.
+func newTestWidget(html string) *HTMLWidget {
+	w := &HTMLWidget{
+		Fonts:     DefaultFonts(),
+		Theme:     LightTheme(),
+		DarkTheme: DarkTheme(),
+		parser:    NewStateMachineParser(DefaultParserOptions()),
+		renderer:  NewHTMLRenderer(),
+	}
+	w.renderer.Measurer = FixedMetrics{}
+	w.parseHTML(html)
+	return w
+}
+
+// checkLayout compares the widget's layout with one made from scratch.
+func checkLayout(t *testing.T, w *HTMLWidget, got *DocumentLayout) {
+	t.Helper()
+
+	want := w.renderer.LayoutDocument(w.document, RenderContext{Width: got.Width, Widget: w})
+	if got.Height != want.Height {
+		t.Errorf("Height = %v, want %v", got.Height, want.Height)
+	}
+	if !reflect.DeepEqual(got.Anchors, want.Anchors) {
+		t.Errorf("Anchors = %v, want %v", got.Anchors, want.Anchors)
+	}
+	if !reflect.DeepEqual(got.Links, want.Links) {
+		t.Errorf("Links = %v, want %v", got.Links, want.Links)
+	}
+	visible := rl.NewRectangle(0, 0, got.Width, max(got.Height, want.Height))
+	if gotOps, wantOps := recordPaint(got, 0, 0, visible), recordPaint(want, 0, 0, visible); !reflect.DeepEqual(gotOps, wantOps) {
+		t.Errorf("painted\n%s\nwant\n%s", strings.Join(gotOps, "\n"), strings.Join(wantOps, "\n"))
+	}
+}
+
+const relayoutDocument = `<style>.note { color: #c00; margin-bottom: 40px }</style>
+<h1 id="top">Title</h1>
+<p id="first">First paragraph.</p>
+<p id="middle" class="note">Middle <a href="#end">paragraph</a>.</p>
+<ul><li id="item">One</li><li>Two</li></ul>
+<p>Last paragraph with a <a href="#top">link</a>.</p>
+<h2 id="end">End</h2>`
+
+func TestWidgetRelayoutsChangedBlock(t *testing.T) {
+	w := newTestWidget(relayoutDocument)
+	before := w.layoutFor(300)
+	height := before.Height
+
+	doc := w.GetDocument()
+	middle, _ := doc.QuerySelector("#middle")
+	doc.SetText(middle, strings.Repeat("Much longer text that wraps. ", 10))
+	item, _ := doc.QuerySelector("#item")
+	doc.SetAttribute(item, "style", "font-size: 30px")
+
+	w.applyDocumentChanges()
+	after := w.layoutFor(300)
+	if after != before {
+		t.Fatalf("the document was laid out from scratch")
+	}
+	if after.Height <= height {
+		t.Errorf("Height = %v, want more than %v", after.Height, height)
+	}
+	checkLayout(t, w, after)
+
+	// The margin below the paragraph changes, which moves what follows it
+	// by a different amount than its height.
+	middle, _ = doc.QuerySelector("#middle")
+	if red := rl.NewColor(0xcc, 0, 0, 0xff); middle.Style.Color != red {
+		t.Errorf("color of #middle = %v, want %v", middle.Style.Color, red)
+	}
+	doc.SetAttribute(middle, "class", "")
+	w.applyDocumentChanges()
+	checkLayout(t, w, w.layoutFor(300))
+	if middle.Style.Color.A != 0 {
+		t.Errorf("color of #middle = %v after its class was removed", middle.Style.Color)
+	}
+}
+
+func TestWidgetRelayoutsWholeDocument(t *testing.T) {
+	w := newTestWidget(relayoutDocument)
+	before := w.layoutFor(300)
+
+	doc := w.GetDocument()
+	style, _ := doc.QuerySelector("style")
+	doc.SetText(style, "p { margin: 0 }")
+	w.applyDocumentChanges()
+
+	after := w.layoutFor(300)
+	if after == before {
+		t.Fatalf("the document was not laid out again after its style sheet changed")
+	}
+	checkLayout(t, w, after)
+	if first, _ := doc.QuerySelector("#first"); !first.Style.Margin.Top.IsSet() {
+		t.Errorf("#first was not restyled with the new style sheet")
+	}
+
+	before = after
+	doc.AppendChild(&doc.Root, NewElement("p", nil, NewText("Appended.")))
+	w.applyDocumentChanges()
+	if after = w.layoutFor(300); after == before {
+		t.Fatalf("the document was not laid out again after a node was added at the top level")
+	}
+	checkLayout(t, w, after)
+}
+
+func TestExternalRenderKeepsWidgetStyles(t *testing.T) {
+	w := newTestWidget(`<h1>Title</h1><p>Text with <b>bold</b>.</p>`)
+	w.SetStyleSheet("h1 { font-size: 30px } b { color: #c00 }")
+
+	var styles func(node HTMLNode) []ComputedStyle
+	styles = func(node HTMLNode) []ComputedStyle {
+		list := []ComputedStyle{node.Style}
+		for _, child := range node.Children {
+			list = append(list, styles(child)...)
+		}
+		return list
+	}
+	want := styles(w.GetDocument().Root)
+
+	if err := RenderTerminal(io.Discard, *w.GetDocument(), TerminalOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := styles(w.GetDocument().Root); !reflect.DeepEqual(got, want) {
+		t.Errorf("RenderTerminal changed the styles of the widget's document")
+	}
+
+	r := NewHTMLRenderer()
+	r.Measurer = FixedMetrics{}
+	r.LayoutDocument(*w.GetDocument(), RenderContext{Width: 300})
+	if got := styles(w.GetDocument().Root); !reflect.DeepEqual(got, want) {
+		t.Errorf("LayoutDocument changed the styles of the widget's document")
+	}
+	if h1, _ := w.GetDocument().QuerySelector("h1"); h1.Style.FontSize != 30 {
+		t.Errorf("font size of h1 = %v, want 30", h1.Style.FontSize)
+	}
+}
+
+// render paints w on a recording canvas, as a frame of the host would.
+func render(w *HTMLWidget) {
+	if w.Canvas == nil {
+		w.Canvas = &RecordingCanvas{}
+	}
+	w.Render(0, 0, 300, 100)
+}
+
+func TestSetContentKeepsSettings(t *testing.T) {
+	const sections = `<h2 id="a">A</h2><p>one</p><p>two</p><h2 id="b">B</h2><p>three</p><p>four</p><h2 id="c">C</h2><p>five</p>` +
+		`<p>six</p><p>seven</p><p>eight</p><p>nine</p><p>ten</p>`
+
+	tests := []struct {
+		restore ScrollRestore
+		want    func(before, after map[string]float32) float32
+	}{
+		{RestoreByAnchor, func(before, after map[string]float32) float32 { return after["b"] + 10 }},
+		{RestoreOffset, func(before, after map[string]float32) float32 { return before["b"] + 10 }},
+		{RestoreTop, func(before, after map[string]float32) float32 { return 0 }},
+	}
+
+	for _, test := range tests {
+		w := newTestWidget(sections)
+		w.ScrollRestore = test.restore
+		var clicked string
+		w.OnLinkClick = func(url string) { clicked = url }
+		theme := w.Theme
+		theme.Colors.Text = rl.NewColor(1, 2, 3, 255)
+		w.SetTheme(theme)
+
+		render(w)
+		before := w.layout.Anchors
+		w.ScrollY = before["b"] + 10
+		w.TargetScrollY = w.ScrollY
+
+		w.SetContent(`<p>A new paragraph at the top.</p>` + sections)
+		render(w)
+		if want := test.want(before, w.layout.Anchors); w.ScrollY != want {
+			t.Errorf("restore %d: ScrollY = %v, want %v", test.restore, w.ScrollY, want)
+		}
+
+		if w.Theme.Colors.Text != theme.Colors.Text {
+			t.Errorf("restore %d: SetContent replaced the theme", test.restore)
+		}
+		w.OnLinkClick("#a")
+		if clicked != "#a" {
+			t.Errorf("restore %d: SetContent dropped OnLinkClick", test.restore)
+		}
+	}
+}
+
+func TestSetDocument(t *testing.T) {
+	w := newTestWidget("<p>old</p>")
+	render(w)
+
+	doc := parse(t, "<title>New</title><h1>new</h1>")
+	w.SetDocument(doc)
+	render(w)
+	if got := lineTexts(w.layout.Root); !reflect.DeepEqual(got, []string{"new"}) {
+		t.Errorf("laid out %q after SetDocument", got)
+	}
+	if w.Title() != "New" || w.Content != "" {
+		t.Errorf("Title() = %q and Content = %q after SetDocument", w.Title(), w.Content)
+	}
+}
+
+// countingHandler counts the nodes laid out by the handler it wraps.
+type countingHandler struct {
+	ParagraphRenderHandler
+	count int
+}
+
+func (h *countingHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
+	h.count++
+	return h.ParagraphRenderHandler.Render(node, ctx)
+}
+
+func TestWidgetCachesLayout(t *testing.T) {
+	w := newTestWidget(`<p>one</p><div class="note">Note text</div><p>two</p>`)
+	counter := &countingHandler{}
+	w.RegisterRenderHandler("p", counter)
+
+	first := w.layoutFor(300)
+	if w.layoutFor(300) != first {
+		t.Error("the layout was not reused for the same width")
+	}
+	render(w)
+	render(w)
+	if counter.count != 2 {
+		t.Errorf("paragraphs laid out %d times over three frames, want 2", counter.count)
+	}
+	if got := lineTexts(first.Root); !reflect.DeepEqual(got, []string{"one", "📝", "Note text", "two"}) {
+		t.Errorf("lines = %q, want the callout's text once", got)
+	}
+
+	changes := []struct {
+		name   string
+		change func()
+	}{
+		{"width", func() { w.layoutFor(200) }},
+		{"theme", func() { w.SetTheme(DarkTheme()) }},
+		{"content", func() { w.SetContent("<p>new</p>") }},
+	}
+	for _, change := range changes {
+		before := w.layoutFor(300)
+		change.change()
+		if w.layoutFor(300) == before {
+			t.Errorf("the layout was reused after the %s changed", change.name)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package marquee
+
+import (
+	"strings"
+)
+
+// metadataElements are the elements that describe the document rather than
+// being part of its content. They stay in the tree but are not rendered.
+var metadataElements = map[string]bool{
+	"head": true, "title": true, "meta": true, "link": true,
+	"style": true, "script": true, "base": true, "template": true,
+}
+
+func isMetadataElement(tagName string) bool {
+	return metadataElements[tagName]
+}
+
+// collectMetadata gathers the title, meta tags, stylesheets and scripts of
+// the document rooted at root, in document order. The first <title> wins, as
+// in browsers.
+func collectMetadata(root *HTMLNode) DocumentMetadata {
+	var metadata DocumentMetadata
+	titleFound := false
+
+	var visit func(node *HTMLNode)
+	visit = func(node *HTMLNode) {
+		if node.Type != NodeTypeElement && node.Type != NodeTypeDocument {
+			return
+		}
+
+		switch node.Tag {
+		case "title":
+			if !titleFound {
+				metadata.Title = collapseWhitespace(strings.TrimFunc(nodeText(*node), isHTMLSpace))
+				titleFound = true
+			}
+			return
+		case "meta":
+			metadata.MetaTags = append(metadata.MetaTags, MetaInfo{
+				Name:      node.Attributes["name"],
+				Content:   node.Attributes["content"],
+				Charset:   node.Attributes["charset"],
+				HTTPEquiv: node.Attributes["http-equiv"],
+			})
+		case "link":
+			if hasToken(node.Attributes["rel"], "stylesheet") {
+				metadata.StyleSheets = append(metadata.StyleSheets, StyleInfo{
+					Href:  node.Attributes["href"],
+					Media: node.Attributes["media"],
+				})
+			}
+		case "style":
+			metadata.StyleSheets = append(metadata.StyleSheets, StyleInfo{
+				Content: nodeText(*node),
+				Media:   node.Attributes["media"],
+			})
+			return
+		case "script":
+			metadata.Scripts = append(metadata.Scripts, ScriptInfo{
+				Src:     node.Attributes["src"],
+				Content: nodeText(*node),
+				Type:    node.Attributes["type"],
+			})
+			return
+		}
+
+		for i := range node.Children {
+			visit(&node.Children[i])
+		}
+	}
+	visit(root)
+
+	return metadata
+}
+
+// hasToken reports whether the space-separated list value contains token,
+// ignoring ASCII case, as used by attributes such as rel and class.
+func hasToken(value, token string) bool {
+	for _, field := range splitHTMLWords(value) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Meta returns the content of the first <meta name="..."> tag with the given
+// name, compared case-insensitively.
+func (m DocumentMetadata) Meta(name string) (string, bool) {
+	for _, meta := range m.MetaTags {
+		if strings.EqualFold(meta.Name, name) {
+			return meta.Content, true
+		}
+	}
+	return "", false
+}
+
+// Charset returns the character encoding declared by <meta charset> or by
+// an http-equiv Content-Type meta tag, or "" if none is declared.
+func (m DocumentMetadata) Charset() string {
+	for _, meta := range m.MetaTags {
+		if meta.Charset != "" {
+			return strings.TrimSpace(meta.Charset)
+		}
+		if strings.EqualFold(meta.HTTPEquiv, "content-type") {
+			if i := strings.Index(strings.ToLower(meta.Content), "charset="); i >= 0 {
+				return strings.Trim(strings.TrimSpace(meta.Content[i+len("charset="):]), `"'`)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package marquee
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDocumentMetadata(t *testing.T) {
+	const html = `<!DOCTYPE html>
+<html><head>
+<meta charset="utf-8">
+<meta name="description" content="A test page">
+<meta http-equiv="refresh" content="30">
+<title>  First
+ title </title>
+<title>Second title</title>
+<link rel="Alternate Stylesheet" href="alt.css" media="print">
+<link rel="icon" href="favicon.ico">
+<style media="screen">p { color: red }</style>
+<script src="app.js" type="module"></script>
+</head><body><p>Body</p><script>init()</script></body></html>`
+
+	want := DocumentMetadata{
+		Title: "First title",
+		Scripts: []ScriptInfo{
+			{Src: "app.js", Type: "module"},
+			{Content: "init()"},
+		},
+		StyleSheets: []StyleInfo{
+			{Href: "alt.css", Media: "print"},
+			{Content: "p { color: red }", Media: "screen"},
+		},
+		MetaTags: []MetaInfo{
+			{Charset: "utf-8"},
+			{Name: "description", Content: "A test page"},
+			{HTTPEquiv: "refresh", Content: "30"},
+		},
+		DocType: "html",
+	}
+	if got := parse(t, html).Metadata; !reflect.DeepEqual(got, want) {
+		t.Errorf("Metadata =\n%+v\nwant\n%+v", got, want)
+	}
+
+	w := newTestWidget(html)
+	if got := w.Title(); got != "First title" {
+		t.Errorf("Title() = %q, want %q", got, "First title")
+	}
+	if got := lineTexts(w.layoutFor(300).Root); !reflect.DeepEqual(got, []string{"Body"}) {
+		t.Errorf("laid out %q, want only the body", got)
+	}
+}
+
+func TestDocumentMetadataEmpty(t *testing.T) {
+	tests := []string{"", "<p>No head</p>", "<title></title><p>x</p>"}
+
+	for _, html := range tests {
+		if got := parse(t, html).Metadata; !reflect.DeepEqual(got, DocumentMetadata{}) {
+			t.Errorf("Metadata of %q = %+v, want none", html, got)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package marquee
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// FontFamily is the kind of typeface a Font uses.
+type FontFamily int
+
+const (
+	FamilySans FontFamily = iota
+	FamilyMonospace
+)
+
+// Font names a typeface, its style and its size in pixels. It describes the
+// font rather than holding it, so the layout pass can measure text without a
+// window or GPU; each backend loads the actual font when it draws.
+type Font struct {
+	Family FontFamily
+	Bold   bool
+	Italic bool
+	Size   float32
+}
+
+// DefaultFonts returns the fonts the widget uses unless told otherwise: 16
+// pixel body text, headings from 32 down to 16 pixels, and 14 and 16 pixel
+// monospace for inline and block code.
+func DefaultFonts() FontSet {
+	return FontSet{
+		Regular:        Font{Size: 16},
+		Bold:           Font{Bold: true, Size: 16},
+		Italic:         Font{Italic: true, Size: 16},
+		BoldItalic:     Font{Bold: true, Italic: true, Size: 16},
+		H1:             Font{Size: 32},
+		H2:             Font{Size: 28},
+		H3:             Font{Size: 24},
+		H4:             Font{Size: 20},
+		H5:             Font{Size: 18},
+		H6:             Font{Size: 16},
+		Monospace:      Font{Family: FamilyMonospace, Size: 14},
+		MonospaceLarge: Font{Family: FamilyMonospace, Size: 16},
+	}
+}
+
+// FontMetrics are the vertical measurements of a font, in pixels. Ascent is
+// the height above the baseline and Descent the depth below it, both
+// positive. LineGap is the extra space the font asks for between lines.
+type FontMetrics struct {
+	Ascent  float32
+	Descent float32
+	LineGap float32
+}
+
+// LineHeight returns the distance between the baselines of two lines.
+func (m FontMetrics) LineHeight() float32 {
+	return m.Ascent + m.Descent + m.LineGap
+}
+
+// TextMeasurer measures text for the layout pass. The widget measures with
+// the raylib fonts it draws with; a layout made without a window can use
+// NewTTFMeasurer, GoFontMeasurer or FixedMetrics.
+type TextMeasurer interface {
+	// MeasureText returns the width of text set in font.
+	MeasureText(font Font, text string) float32
+	// Metrics returns the vertical metrics of font.
+	Metrics(font Font) FontMetrics
+}
+
+// FixedMetrics is a TextMeasurer that gives every character the same width,
+// a fixed share of the font size. Layouts made with it depend on no font
+// file at all, so tests can check positions exactly.
+type FixedMetrics struct {
+	// CharWidth is the width of a character as a fraction of the font
+	// size. Zero means 0.5.
+	CharWidth float32
+}
+
+func (m FixedMetrics) MeasureText(font Font, text string) float32 {
+	charWidth := m.CharWidth
+	if charWidth == 0 {
+		charWidth = 0.5
+	}
+
+	count := 0
+	for range text {
+		count++
+	}
+	return float32(count) * charWidth * font.Size
+}
+
+func (m FixedMetrics) Metrics(font Font) FontMetrics {
+	return FontMetrics{Ascent: font.Size * 0.8, Descent: font.Size * 0.2}
+}
+
+// TTFFonts holds the TrueType or OpenType files a TTFMeasurer uses. Regular
+// is required; a missing style falls back to the nearest one given, and a
+// missing Monospace to Regular. A font collection, such as a .ttc file,
+// stands for its first font.
+type TTFFonts struct {
+	Regular    []byte
+	Bold       []byte
+	Italic     []byte
+	BoldItalic []byte
+	Monospace  []byte
+}
+
+// TTFMeasurer is a TextMeasurer that reads advance widths and vertical
+// metrics straight from font files, in pure Go. Kerning is not applied, so
+// a painter drawing its layout should place glyphs by advance alone.
+type TTFMeasurer struct {
+	mu     sync.Mutex
+	buffer sfnt.Buffer
+	faces  map[fontStyle]*ttfFace
+}
+
+// fontStyle picks one of the faces of a TTFMeasurer.
+type fontStyle struct {
+	family FontFamily
+	bold   bool
+	italic bool
+}
+
+func (f Font) style() fontStyle {
+	return fontStyle{family: f.Family, bold: f.Bold, italic: f.Italic}
+}
+
+// ttfFace is a parsed font with its measurements in ems, so they scale to
+// any size. data is the font file, for writers that embed it.
+type ttfFace struct {
+	data     []byte
+	font     *sfnt.Font
+	advances map[rune]float32
+	metrics  FontMetrics
+}
+
+// NewTTFMeasurer parses fonts and returns a measurer for them.
+func NewTTFMeasurer(fonts TTFFonts) (*TTFMeasurer, error) {
+	if len(fonts.Regular) == 0 {
+		return nil, errors.New("marquee: TTFFonts.Regular is required")
+	}
+
+	m := &TTFMeasurer{faces: make(map[fontStyle]*ttfFace)}
+	for _, entry := range []struct {
+		style fontStyle
+		data  []byte
+	}{
+		{fontStyle{FamilySans, false, false}, fonts.Regular},
+		{fontStyle{FamilySans, true, false}, fonts.Bold},
+		{fontStyle{FamilySans, false, true}, fonts.Italic},
+		{fontStyle{FamilySans, true, true}, fonts.BoldItalic},
+		{fontStyle{FamilyMonospace, false, false}, fonts.Monospace},
+	} {
+		if len(entry.data) == 0 {
+			continue
+		}
+		face, err := m.parseFace(entry.data)
+		if err != nil {
+			return nil, err
+		}
+		m.faces[entry.style] = face
+	}
+
+	return m, nil
+}
+
+var (
+	goFontMeasurer     *TTFMeasurer
+	goFontMeasurerOnce sync.Once
+)
+
+// GoFontMeasurer returns a measurer for the Go fonts, which are built into
+// the program. Layouts made with it come out the same on every machine.
+func GoFontMeasurer() *TTFMeasurer {
+	goFontMeasurerOnce.Do(func() {
+		measurer, err := NewTTFMeasurer(goFonts)
+		if err != nil {
+			panic("marquee: cannot parse the Go fonts: " + err.Error())
+		}
+		goFontMeasurer = measurer
+	})
+	return goFontMeasurer
+}
+
+var goFonts = TTFFonts{
+	Regular:    goregular.TTF,
+	Bold:       gobold.TTF,
+	Italic:     goitalic.TTF,
+	BoldItalic: gobolditalic.TTF,
+	Monospace:  gomono.TTF,
+}
+
+func (m *TTFMeasurer) parseFace(data []byte) (*ttfFace, error) {
+	data, err := singleFont(data)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := emMetrics(parsed, &m.buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ttfFace{
+		data:     data,
+		font:     parsed,
+		advances: make(map[rune]float32),
+		metrics:  metrics,
+	}, nil
+}
+
+var errBadCollection = errors.New("marquee: malformed font collection")
+
+// singleFont returns data when it is a single font file. When it is a font
+// collection, it returns the first font of the collection as a file of its
+// own, which sfnt.Parse reads and a PDF file can embed.
+func singleFont(data []byte) ([]byte, error) {
+	if len(data) < 4 || string(data[:4]) != "ttcf" {
+		return data, nil
+	}
+	if len(data) < 16 || binary.BigEndian.Uint32(data[8:]) == 0 {
+		return nil, errBadCollection
+	}
+
+	// The first font's offset table and table records are copied as they
+	// are, then each table is appended, 4-byte aligned, and its record
+	// pointed at the copy.
+	start := int(binary.BigEndian.Uint32(data[12:]))
+	if start+12 > len(data) {
+		return nil, errBadCollection
+	}
+	headerSize := 12 + 16*int(binary.BigEndian.Uint16(data[start+4:]))
+	if start+headerSize > len(data) {
+		return nil, errBadCollection
+	}
+
+	single := append([]byte(nil), data[start:start+headerSize]...)
+	for record := 12; record < headerSize; record += 16 {
+		offset := int(binary.BigEndian.Uint32(single[record+8:]))
+		length := int(binary.BigEndian.Uint32(single[record+12:]))
+		if offset+length > len(data) {
+			return nil, errBadCollection
+		}
+		binary.BigEndian.PutUint32(single[record+8:], uint32(len(single)))
+		single = append(single, data[offset:offset+length]...)
+		for len(single)%4 != 0 {
+			single = append(single, 0)
+		}
+	}
+	return single, nil
+}
+
+// emMetrics returns the vertical metrics of f in ems, to be multiplied by
+// the size of the text.
+func emMetrics(f *sfnt.Font, buffer *sfnt.Buffer) (FontMetrics, error) {
+	em := float32(f.UnitsPerEm())
+	metrics, err := f.Metrics(buffer, fixed.I(int(f.UnitsPerEm())), font.HintingNone)
+	if err != nil {
+		return FontMetrics{}, err
+	}
+
+	ascent := float32(metrics.Ascent) / 64 / em
+	descent := float32(metrics.Descent) / 64 / em
+	height := float32(metrics.Height) / 64 / em
+	lineGap := height - ascent - descent
+	if lineGap < 0 {
+		lineGap = 0
+	}
+	return FontMetrics{Ascent: ascent, Descent: descent, LineGap: lineGap}, nil
+}
+
+// face returns the face for font, falling back to a plainer style of the
+// same family and then to the regular sans face.
+func (m *TTFMeasurer) face(font Font) *ttfFace {
+	style := font.style()
+	for _, candidate := range []fontStyle{
+		style,
+		{style.family, style.bold, false},
+		{style.family, false, style.italic},
+		{style.family, false, false},
+		{FamilySans, style.bold, style.italic},
+		{FamilySans, style.bold, false},
+		{FamilySans, false, false},
+	} {
+		if face, exists := m.faces[candidate]; exists {
+			return face
+		}
+	}
+	return nil
+}
+
+func (m *TTFMeasurer) MeasureText(font Font, text string) float32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	face := m.face(font)
+	width := float32(0)
+	for _, r := range text {
+		width += m.advance(face, r)
+	}
+	return width * font.Size
+}
+
+func (m *TTFMeasurer) Metrics(font Font) FontMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics := m.face(font).metrics
+	return FontMetrics{
+		Ascent:  metrics.Ascent * font.Size,
+		Descent: metrics.Descent * font.Size,
+		LineGap: metrics.LineGap * font.Size,
+	}
+}
+
+// advance returns the advance width of r in ems. Characters the font lacks
+// are measured as its missing-glyph box.
+func (m *TTFMeasurer) advance(face *ttfFace, r rune) float32 {
+	if advance, exists := face.advances[r]; exists {
+		return advance
+	}
+
+	advance := float32(0)
+	em := face.font.UnitsPerEm()
+	if index, err := face.font.GlyphIndex(&m.buffer, r); err == nil {
+		if units, err := face.font.GlyphAdvance(&m.buffer, index, fixed.I(int(em)), font.HintingNone); err == nil {
+			advance = float32(units) / 64 / float32(em)
+		}
+	}
+
+	face.advances[r] = advance
+	return advance
+}
+
+// faceFor returns the face a TTFMeasurer measures font with, so a painter
+// or writer can draw the same glyphs. Its font may be used concurrently with
+// a Buffer of the caller's own; its advances and metrics only under m.mu.
+func (m *TTFMeasurer) faceFor(font Font) *ttfFace {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.face(font)
+}
+
+var (
+	systemFontMeasurer     *TTFMeasurer
+	systemFontMeasurerOnce sync.Once
+)
+
+// systemMeasurer returns a measurer for the font files the widget loads,
+// such as Arial on Windows and macOS or Liberation Sans and DejaVu Sans Mono
+// on Linux, so output drawn with it looks like the window. When those files
+// are missing it returns the Go fonts, and Go Mono stands in for a missing
+// monospaced font.
+func systemMeasurer() *TTFMeasurer {
+	systemFontMeasurerOnce.Do(func() {
+		systemFontMeasurer = GoFontMeasurer()
+		if fonts, ok := getFontManager().systemFonts(); ok {
+			if fonts.Monospace == nil {
+				fonts.Monospace = goFonts.Monospace
+			}
+			if measurer, err := NewTTFMeasurer(fonts); err == nil {
+				systemFontMeasurer = measurer
+			}
+		}
+	})
+	return systemFontMeasurer
+}
+
+// withTTFMeasurer returns a copy of r that measures with a TTFMeasurer, for
+// output that draws its own text: r.Measurer if it is one, or the fonts the
+// widget draws with. The copy keeps r's handlers and fonts and leaves r
+// unchanged.
+func (r *HTMLRenderer) withTTFMeasurer() (*HTMLRenderer, *TTFMeasurer) {
+	measurer, _ := r.Measurer.(*TTFMeasurer)
+	if measurer == nil {
+		measurer = systemMeasurer()
+	}
+
+	renderer := *r
+	renderer.Measurer = measurer
+	return &renderer, measurer
+}
@@ -1,6 +1,7 @@
 package marquee
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -11,12 +12,26 @@ type HTMLNode struct {
 	Attributes map[string]string
 	Children   []HTMLNode
 	Context    NodeContext
-	Parent     *HTMLNode
+
+	// Parent is the node's parent, or nil for the root. The parents of the
+	// root's children are pointed at the root of the document by its
+	// methods, such as QuerySelector and Walk, since the root moves when
+	// the document is copied.
+	Parent *HTMLNode
+
+	// Style is the computed style, filled in by HTMLWidget after parsing or
+	// by LayoutDocument for a document laid out on its own.
+	Style ComputedStyle
 }
 
 type HTMLDocument struct {
-	Root     HTMLNode
-	Metadata DocumentMetadata
+	Root        HTMLNode
+	Metadata    DocumentMetadata
+	Diagnostics []ParseDiagnostic
+
+	// Changes made through the methods in dom.go, for HTMLWidget.
+	version      int
+	changedNodes []*HTMLNode
 }
 
 type DocumentMetadata struct {
@@ -40,9 +55,10 @@ type StyleInfo struct {
 }
 
 type MetaInfo struct {
-	Name    string
-	Content string
-	Charset string
+	Name      string
+	Content   string
+	Charset   string
+	HTTPEquiv string
 }
 
 type StateMachineParser struct {
@@ -52,30 +68,113 @@ type StateMachineParser struct {
 	nodeStack    []NodeStackEntry
 	textBuffer   strings.Builder
 	tagBuffer    strings.Builder
+	markupBuffer strings.Builder
 	attrName     string
 	attrValue    strings.Builder
 	currentAttrs map[string]string
 	quoteChar    rune
-
-	maxDepth     int
-	currentDepth int
-	maxLength    int
-
-	errorCount int
-	maxErrors  int
+	rawTextTag   string
+	docType      string
+	tagStart     int
+
+	maxDepth         int
+	currentDepth     int
+	maxLength        int
+	maxTagNameLength int
+
+	errorCount  int
+	maxErrors   int
+	diagnostics []ParseDiagnostic
+
+	keepComments bool
+	cursor       sourceCursor
+
+	// Streaming state; see stream.go.
+	root         *HTMLNode
+	pending      []byte
+	written      int
+	truncated    bool
+	started      bool
+	inputEnd     int
+	skippedBytes int
 }
 
 type NodeStackEntry struct {
 	Node        *HTMLNode
 	OriginalTag string
+	Position    int
+}
+
+// ParserOptions sets the limits a StateMachineParser enforces. A zero field
+// selects the default shown next to it.
+type ParserOptions struct {
+	// MaxDepth is how deeply elements may nest (50). Deeper elements are
+	// added to their parent and reported.
+	MaxDepth int
+	// MaxLength is the longest input in bytes that is parsed (1,000,000).
+	// Longer input is cut off and reported as truncated.
+	MaxLength int
+	// MaxErrors is how many diagnostics are recorded (100) before the rest
+	// are suppressed.
+	MaxErrors int
+	// MaxTagNameLength is the longest accepted tag name (20). Longer tags
+	// are dropped and reported.
+	MaxTagNameLength int
+
+	// KeepComments keeps comments in the tree as NodeTypeComment nodes, so
+	// tools can read annotations such as "<!-- help-id: export-dialog -->".
+	// Comments are never rendered.
+	KeepComments bool
+}
+
+// DefaultParserOptions returns the limits used when none are given.
+func DefaultParserOptions() ParserOptions {
+	return ParserOptions{
+		MaxDepth:         50,
+		MaxLength:        1000000,
+		MaxErrors:        100,
+		MaxTagNameLength: 20,
+	}
 }
 
-func NewStateMachineParser() *StateMachineParser {
+// withDefaults fills in the zero fields of o from DefaultParserOptions.
+func (o ParserOptions) withDefaults() ParserOptions {
+	defaults := DefaultParserOptions()
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = defaults.MaxDepth
+	}
+	if o.MaxLength <= 0 {
+		o.MaxLength = defaults.MaxLength
+	}
+	if o.MaxErrors <= 0 {
+		o.MaxErrors = defaults.MaxErrors
+	}
+	if o.MaxTagNameLength <= 0 {
+		o.MaxTagNameLength = defaults.MaxTagNameLength
+	}
+	return o
+}
+
+func NewStateMachineParser(options ParserOptions) *StateMachineParser {
+	options = options.withDefaults()
 	return &StateMachineParser{
-		currentAttrs: make(map[string]string),
-		maxDepth:     50,
-		maxLength:    1000000,
-		maxErrors:    100,
+		currentAttrs:     make(map[string]string),
+		maxDepth:         options.MaxDepth,
+		maxLength:        options.MaxLength,
+		maxErrors:        options.MaxErrors,
+		maxTagNameLength: options.MaxTagNameLength,
+		keepComments:     options.KeepComments,
+	}
+}
+
+// Options returns the limits the parser enforces.
+func (p *StateMachineParser) Options() ParserOptions {
+	return ParserOptions{
+		MaxDepth:         p.maxDepth,
+		MaxLength:        p.maxLength,
+		MaxErrors:        p.maxErrors,
+		MaxTagNameLength: p.maxTagNameLength,
+		KeepComments:     p.keepComments,
 	}
 }
 
@@ -86,62 +185,50 @@ func (p *StateMachineParser) Reset() {
 	p.nodeStack = nil
 	p.textBuffer.Reset()
 	p.tagBuffer.Reset()
+	p.markupBuffer.Reset()
 	p.attrName = ""
 	p.attrValue.Reset()
 	p.currentAttrs = make(map[string]string)
 	p.quoteChar = 0
+	p.rawTextTag = ""
+	p.docType = ""
+	p.tagStart = 0
 	p.currentDepth = 0
 	p.errorCount = 0
+	p.diagnostics = nil
+	p.cursor = sourceCursor{}
+	p.root = nil
+	p.pending = nil
+	p.written = 0
+	p.truncated = false
+	p.started = false
+	p.inputEnd = 0
+	p.skippedBytes = 0
 }
 
-func (p *StateMachineParser) handleParseError(message string) bool {
-	p.errorCount++
-	if p.errorCount > p.maxErrors {
-
-		return false
-	}
+// handleParseError records an error for the tag being read and drops it.
+func (p *StateMachineParser) handleParseError(code DiagnosticCode, tag, message string) {
+	p.addDiagnostic(SeverityError, code, p.tagStart, tag, message)
 
 	p.state = StateText
 	p.tagBuffer.Reset()
 	p.attrValue.Reset()
 	p.currentAttrs = make(map[string]string)
-
-	return true
 }
 
+// Parse parses a complete document. To parse a document as it arrives, use
+// a StreamParser.
 func (p *StateMachineParser) Parse(html string) HTMLDocument {
+	p.begin()
+	p.write([]byte(html))
+	return p.end()
+}
 
-	p.Reset()
-
-	if len(html) == 0 {
-		return HTMLDocument{Root: HTMLNode{Type: NodeTypeDocument, Context: ContextRoot}}
-	}
-
-	if len(html) > p.maxLength {
-		html = html[:p.maxLength]
-	}
-
-	p.input = []rune(strings.TrimSpace(html))
-	p.position = 0
-	p.state = StateText
-
-	root := &HTMLNode{
-		Type:       NodeTypeDocument,
-		Context:    ContextRoot,
-		Attributes: make(map[string]string),
-		Children:   make([]HTMLNode, 0),
-	}
-	p.nodeStack = []NodeStackEntry{{Node: root, OriginalTag: "document"}}
-
-	for p.position < len(p.input) {
+// run processes the input up to rune position limit.
+func (p *StateMachineParser) run(limit int) {
+	for p.position < limit {
 		char := p.input[p.position]
 
-		if p.currentDepth > p.maxDepth {
-			if !p.handleParseError("max depth exceeded") {
-				break
-			}
-		}
-
 		switch p.state {
 		case StateText:
 			p.handleTextState(char)
@@ -163,6 +250,16 @@ func (p *StateMachineParser) Parse(html string) HTMLDocument {
 			p.handleEndTagState(char)
 		case StateComment:
 			p.handleCommentState(char)
+		case StateBogusComment:
+			p.handleBogusCommentState(char)
+		case StateDoctype:
+			p.handleDoctypeState(char)
+		case StateCDATA:
+			p.handleCDATAState(char)
+		case StateProcessingInstruction:
+			p.handleProcessingInstructionState(char)
+		case StateRawText:
+			p.handleRawTextState(char)
 		}
 
 		p.position++
@@ -171,16 +268,6 @@ func (p *StateMachineParser) Parse(html string) HTMLDocument {
 			break
 		}
 	}
-
-	if p.textBuffer.Len() > 0 {
-		p.addTextNode(p.textBuffer.String())
-	}
-
-	for len(p.nodeStack) > 1 {
-		p.nodeStack = p.nodeStack[:len(p.nodeStack)-1]
-	}
-
-	return HTMLDocument{Root: *root}
 }
 
 func (p *StateMachineParser) handleTextState(char rune) {
@@ -190,6 +277,7 @@ func (p *StateMachineParser) handleTextState(char rune) {
 			p.addTextNode(p.textBuffer.String())
 			p.textBuffer.Reset()
 		}
+		p.tagStart = p.position
 		p.state = StateTagOpen
 	} else {
 		p.textBuffer.WriteRune(char)
@@ -201,15 +289,22 @@ func (p *StateMachineParser) handleTagOpenState(char rune) {
 		p.state = StateEndTag
 		p.tagBuffer.Reset()
 	} else if char == '!' {
-		p.state = StateComment
-	} else if char == ' ' || char == '\t' || char == '\n' {
-
-	} else {
-
+		p.startMarkupDeclaration()
+	} else if char == '?' {
+		p.markupBuffer.Reset()
+		p.state = StateProcessingInstruction
+	} else if isASCIILetter(char) {
 		p.tagBuffer.Reset()
 		p.tagBuffer.WriteRune(char)
 		p.state = StateTagName
 		p.currentAttrs = make(map[string]string)
+	} else {
+		// As in HTML5, "<" that does not start a tag is text: "a < b".
+		p.addDiagnostic(SeverityWarning, DiagnosticInvalidTagName, p.tagStart, "",
+			"\"<\" is not followed by a tag name and is kept as text")
+		p.textBuffer.WriteRune('<')
+		p.state = StateText
+		p.handleTextState(char)
 	}
 }
 
@@ -217,8 +312,8 @@ func (p *StateMachineParser) handleTagNameState(char rune) {
 	if char == ' ' || char == '\t' || char == '\n' {
 		p.state = StateAttributes
 	} else if char == '>' {
-		p.finishOpenTag()
 		p.state = StateText
+		p.finishOpenTag()
 	} else if char == '/' {
 		p.state = StateTagClose
 	} else {
@@ -228,8 +323,8 @@ func (p *StateMachineParser) handleTagNameState(char rune) {
 
 func (p *StateMachineParser) handleAttributesState(char rune) {
 	if char == '>' {
-		p.finishOpenTag()
 		p.state = StateText
+		p.finishOpenTag()
 	} else if char == '/' {
 		p.state = StateTagClose
 	} else if char != ' ' && char != '\t' && char != '\n' {
@@ -247,8 +342,8 @@ func (p *StateMachineParser) handleAttributeNameState(char rune) {
 		p.state = StateAttributes
 	} else if char == '>' {
 		p.currentAttrs[p.attrName] = p.attrName
-		p.finishOpenTag()
 		p.state = StateText
+		p.finishOpenTag()
 	} else {
 		p.attrName += string(char)
 	}
@@ -259,12 +354,12 @@ func (p *StateMachineParser) handleAttributeValueState(char rune) {
 		p.quoteChar = char
 		p.state = StateAttributeQuoted
 	} else if char == ' ' || char == '\t' || char == '\n' {
-		p.currentAttrs[p.attrName] = p.attrValue.String()
+		p.commitAttributeValue()
 		p.state = StateAttributes
 	} else if char == '>' {
-		p.currentAttrs[p.attrName] = p.attrValue.String()
-		p.finishOpenTag()
+		p.commitAttributeValue()
 		p.state = StateText
+		p.finishOpenTag()
 	} else {
 		p.attrValue.WriteRune(char)
 	}
@@ -272,17 +367,21 @@ func (p *StateMachineParser) handleAttributeValueState(char rune) {
 
 func (p *StateMachineParser) handleAttributeQuotedState(char rune) {
 	if char == p.quoteChar {
-		p.currentAttrs[p.attrName] = p.attrValue.String()
+		p.commitAttributeValue()
 		p.state = StateAttributes
 	} else {
 		p.attrValue.WriteRune(char)
 	}
 }
 
+func (p *StateMachineParser) commitAttributeValue() {
+	p.currentAttrs[p.attrName] = decodeCharacterReferences(p.attrValue.String(), true)
+}
+
 func (p *StateMachineParser) handleTagCloseState(char rune) {
 	if char == '>' {
-		p.finishSelfClosingTag()
 		p.state = StateText
+		p.finishSelfClosingTag()
 	}
 }
 
@@ -295,30 +394,95 @@ func (p *StateMachineParser) handleEndTagState(char rune) {
 	}
 }
 
-func (p *StateMachineParser) handleCommentState(char rune) {
-
-	if char == '>' && p.position >= 2 {
-		if p.position-1 < len(p.input) && p.position-2 < len(p.input) &&
-			p.input[p.position-1] == '-' && p.input[p.position-2] == '-' {
-			p.state = StateText
+// handleRawTextState reads the contents of script, style, title and
+// textarea, where "<" does not start a tag. Only the matching end tag ends
+// the element.
+func (p *StateMachineParser) handleRawTextState(char rune) {
+	if char == '<' && p.lookingAt(p.position+1, "/"+p.rawTextTag) {
+		next := p.position + 2 + len([]rune(p.rawTextTag))
+		if next >= len(p.input) || p.input[next] == '>' || p.input[next] == '/' || isHTMLSpace(p.input[next]) {
+			if p.textBuffer.Len() > 0 {
+				p.addTextNode(p.textBuffer.String())
+				p.textBuffer.Reset()
+			}
+			p.rawTextTag = ""
+			p.tagBuffer.Reset()
+			p.position++
+			p.state = StateEndTag
+			return
 		}
 	}
 
-	if p.position > 1000 {
-		p.state = StateText
+	p.textBuffer.WriteRune(char)
+}
+
+func isASCIILetter(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+}
+
+// lookingAt reports whether the input at position starts with s, ignoring
+// ASCII case. s must be lower case.
+func (p *StateMachineParser) lookingAt(position int, s string) bool {
+	for _, want := range s {
+		if position >= len(p.input) {
+			return false
+		}
+		got := p.input[position]
+		if 'A' <= got && got <= 'Z' {
+			got += 'a' - 'A'
+		}
+		if got != want {
+			return false
+		}
+		position++
 	}
+	return true
 }
 
 func (p *StateMachineParser) addTextNode(content string) {
-	if strings.TrimSpace(content) == "" {
-		return
-	}
+	p.insertText(content, true)
+}
 
+// insertText adds text to the current node, applying the whitespace rules
+// for where it appears. Character references are decoded when decode is set.
+func (p *StateMachineParser) insertText(content string, decode bool) {
 	if len(p.nodeStack) == 0 {
 		return
 	}
 
 	parent := p.nodeStack[len(p.nodeStack)-1].Node
+
+	if parent.Tag == "script" || parent.Tag == "style" {
+		// Script and style contents are kept exactly as written.
+		parent.Children = append(parent.Children, HTMLNode{
+			Type:    NodeTypeText,
+			Content: content,
+			Context: ContextInline,
+			Parent:  parent,
+		})
+		return
+	}
+
+	if p.inPreformattedContent() {
+		// A newline straight after <pre> or <textarea> is not part of the content.
+		if len(parent.Children) == 0 && (parent.Tag == "pre" || parent.Tag == "textarea" || parent.Tag == "listing") {
+			content = strings.TrimPrefix(content, "\n")
+		}
+	} else {
+		content = collapseWhitespace(content)
+		if content == " " && !p.keepsWhitespaceText(parent) {
+			return
+		}
+	}
+
+	if content == "" {
+		return
+	}
+
+	if decode {
+		content = decodeCharacterReferences(content, false)
+	}
+
 	textNode := HTMLNode{
 		Type:    NodeTypeText,
 		Content: content,
@@ -329,18 +493,90 @@ func (p *StateMachineParser) addTextNode(content string) {
 	parent.Children = append(parent.Children, textNode)
 }
 
+// inPreformattedContent reports whether text at the current position keeps
+// its whitespace verbatim: inside pre, textarea or listing. A code block is
+// written as code inside pre.
+func (p *StateMachineParser) inPreformattedContent() bool {
+	for i := len(p.nodeStack) - 1; i >= 0; i-- {
+		switch p.nodeStack[i].Node.Tag {
+		case "pre", "textarea", "listing":
+			return true
+		}
+	}
+	return false
+}
+
+// keepsWhitespaceText reports whether a whitespace-only text node is
+// significant inside parent. Lists and tables only hold block content, so
+// the whitespace between their children is dropped; inline content keeps it
+// as a single space, as in "<b>foo</b> <i>bar</i>". The document itself may
+// hold either, so there the space is kept after inline content and dropped
+// again by trimDocumentWhitespace if a block follows it.
+func (p *StateMachineParser) keepsWhitespaceText(parent *HTMLNode) bool {
+	switch parent.Tag {
+	case "html", "head", "ul", "ol", "dl", "table", "thead", "tbody", "tfoot", "tr", "colgroup", "select":
+		return false
+	}
+	if parent.Type == NodeTypeDocument {
+		last := len(parent.Children) - 1
+		return last >= 0 && isInlineContent(parent.Children[last])
+	}
+	return true
+}
+
+// trimDocumentWhitespace drops the whitespace-only text at the top level of
+// root that keepsWhitespaceText kept after inline content but that is not
+// followed by more of it, such as the line break before a paragraph.
+func trimDocumentWhitespace(root *HTMLNode) {
+	if root.Type != NodeTypeDocument {
+		return
+	}
+
+	children := make([]HTMLNode, 0, len(root.Children))
+	for i, child := range root.Children {
+		if child.Type == NodeTypeText && child.Content == " " &&
+			(i+1 == len(root.Children) || !isInlineContent(root.Children[i+1])) {
+			continue
+		}
+		children = append(children, child)
+	}
+	root.Children = children
+}
+
+// isInlineContent reports whether node is text or an inline element, which
+// a space between it and its neighbour would separate.
+func isInlineContent(node HTMLNode) bool {
+	return node.Type == NodeTypeText || (node.Type == NodeTypeElement && !isBlockLevel(node))
+}
+
 func (p *StateMachineParser) finishOpenTag() {
 	tagName := strings.ToLower(p.tagBuffer.String())
 
-	if tagName == "" || len(tagName) > 20 {
-		if !p.handleParseError("invalid tag name") {
-			return
-		}
-		p.tagBuffer.Reset()
-		p.currentAttrs = make(map[string]string)
+	if tagName == "" || len(tagName) > p.maxTagNameLength {
+		p.handleParseError(DiagnosticInvalidTagName, tagName,
+			fmt.Sprintf("tag name %q is empty or longer than %d characters; tag dropped", tagName, p.maxTagNameLength))
+		return
+	}
+
+	if len(p.nodeStack) == 0 {
 		return
 	}
 
+	p.closeImpliedElements(tagName)
+	p.insertElement(tagName, p.currentAttrs)
+
+	if isRawTextElement(tagName) {
+		p.rawTextTag = tagName
+		p.state = StateRawText
+	}
+
+	p.tagBuffer.Reset()
+	p.currentAttrs = make(map[string]string)
+}
+
+// insertElement appends a new element to the current node and, unless it is
+// a void element, makes it the current node.
+func (p *StateMachineParser) insertElement(tagName string, attrs map[string]string) {
 	node := HTMLNode{
 		Type:       NodeTypeElement,
 		Tag:        tagName,
@@ -348,24 +584,17 @@ func (p *StateMachineParser) finishOpenTag() {
 		Children:   make([]HTMLNode, 0),
 	}
 
-	for k, v := range p.currentAttrs {
+	for k, v := range attrs {
 		node.Attributes[k] = v
 	}
 
-	if len(p.nodeStack) == 0 {
-		return
-	}
-
 	parent := p.nodeStack[len(p.nodeStack)-1].Node
-	node.Context = p.determineContext(tagName, parent)
+	node.Context = determineContext(tagName, parent)
 	node.Parent = parent
 
-	originalTag := tagName
-	node = *p.normalizeElement(&node)
-
 	parent.Children = append(parent.Children, node)
 
-	if p.isContainerElement(originalTag) {
+	if !isVoidElement(tagName) {
 
 		if p.currentDepth < p.maxDepth {
 			childIndex := len(parent.Children) - 1
@@ -373,72 +602,46 @@ func (p *StateMachineParser) finishOpenTag() {
 
 			stackEntry := NodeStackEntry{
 				Node:        childNode,
-				OriginalTag: originalTag,
+				OriginalTag: tagName,
+				Position:    p.tagStart,
 			}
 			p.nodeStack = append(p.nodeStack, stackEntry)
 			p.currentDepth++
+		} else {
+			p.addDiagnostic(SeverityError, DiagnosticMaxDepthExceeded, p.tagStart, tagName,
+				fmt.Sprintf("<%s> is nested more than %d levels deep; its content is added to its parent", tagName, p.maxDepth))
 		}
 	}
-
-	p.tagBuffer.Reset()
-	p.currentAttrs = make(map[string]string)
 }
 
+// finishSelfClosingTag handles "<tag/>". As in HTML5, the slash only matters
+// for void elements; "<div/>" opens a div like "<div>" does.
 func (p *StateMachineParser) finishSelfClosingTag() {
-	tagName := strings.ToLower(p.tagBuffer.String())
-
-	if tagName == "" || len(p.nodeStack) == 0 || len(tagName) > 20 {
-		if !p.handleParseError("invalid self-closing tag") {
-			return
-		}
-		p.tagBuffer.Reset()
-		p.currentAttrs = make(map[string]string)
-		return
-	}
-
-	parent := p.nodeStack[len(p.nodeStack)-1].Node
-	node := HTMLNode{
-		Type:       NodeTypeElement,
-		Tag:        tagName,
-		Attributes: make(map[string]string),
-		Context:    p.determineContext(tagName, parent),
-		Parent:     parent,
-	}
-
-	for k, v := range p.currentAttrs {
-		node.Attributes[k] = v
-	}
-
-	node = *p.normalizeElement(&node)
-	parent.Children = append(parent.Children, node)
-
-	p.tagBuffer.Reset()
-	p.currentAttrs = make(map[string]string)
+	p.finishOpenTag()
 }
 
 func (p *StateMachineParser) finishEndTag() {
 	tagName := strings.ToLower(p.tagBuffer.String())
 
-	if tagName == "" || len(p.nodeStack) <= 1 || len(tagName) > 20 {
+	if len(p.nodeStack) == 0 {
 		p.tagBuffer.Reset()
 		return
 	}
 
-	for i := len(p.nodeStack) - 1; i > 0; i-- {
-		current := p.nodeStack[i]
-
-		if current.Node.Tag == tagName || current.OriginalTag == tagName {
-
-			p.nodeStack = p.nodeStack[:i]
-			p.currentDepth = len(p.nodeStack) - 1
-			break
-		}
+	if tagName == "" || len(tagName) > p.maxTagNameLength {
+		p.handleParseError(DiagnosticInvalidTagName, tagName,
+			fmt.Sprintf("end tag name %q is empty or longer than %d characters; tag dropped", tagName, p.maxTagNameLength))
+		return
 	}
 
+	p.closeElement(tagName)
+
 	p.tagBuffer.Reset()
 }
 
-func (p *StateMachineParser) determineContext(tagName string, parent *HTMLNode) NodeContext {
+// determineContext decides whether an element with tagName is laid out as a
+// block or inline inside parent.
+func determineContext(tagName string, parent *HTMLNode) NodeContext {
 	blockTags := map[string]bool{
 		"p": true, "div": true, "h1": true, "h2": true, "h3": true,
 		"h4": true, "h5": true, "h6": true, "ul": true, "ol": true,
@@ -460,31 +663,37 @@ func (p *StateMachineParser) determineContext(tagName string, parent *HTMLNode)
 	return parent.Context
 }
 
-func (p *StateMachineParser) isContainerElement(tagName string) bool {
-	containers := map[string]bool{
-		"p": true, "div": true, "ul": true, "ol": true, "li": true,
-		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
-		"a": true, "b": true, "i": true, "span": true, "pre": true, "code": true,
-		"dl": true, "dt": true, "dd": true,
-		"table": true, "thead": true, "tbody": true, "tr": true, "th": true, "td": true,
-	}
-	return containers[tagName]
-}
-
-func (p *StateMachineParser) normalizeElement(node *HTMLNode) *HTMLNode {
-	switch node.Tag {
-	case "b":
-		node.Tag = "span"
-		node.Attributes["style"] = "font-weight: bold"
-	case "i":
-		node.Tag = "span"
-		node.Attributes["style"] = "font-style: italic"
-	case "strong":
-		node.Tag = "span"
-		node.Attributes["style"] = "font-weight: bold"
-	case "em":
-		node.Tag = "span"
-		node.Attributes["style"] = "font-style: italic"
-	}
-	return node
+// isHTMLSpace reports whether r is one of the ASCII whitespace characters
+// HTML collapses and breaks lines at. U+00A0 (&nbsp;) is deliberately not
+// included.
+func isHTMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
+
+// collapseWhitespace replaces each run of HTML whitespace in s with a single
+// space, as the CSS "white-space: normal" rule does.
+func collapseWhitespace(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	inSpace := false
+	for _, r := range s {
+		if isHTMLSpace(r) {
+			if !inSpace {
+				b.WriteByte(' ')
+				inSpace = true
+			}
+			continue
+		}
+		b.WriteRune(r)
+		inSpace = false
+	}
+
+	return b.String()
+}
+
+// splitHTMLWords splits text at HTML whitespace, keeping non-breaking spaces
+// inside the words they join.
+func splitHTMLWords(text string) []string {
+	return strings.FieldsFunc(text, isHTMLSpace)
 }
@@ -0,0 +1,329 @@
+package marquee
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, html string) HTMLDocument {
+	t.Helper()
+	return NewStateMachineParser(DefaultParserOptions()).Parse(html)
+}
+
+// outline describes the top level of nodes: element tags, and text quoted.
+func outline(nodes []HTMLNode) []string {
+	var items []string
+	for _, node := range nodes {
+		switch node.Type {
+		case NodeTypeElement:
+			items = append(items, node.Tag)
+		case NodeTypeText:
+			items = append(items, "\""+node.Content+"\"")
+		}
+	}
+	return items
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWhitespaceBetweenSiblings(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{"inline at document level", "<b>foo</b> <i>bar</i>", []string{"b", `" "`, "i"}},
+		{"inline after text", "foo\n<i>bar</i>", []string{`"foo "`, "i"}},
+		{"blocks at document level", "<p>foo</p>\n\n<p>bar</p>", []string{"p", "p"}},
+		{"inline before block", "<b>foo</b>\n<p>bar</p>\n", []string{"b", "p"}},
+		{"block before inline", "<p>foo</p> <b>bar</b>", []string{"p", "b"}},
+		{"trailing", "<b>foo</b>\n", []string{"b"}},
+		{"inline in block", "<p><b>foo</b> <i>bar</i></p>", []string{"p"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := parse(t, test.html)
+			if got := outline(doc.Root.Children); !equalStrings(got, test.want) {
+				t.Errorf("Parse(%q) top level = %v, want %v", test.html, got, test.want)
+			}
+		})
+	}
+
+	doc := parse(t, "<p><b>foo</b> <i>bar</i></p>")
+	if got, want := outline(doc.Root.Children[0].Children), []string{"b", `" "`, "i"}; !equalStrings(got, want) {
+		t.Errorf("<p> children = %v, want %v", got, want)
+	}
+
+	doc = parse(t, "<ul>\n  <li>one</li>\n  <li>two</li>\n</ul>")
+	if got, want := outline(doc.Root.Children[0].Children), []string{"li", "li"}; !equalStrings(got, want) {
+		t.Errorf("<ul> children = %v, want %v", got, want)
+	}
+}
+
+func TestPreformattedWhitespace(t *testing.T) {
+	tests := []struct {
+		html, want string
+	}{
+		{"<p>a  \n  b</p>", "<p>a b</p>"},
+		{"<pre>\n  a\n    b  </pre>", "<pre>  a\n    b  </pre>"},
+		{"<pre><code>if x {\n\treturn\n}</code></pre>", "<pre><code>if x {\n\treturn\n}</code></pre>"},
+		{"<textarea>\n a  b</textarea>", "<textarea> a  b</textarea>"},
+		{"<div>Use <code>foo   bar\n  baz</code> here</div>", "<div>Use <code>foo bar baz</code> here</div>"},
+		{"<code>foo   bar</code>", "<code>foo bar</code>"},
+	}
+
+	for _, test := range tests {
+		doc := parse(t, test.html)
+		if got := doc.Serialize(SerializeOptions{}); got != test.want {
+			t.Errorf("Parse(%q) = %q, want %q", test.html, got, test.want)
+		}
+	}
+}
+
+func TestCharacterReferences(t *testing.T) {
+	tests := []struct {
+		html, want string
+	}{
+		{"a &amp; b", "a & b"},
+		{"&lt;p&gt;", "<p>"},
+		{"x&nbsp;y", "x\u00a0y"},
+		{"&mdash;&#8212;&#x2014;&#X2014;", "————"},
+		{"&amp &lt", "& <"},
+		{"&notit; &notin;", "¬it; ∉"},
+		{"&unknown; &", "&unknown; &"},
+		{"&#0;&#x110000;&#xD800;", "\ufffd\ufffd\ufffd"},
+		{"&#128;&#x99;", "€™"},
+		{"&#65", "A"},
+		{"&#;&#x;", "&#;&#x;"},
+	}
+
+	for _, test := range tests {
+		doc := parse(t, "<p>"+test.html+"</p>")
+		if got := doc.Root.Children[0].Children[0].Content; got != test.want {
+			t.Errorf("text of %q = %q, want %q", test.html, got, test.want)
+		}
+	}
+}
+
+func TestAttributeCharacterReferences(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"?a=1&amp;b=2", "?a=1&b=2"},
+		{"?a=1&b=2", "?a=1&b=2"},
+		{"&notit;", "&notit;"},
+		{"&notin;", "∉"},
+		{"&copy=1", "&copy=1"},
+		{"&#x41;&#66;", "AB"},
+	}
+
+	for _, test := range tests {
+		for _, quote := range []string{`"`, `'`} {
+			html := `<a href=` + quote + test.value + quote + `>x</a>`
+			doc := parse(t, html)
+			if got := doc.Root.Children[0].Attributes["href"]; got != test.want {
+				t.Errorf("href of %s = %q, want %q", html, got, test.want)
+			}
+		}
+	}
+}
+
+func TestImpliedEndTags(t *testing.T) {
+	tests := []struct {
+		html, want string
+	}{
+		{"<p>one<p>two", "<p>one</p><p>two</p>"},
+		{"<p>one<div>two</div>", "<p>one</p><div>two</div>"},
+		{"<p>one<b>two</p>three", "<p>one<b>two</b></p>three"},
+		{"<ul><li>one<li>two</ul>", "<ul><li>one</li><li>two</li></ul>"},
+		{"<ul><li>one<ul><li>two</ul><li>three</ul>", "<ul><li>one<ul><li>two</li></ul></li><li>three</li></ul>"},
+		{"<dl><dt>a<dd>b<dt>c</dl>", "<dl><dt>a</dt><dd>b</dd><dt>c</dt></dl>"},
+		{"<table><tr><td>a<td>b<tr><td>c</table>", "<table><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></table>"},
+		{"<table><thead><tr><th>a<tbody><tr><td>b</table>", "<table><thead><tr><th>a</th></tr></thead><tbody><tr><td>b</td></tr></tbody></table>"},
+		{"<select><option>a<option>b</select>", "<select><option>a</option><option>b</option></select>"},
+		{"one<br>two<img src=x>three", `one<br>two<img src="x">three`},
+		{"<p>a<br/>b</p>", "<p>a<br>b</p>"},
+		{"<b>one</i>two</b>", "<b>onetwo</b>"},
+		{"</p>", "<p></p>"},
+	}
+
+	for _, test := range tests {
+		doc := parse(t, test.html)
+		if got := doc.Serialize(SerializeOptions{}); got != test.want {
+			t.Errorf("Parse(%q) = %q, want %q", test.html, got, test.want)
+		}
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	type diagnostic struct {
+		code                 DiagnosticCode
+		severity             DiagnosticSeverity
+		tag                  string
+		line, column, offset int
+	}
+	tests := []struct {
+		html string
+		want []diagnostic
+	}{
+		{"<ul><li>one<li>two</ul><p>three", nil},
+		{"<div><b>x</div>", []diagnostic{{DiagnosticUnclosedTag, SeverityWarning, "b", 1, 6, 5}}},
+		{"<p>a</span></p>", []diagnostic{{DiagnosticStrayEndTag, SeverityWarning, "span", 1, 5, 4}}},
+		{"line one\n<p>two</i>", []diagnostic{{DiagnosticStrayEndTag, SeverityWarning, "i", 2, 7, 15}}},
+		{"é<div>\r\n</em>", []diagnostic{
+			{DiagnosticStrayEndTag, SeverityWarning, "em", 2, 1, 9},
+			{DiagnosticUnclosedTag, SeverityWarning, "div", 1, 2, 2},
+		}},
+		{"<p>\n  <abcdefghijklmnopqrstuvwxyz>x</p>", []diagnostic{{DiagnosticInvalidTagName, SeverityError, "abcdefghijklmnopqrstuvwxyz", 2, 3, 6}}},
+		{"<!-- open", []diagnostic{{DiagnosticUnterminatedComment, SeverityWarning, "", 1, 1, 0}}},
+	}
+
+	for _, test := range tests {
+		var got []diagnostic
+		for _, d := range parse(t, test.html).Diagnostics {
+			got = append(got, diagnostic{d.Code, d.Severity, d.Tag, d.Line, d.Column, d.Offset})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("diagnostics of %q = %+v, want %+v", test.html, got, test.want)
+		}
+	}
+}
+
+func TestParseDiagnosticsLimit(t *testing.T) {
+	options := DefaultParserOptions()
+	options.MaxErrors = 2
+	doc := NewStateMachineParser(options).Parse("</a></b></c></d>")
+
+	var codes []string
+	for _, d := range doc.Diagnostics {
+		codes = append(codes, string(d.Code))
+	}
+	if want := []string{"stray-end-tag", "stray-end-tag", "too-many-errors"}; !equalStrings(codes, want) {
+		t.Errorf("diagnostics = %v, want %v", codes, want)
+	}
+
+	w := newTestWidget("<div><b>x</div>")
+	if got := w.Diagnostics(); len(got) != 1 || got[0].String() != "1:6: warning: <b> is never closed [unclosed-tag]" {
+		t.Errorf("widget Diagnostics() = %v", got)
+	}
+}
+
+func TestParserOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ParserOptions
+		html    string
+		want    string
+		codes   []string
+	}{
+		{
+			"max depth", ParserOptions{MaxDepth: 3},
+			"<div><div><div><div>x</div></div></div></div>",
+			"<div><div><div><div></div>x</div></div></div>",
+			[]string{"max-depth-exceeded", "stray-end-tag"},
+		},
+		{
+			"max length", ParserOptions{MaxLength: 10},
+			"<p>hello world</p>",
+			"<p>hello w</p>",
+			[]string{"truncated-input"},
+		},
+		{
+			"max tag name length", ParserOptions{MaxTagNameLength: 3},
+			"<div>a<span>b</span>c</div>",
+			"<div>abc</div>",
+			[]string{"invalid-tag-name", "invalid-tag-name"},
+		},
+		{
+			"defaults", ParserOptions{},
+			"<div><div><div><div>x</div></div></div></div>",
+			"<div><div><div><div>x</div></div></div></div>",
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		doc := NewStateMachineParser(test.options).Parse(test.html)
+		if got := doc.Serialize(SerializeOptions{}); got != test.want {
+			t.Errorf("%s: Parse(%q) = %q, want %q", test.name, test.html, got, test.want)
+		}
+		var codes []string
+		for _, d := range doc.Diagnostics {
+			codes = append(codes, string(d.Code))
+		}
+		if !equalStrings(codes, test.codes) {
+			t.Errorf("%s: diagnostics = %v, want %v", test.name, codes, test.codes)
+		}
+	}
+
+	want := DefaultParserOptions()
+	want.MaxErrors = 7
+	if got := NewStateMachineParser(ParserOptions{MaxErrors: 7}).Options(); got != want {
+		t.Errorf("Options() = %+v, want the defaults with MaxErrors 7, %+v", got, want)
+	}
+}
+
+func TestCommentsAfterFirstKilobyte(t *testing.T) {
+	options := DefaultParserOptions()
+	options.KeepComments = true
+	html := "<p>" + strings.Repeat("x", 2000) + "</p><!-- help-id: late --><p>after</p>"
+	doc := NewStateMachineParser(options).Parse(html)
+
+	if got, want := outline(doc.Root.Children), []string{"p", "p"}; len(doc.Root.Children) != 3 || !equalStrings(got, want) {
+		t.Fatalf("top level = %v and %d nodes, want %v around a comment", got, len(doc.Root.Children), want)
+	}
+	if comment := doc.Root.Children[1]; comment.Type != NodeTypeComment || comment.Content != " help-id: late " {
+		t.Errorf("comment = %+v", comment)
+	}
+}
+
+func TestMarkupDeclarations(t *testing.T) {
+	tests := []struct {
+		html, want, docType string
+		wantComments        string
+	}{
+		{`<!DOCTYPE html><p>a</p>`, "<p>a</p>", "html", ""},
+		{`<!doctype HTML PUBLIC "-//W3C//DTD HTML 4.01//EN"><p>a</p>`, "<p>a</p>", `HTML PUBLIC "-//W3C//DTD HTML 4.01//EN"`, ""},
+		{`<?xml version="1.0"?><p>a</p>`, "<p>a</p>", "", ""},
+		{`<p>a<![CDATA[x < y]]>b</p>`, "<p>ax &lt; yb</p>", "", ""},
+		{`<p>a<!-- c -- d -->b</p>`, "<p>ab</p>", "", "<p>a<!-- c -- d -->b</p>"},
+		{`<p>a<!---->b</p>`, "<p>ab</p>", "", "<p>a<!---->b</p>"},
+		{`<p>a<!bogus>b</p>`, "<p>ab</p>", "", "<p>a<!--bogus-->b</p>"},
+		{`<!-- help-id: export-dialog --><p>a</p>`, "<p>a</p>", "", "<!-- help-id: export-dialog --><p>a</p>"},
+	}
+
+	keep := DefaultParserOptions()
+	keep.KeepComments = true
+	for _, test := range tests {
+		doc := parse(t, test.html)
+		doc.Metadata.DocType = ""
+		if got := doc.Serialize(SerializeOptions{}); got != test.want {
+			t.Errorf("Parse(%q) = %q, want %q", test.html, got, test.want)
+		}
+		if doc := parse(t, test.html); doc.Metadata.DocType != test.docType {
+			t.Errorf("DOCTYPE of %q = %q, want %q", test.html, doc.Metadata.DocType, test.docType)
+		}
+
+		want := test.wantComments
+		if want == "" {
+			want = test.want
+		}
+		doc = NewStateMachineParser(keep).Parse(test.html)
+		doc.Metadata.DocType = ""
+		if got := doc.Serialize(SerializeOptions{}); got != want {
+			t.Errorf("Parse(%q) keeping comments = %q, want %q", test.html, got, want)
+		}
+	}
+}
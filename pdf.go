@@ -0,0 +1,317 @@
+package marquee
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// PageSize is the size of a PDF page in points, 72 to the inch. The layout
+// is made with one point to a pixel, so text keeps the sizes it has in the
+// widget.
+type PageSize struct {
+	Width, Height float32
+}
+
+var (
+	PageA4     = PageSize{Width: 595.28, Height: 841.89}
+	PageLetter = PageSize{Width: 612, Height: 792}
+)
+
+// Margins are the space left blank around the content of each page, in
+// points.
+type Margins struct {
+	Top, Right, Bottom, Left float32
+}
+
+// PDFOptions controls how RenderPDF lays out pages.
+type PDFOptions struct {
+	// PageSize is the size of each page. The zero value means A4.
+	PageSize PageSize
+
+	// Margins are the margins of each page. The zero value means an inch on
+	// every side.
+	Margins Margins
+}
+
+func (o PDFOptions) withDefaults() PDFOptions {
+	if o.PageSize == (PageSize{}) {
+		o.PageSize = PageA4
+	}
+	if o.Margins == (Margins{}) {
+		o.Margins = Margins{Top: 72, Right: 72, Bottom: 72, Left: 72}
+	}
+	return o
+}
+
+// RenderPDF lays document out to the width of the page between its margins
+// and writes it to w as a PDF, split into as many pages as it needs. Pages
+// break between lines of text and between table rows, never through them,
+// and the header row of a table that continues onto a new page is repeated
+// at its top. Text is measured with r.Measurer if it is a TTFMeasurer and
+// otherwise with the font files the widget draws with, which are embedded,
+// and links can be clicked: links to other pages follow the URL, and links
+// to an id in the document go to the page it is on.
+func (r *HTMLRenderer) RenderPDF(w io.Writer, document HTMLDocument, options PDFOptions) error {
+	options = options.withDefaults()
+	page := options.PageSize
+	margins := options.Margins
+
+	contentWidth := page.Width - margins.Left - margins.Right
+	contentHeight := page.Height - margins.Top - margins.Bottom
+	if contentWidth <= 0 || contentHeight <= 0 {
+		return fmt.Errorf("marquee: PDF margins leave no room on a %gx%g page", page.Width, page.Height)
+	}
+
+	renderer, measurer := r.withTTFMeasurer()
+	layout := renderer.LayoutDocument(document, RenderContext{Width: contentWidth})
+	pages := paginate(layout, measurer, contentHeight)
+
+	file := &pdfFile{}
+	catalog := file.reserve()
+	pagesID := file.reserve()
+	resourcesID := file.reserve()
+	resources := newPDFResources(file)
+
+	pageIDs := make([]int, len(pages))
+	for i := range pages {
+		pageIDs[i] = file.reserve()
+	}
+
+	for i, p := range pages {
+		canvas := &pdfCanvas{resources: resources, measurer: measurer, height: page.Height}
+		canvas.FillRect(rl.NewRectangle(0, 0, page.Width, page.Height), r.Theme.Colors.Background)
+		fullWidth := rl.NewRectangle(0, margins.Top, page.Width, 0)
+
+		// The repeated header goes at the top of the page, and the content
+		// of the page under it.
+		headerHeight := float32(0)
+		if p.header != nil {
+			headerHeight = p.header.Bounds.Height
+			clip := fullWidth
+			clip.Height = headerHeight
+			canvas.PushClip(clip)
+			p.header.Paint(canvas, margins.Left, margins.Top-p.header.Bounds.Y, clip)
+			canvas.PopClip()
+		}
+
+		offsetY := margins.Top + headerHeight - p.top
+		clip := fullWidth
+		clip.Y += headerHeight
+		clip.Height = p.bottom - p.top
+		canvas.PushClip(clip)
+		layout.Root.Paint(canvas, margins.Left, offsetY, shrinkVertically(clip, 0.01))
+		canvas.PopClip()
+
+		var annotations []int
+		for _, link := range layout.Links {
+			if link.Bounds.Y < p.top || link.Bounds.Y >= p.bottom {
+				continue
+			}
+			bounds := link.Bounds
+			bounds.X += margins.Left
+			bounds.Y += offsetY
+			annotations = append(annotations, file.add(linkAnnotation(link.URL, bounds, page.Height, layout, pages, pageIDs, margins.Top)))
+		}
+
+		contents := file.addStream("", canvas.content.Bytes(), true)
+		var annots string
+		if len(annotations) > 0 {
+			refs := make([]string, len(annotations))
+			for j, id := range annotations {
+				refs[j] = fmt.Sprintf("%d 0 R", id)
+			}
+			annots = " /Annots [" + strings.Join(refs, " ") + "]"
+		}
+		file.set(pageIDs[i], fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %d 0 R /Contents %d 0 R%s >>",
+			pagesID, pdfNumber(page.Width), pdfNumber(page.Height), resourcesID, contents, annots))
+	}
+
+	resources.write(resourcesID)
+
+	kids := make([]string, len(pageIDs))
+	for i, id := range pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	file.set(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pageIDs)))
+	file.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+
+	info := "<< /Producer (marquee)"
+	if title := document.Metadata.Title; title != "" {
+		info += " /Title " + pdfString(title)
+	}
+	infoID := file.add(info + " >>")
+
+	return file.writeTo(w, catalog, infoID)
+}
+
+// RenderToPDF renders document with the default renderer and returns it as
+// a PDF; see HTMLRenderer.RenderPDF.
+func RenderToPDF(document HTMLDocument, options PDFOptions) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := NewHTMLRenderer().RenderPDF(&buffer, document, options); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// linkAnnotation returns a link annotation over bounds. Links to an anchor
+// in the document go to its page; anything else is opened as a URI.
+func linkAnnotation(url string, bounds rl.Rectangle, pageHeight float32, layout *DocumentLayout, pages []pdfPage, pageIDs []int, marginTop float32) string {
+	rect := fmt.Sprintf("[%s %s %s %s]", pdfNumber(bounds.X), pdfNumber(pageHeight-bounds.Y-bounds.Height),
+		pdfNumber(bounds.X+bounds.Width), pdfNumber(pageHeight-bounds.Y))
+
+	action := fmt.Sprintf("/A << /S /URI /URI %s >>", pdfString(url))
+	if id, found := strings.CutPrefix(url, "#"); found {
+		if y, exists := layout.Anchors[id]; exists {
+			for i, p := range pages {
+				if y < p.bottom || i == len(pages)-1 {
+					top := pageHeight - (marginTop + y - p.top)
+					action = fmt.Sprintf("/Dest [%d 0 R /XYZ null %s null]", pageIDs[i], pdfNumber(top))
+					break
+				}
+			}
+		}
+	}
+
+	return fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect %s /Border [0 0 0] %s >>", rect, action)
+}
+
+func shrinkVertically(r rl.Rectangle, amount float32) rl.Rectangle {
+	return rl.NewRectangle(r.X, r.Y+amount, r.Width, r.Height-2*amount)
+}
+
+// pdfPage is the part of the layout shown on one page, from top to bottom,
+// and the header row repeated above it when it continues a table.
+type pdfPage struct {
+	top, bottom float32
+	header      *LayoutBox
+}
+
+// pageBreaks records what must not be split across pages: each line of
+// text and each table row, as vertical spans of the layout, and the tables
+// whose header row is repeated. end is the bottom of the last thing drawn,
+// above the margin the layout ends with.
+type pageBreaks struct {
+	measurer *TTFMeasurer
+	atoms    []span
+	tables   []pdfTable
+	end      float32
+}
+
+type span struct {
+	top, bottom float32
+}
+
+type pdfTable struct {
+	span
+	header *LayoutBox
+}
+
+// paginate splits the layout into pages with height points of content
+// each.
+func paginate(layout *DocumentLayout, measurer *TTFMeasurer, height float32) []pdfPage {
+	breaks := &pageBreaks{measurer: measurer}
+	breaks.collect(&layout.Root)
+	sort.Slice(breaks.atoms, func(i, j int) bool { return breaks.atoms[i].top < breaks.atoms[j].top })
+
+	var pages []pdfPage
+	top := float32(0)
+	for {
+		header := breaks.headerAt(top)
+		headerHeight := float32(0)
+		if header != nil {
+			headerHeight = header.Bounds.Height
+			if headerHeight >= height/2 {
+				header, headerHeight = nil, 0
+			}
+		}
+
+		// The margin below the last thing drawn does not start a page of
+		// its own.
+		limit := top + height - headerHeight
+		if limit >= breaks.end {
+			return append(pages, pdfPage{top: top, bottom: max(breaks.end, top), header: header})
+		}
+
+		// Break above the first line or row that would cross the bottom of
+		// the page, unless it is already at the top and taller than a page.
+		bottom := limit
+		for _, atom := range breaks.atoms {
+			if atom.top >= limit {
+				break
+			}
+			if atom.top > top && atom.bottom > limit {
+				bottom = min(bottom, atom.top)
+			}
+		}
+
+		pages = append(pages, pdfPage{top: top, bottom: bottom, header: header})
+		top = bottom
+	}
+}
+
+func (pb *pageBreaks) collect(box *LayoutBox) {
+	if box.Background.A != 0 || (box.BorderColor.A != 0 && box.BorderWidth > 0) {
+		pb.end = max(pb.end, box.Bounds.Y+box.Bounds.Height)
+	}
+
+	if box.Tag == "tr" {
+		pb.add(span{box.Bounds.Y, box.Bounds.Y + box.Bounds.Height})
+		return
+	}
+
+	if box.Tag == "table" && len(box.Children) > 0 && isHeaderRow(&box.Children[0]) {
+		pb.tables = append(pb.tables, pdfTable{
+			span:   span{box.Bounds.Y, box.Bounds.Y + box.Bounds.Height},
+			header: &box.Children[0],
+		})
+	}
+
+	for _, line := range box.Lines {
+		bottom := line.Bounds.Y + line.Bounds.Height
+		for _, fragment := range line.Fragments {
+			metrics := pb.measurer.Metrics(fragment.Font)
+			bottom = max(bottom, fragment.Bounds.Y+metrics.Ascent+metrics.Descent)
+		}
+		pb.add(span{line.Bounds.Y, bottom})
+	}
+
+	for i := range box.Children {
+		pb.collect(&box.Children[i])
+	}
+}
+
+func (pb *pageBreaks) add(atom span) {
+	pb.atoms = append(pb.atoms, atom)
+	pb.end = max(pb.end, atom.bottom)
+}
+
+// headerAt returns the header row to repeat on a page starting at y, which
+// is the header of a table that y falls inside, below that header.
+func (pb *pageBreaks) headerAt(y float32) *LayoutBox {
+	for _, table := range pb.tables {
+		headerBottom := table.header.Bounds.Y + table.header.Bounds.Height
+		if y >= headerBottom && y < table.bottom {
+			return table.header
+		}
+	}
+	return nil
+}
+
+func isHeaderRow(row *LayoutBox) bool {
+	if row.Tag != "tr" {
+		return false
+	}
+	for _, cell := range row.Children {
+		if cell.Tag == "th" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package marquee
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"regexp"
+	"strings"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var pdfPageObject = regexp.MustCompile(`/Type /Page\b`)
+
+func renderPDF(t *testing.T, r *HTMLRenderer, html string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	if err := r.RenderPDF(&buffer, parse(t, html), PDFOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestPaginate(t *testing.T) {
+	var html strings.Builder
+	html.WriteString("<table border=1><tr><th>Name</th><th>Value</th></tr>")
+	for i := 0; i < 80; i++ {
+		fmt.Fprintf(&html, "<tr><td>row %d</td><td>%d</td></tr>", i, i)
+	}
+	html.WriteString(`</table><p><a href="#top">Back to the top</a></p>`)
+
+	r := NewHTMLRenderer()
+	r.Measurer = GoFontMeasurer()
+	height := PageA4.Height - 144
+	layout := r.LayoutDocument(parse(t, html.String()), RenderContext{Width: PageA4.Width - 144})
+	pages := paginate(layout, GoFontMeasurer(), height)
+
+	// The paragraph's bottom margin is all that is left after the link, and
+	// does not take a page of its own.
+	if len(pages) != 8 {
+		t.Fatalf("got %d pages, want 8", len(pages))
+	}
+	for i, page := range pages {
+		if i > 0 && page.top != pages[i-1].bottom {
+			t.Errorf("page %d starts at %v, not where page %d ends", i+1, page.top, i)
+		}
+		headerHeight := float32(0)
+		if page.header != nil {
+			headerHeight = page.header.Bounds.Height
+		}
+		if page.bottom-page.top+headerHeight > height {
+			t.Errorf("page %d is %v tall, more than %v", i+1, page.bottom-page.top+headerHeight, height)
+		}
+		if i > 0 && page.header == nil {
+			t.Errorf("page %d does not repeat the table header", i+1)
+		}
+	}
+
+	if got := len(pdfPageObject.FindAll(renderPDF(t, r, html.String()), -1)); got != 8 {
+		t.Errorf("PDF has %d pages, want 8", got)
+	}
+}
+
+func TestPaginateShortDocuments(t *testing.T) {
+	r := NewHTMLRenderer()
+	r.Measurer = GoFontMeasurer()
+
+	for _, html := range []string{"", "<p>One line.</p>", `<p style="margin-bottom: 2000px">Big margin.</p>`} {
+		if got := len(pdfPageObject.FindAll(renderPDF(t, r, html), -1)); got != 1 {
+			t.Errorf("%q: PDF has %d pages, want 1", html, got)
+		}
+	}
+}
+
+func TestPDFCanvasOpacity(t *testing.T) {
+	file := &pdfFile{}
+	resources := newPDFResources(file)
+	canvas := &pdfCanvas{resources: resources, measurer: GoFontMeasurer(), height: 100}
+
+	canvas.FillRect(rl.NewRectangle(0, 0, 100, 100), rl.NewColor(30, 30, 30, 255))
+	canvas.FillRect(rl.NewRectangle(10, 10, 20, 20), rl.NewColor(255, 255, 255, 128))
+
+	// The translucent white is not blended with anything, so it lightens
+	// the dark page rather than painting it grey.
+	want := "0.118 0.118 0.118 rg 0 0 100 100 re f\n" +
+		"q /GA128 gs 1.000 1.000 1.000 rg 10 70 20 20 re f\nQ\n"
+	if got := canvas.content.String(); got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+
+	id := file.reserve()
+	resources.write(id)
+	if got := string(file.objects[id-1]); !strings.Contains(got, "/ExtGState << /GA128 << /ca 0.502 /CA 0.502 >> >>") {
+		t.Errorf("resources = %s, want a graphics state with opacity 0.502", got)
+	}
+}
+
+func TestPDFImageSoftMask(t *testing.T) {
+	file := &pdfFile{}
+	resources := newPDFResources(file)
+
+	opaque := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	draw.Draw(opaque, opaque.Bounds(), image.NewUniform(color.RGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+	resources.image(opaque)
+	if len(file.objects) != 1 || bytes.Contains(file.objects[0], []byte("/SMask")) {
+		t.Errorf("an opaque image was given a soft mask")
+	}
+
+	translucent := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	translucent.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 64})
+	resources.image(translucent)
+	if len(file.objects) != 3 || !bytes.Contains(file.objects[2], []byte("/SMask 2 0 R")) {
+		t.Errorf("a translucent image was not given a soft mask")
+	}
+}
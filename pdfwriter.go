@@ -0,0 +1,408 @@
+package marquee
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// pdfFile collects the objects of a PDF file and writes them out with their
+// cross-reference table. Object numbers start at 1.
+type pdfFile struct {
+	objects [][]byte
+}
+
+// reserve allocates an object number whose body is set later.
+func (f *pdfFile) reserve() int {
+	f.objects = append(f.objects, nil)
+	return len(f.objects)
+}
+
+func (f *pdfFile) set(id int, body string) {
+	f.objects[id-1] = []byte(body)
+}
+
+func (f *pdfFile) add(body string) int {
+	id := f.reserve()
+	f.set(id, body)
+	return id
+}
+
+// addStream adds a stream object with extra entries in its dictionary,
+// compressed when compress is set.
+func (f *pdfFile) addStream(dictionary string, data []byte, compress bool) int {
+	if compress {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		writer.Write(data)
+		writer.Close()
+		data = compressed.Bytes()
+		dictionary += " /Filter /FlateDecode"
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "<< /Length %d%s >>\nstream\n", len(data), dictionary)
+	body.Write(data)
+	body.WriteString("\nendstream")
+
+	id := f.reserve()
+	f.objects[id-1] = body.Bytes()
+	return id
+}
+
+func (f *pdfFile) writeTo(w io.Writer, catalog, info int) error {
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(f.objects))
+	for i, body := range f.objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(body)
+		out.WriteString("\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(f.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(f.objects)+1, catalog, info, xref)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// pdfFont is a TrueType face embedded as a Type0 font with Identity-H
+// encoding, so text is written as glyph numbers and any character the font
+// has can be shown. glyphs records the characters used, for the widths and
+// the ToUnicode map that lets readers copy the text.
+type pdfFont struct {
+	name   string
+	id     int
+	face   *ttfFace
+	buffer sfnt.Buffer
+	glyphs map[sfnt.GlyphIndex]rune
+}
+
+func (pf *pdfFont) glyphIndex(r rune) sfnt.GlyphIndex {
+	index, err := pf.face.font.GlyphIndex(&pf.buffer, r)
+	if err != nil {
+		return 0
+	}
+	if _, exists := pf.glyphs[index]; !exists || index == 0 {
+		pf.glyphs[index] = r
+	}
+	return index
+}
+
+// toFontUnits converts a length in 26.6 fixed point at one pixel per font
+// unit into the thousandths of an em PDF font metrics use.
+func (pf *pdfFont) toFontUnits(v fixed.Int26_6) int {
+	return int(float64(v) / 64 / float64(pf.face.font.UnitsPerEm()) * 1000)
+}
+
+// write adds the objects describing the font to file.
+func (pf *pdfFont) write(file *pdfFile) {
+	f := pf.face.font
+	ppem := fixed.I(int(f.UnitsPerEm()))
+
+	baseName, err := f.Name(&pf.buffer, sfnt.NameIDPostScript)
+	if err != nil || baseName == "" {
+		baseName = pf.name
+	}
+	baseName = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+			return -1
+		}
+		return r
+	}, baseName)
+
+	bounds, _ := f.Bounds(&pf.buffer, ppem, font.HintingNone)
+	metrics, _ := f.Metrics(&pf.buffer, ppem, font.HintingNone)
+	flags := 32
+	italicAngle := 0
+	if post := f.PostTable(); post != nil && post.ItalicAngle != 0 {
+		flags |= 64
+		italicAngle = int(post.ItalicAngle)
+	}
+
+	fontFile := file.addStream(fmt.Sprintf(" /Length1 %d", len(pf.face.data)), pf.face.data, true)
+	descriptor := file.add(fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /%s /Flags %d /FontBBox [%d %d %d %d] /ItalicAngle %d /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		baseName, flags,
+		pf.toFontUnits(bounds.Min.X), -pf.toFontUnits(bounds.Max.Y), pf.toFontUnits(bounds.Max.X), -pf.toFontUnits(bounds.Min.Y),
+		italicAngle, pf.toFontUnits(metrics.Ascent), -pf.toFontUnits(metrics.Descent), pf.toFontUnits(metrics.CapHeight),
+		fontFile))
+
+	indices := make([]sfnt.GlyphIndex, 0, len(pf.glyphs))
+	for index := range pf.glyphs {
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	var widths strings.Builder
+	for _, index := range indices {
+		advance, err := f.GlyphAdvance(&pf.buffer, index, ppem, font.HintingNone)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&widths, "%d [%d] ", index, pf.toFontUnits(advance))
+	}
+
+	cidFont := file.add(fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /W [%s] /CIDToGIDMap /Identity >>",
+		baseName, descriptor, widths.String()))
+	toUnicode := file.addStream("", pf.toUnicodeCMap(indices), true)
+
+	file.set(pf.id, fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		baseName, cidFont, toUnicode))
+}
+
+// toUnicodeCMap maps each glyph used back to its character.
+func (pf *pdfFont) toUnicodeCMap(indices []sfnt.GlyphIndex) []byte {
+	var cmap bytes.Buffer
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	cmap.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	cmap.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	cmap.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	for start := 0; start < len(indices); start += 100 {
+		end := min(start+100, len(indices))
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", end-start)
+		for _, index := range indices[start:end] {
+			fmt.Fprintf(&cmap, "<%04X> <%s>\n", index, utf16Hex(string(pf.glyphs[index])))
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return cmap.Bytes()
+}
+
+// pdfResources are the fonts, images and opacities shared by the pages of a
+// document.
+type pdfResources struct {
+	file     *pdfFile
+	fonts    map[*ttfFace]*pdfFont
+	order    []*pdfFont
+	images   map[image.Image]string
+	xobjects []string
+	alphas   map[uint8]bool
+}
+
+func newPDFResources(file *pdfFile) *pdfResources {
+	return &pdfResources{
+		file:   file,
+		fonts:  make(map[*ttfFace]*pdfFont),
+		images: make(map[image.Image]string),
+		alphas: make(map[uint8]bool),
+	}
+}
+
+// alpha returns the name of the graphics state that paints with opacity
+// alpha, adding it the first time it is used.
+func (res *pdfResources) alpha(alpha uint8) string {
+	res.alphas[alpha] = true
+	return fmt.Sprintf("GA%d", alpha)
+}
+
+func (res *pdfResources) font(face *ttfFace) *pdfFont {
+	if pf, exists := res.fonts[face]; exists {
+		return pf
+	}
+	pf := &pdfFont{
+		name:   fmt.Sprintf("F%d", len(res.order)+1),
+		id:     res.file.reserve(),
+		face:   face,
+		glyphs: make(map[sfnt.GlyphIndex]rune),
+	}
+	res.fonts[face] = pf
+	res.order = append(res.order, pf)
+	return pf
+}
+
+// image adds img as an image object the first time it is drawn and returns
+// its resource name. An image with transparent pixels gets a soft mask, so
+// what is under it shows through.
+func (res *pdfResources) image(img image.Image) string {
+	if name, exists := res.images[img]; exists {
+		return name
+	}
+
+	bounds := img.Bounds()
+	pixels := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	alphas := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			pixels = append(pixels, c.R, c.G, c.B)
+			alphas = append(alphas, c.A)
+			opaque = opaque && c.A == 0xff
+		}
+	}
+
+	dictionary := fmt.Sprintf(" /Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8", bounds.Dx(), bounds.Dy())
+	mask := ""
+	if !opaque {
+		maskID := res.file.addStream(dictionary+" /ColorSpace /DeviceGray", alphas, true)
+		mask = fmt.Sprintf(" /SMask %d 0 R", maskID)
+	}
+	id := res.file.addStream(dictionary+" /ColorSpace /DeviceRGB"+mask, pixels, true)
+	name := fmt.Sprintf("Im%d", len(res.xobjects)+1)
+	res.images[img] = name
+	res.xobjects = append(res.xobjects, fmt.Sprintf("/%s %d 0 R", name, id))
+	return name
+}
+
+// write adds the fonts and the resource dictionary to the file.
+func (res *pdfResources) write(id int) {
+	var fonts strings.Builder
+	for _, pf := range res.order {
+		pf.write(res.file)
+		fmt.Fprintf(&fonts, "/%s %d 0 R ", pf.name, pf.id)
+	}
+
+	alphas := make([]int, 0, len(res.alphas))
+	for alpha := range res.alphas {
+		alphas = append(alphas, int(alpha))
+	}
+	sort.Ints(alphas)
+	var states strings.Builder
+	for _, alpha := range alphas {
+		opacity := strconv.FormatFloat(float64(alpha)/255, 'f', 3, 64)
+		fmt.Fprintf(&states, "/%s << /ca %s /CA %s >> ", res.alpha(uint8(alpha)), opacity, opacity)
+	}
+
+	res.file.set(id, fmt.Sprintf("<< /Font << %s>> /XObject << %s >> /ExtGState << %s>> >>",
+		fonts.String(), strings.Join(res.xobjects, " "), states.String()))
+}
+
+// pdfCanvas is a Canvas that writes the content stream of one page.
+// Coordinates are flipped, since PDF measures up from the bottom of the
+// page.
+type pdfCanvas struct {
+	content   bytes.Buffer
+	resources *pdfResources
+	measurer  *TTFMeasurer
+	height    float32
+}
+
+func (c *pdfCanvas) printf(format string, args ...any) {
+	fmt.Fprintf(&c.content, format, args...)
+}
+
+func (c *pdfCanvas) DrawText(text string, x, y float32, f Font, color rl.Color) {
+	pf := c.resources.font(c.measurer.faceFor(f))
+
+	var glyphs strings.Builder
+	for _, r := range text {
+		fmt.Fprintf(&glyphs, "%04X", pf.glyphIndex(r))
+	}
+
+	baseline := y + c.measurer.Metrics(f).Ascent
+	c.paint(color, "%s rg BT /%s %s Tf 1 0 0 1 %s %s Tm <%s> Tj ET\n",
+		pdfColor(color), pf.name, pdfNumber(f.Size), pdfNumber(x), pdfNumber(c.height-baseline), glyphs.String())
+}
+
+func (c *pdfCanvas) FillRect(bounds rl.Rectangle, color rl.Color) {
+	c.paint(color, "%s rg %s re f\n", pdfColor(color), c.rect(bounds))
+}
+
+// StrokeRect draws the border inside bounds, as raylib does.
+func (c *pdfCanvas) StrokeRect(bounds rl.Rectangle, width float32, color rl.Color) {
+	inset := width / 2
+	inner := rl.NewRectangle(bounds.X+inset, bounds.Y+inset, bounds.Width-width, bounds.Height-width)
+	c.paint(color, "%s RG %s w %s re S\n", pdfColor(color), pdfNumber(width), c.rect(inner))
+}
+
+func (c *pdfCanvas) DrawLine(x1, y1, x2, y2, width float32, color rl.Color) {
+	c.paint(color, "%s RG %s w %s %s m %s %s l S\n", pdfColor(color), pdfNumber(width),
+		pdfNumber(x1), pdfNumber(c.height-y1), pdfNumber(x2), pdfNumber(c.height-y2))
+}
+
+// paint writes an operation that draws in color, with the opacity of color
+// set around it when it is translucent.
+func (c *pdfCanvas) paint(color rl.Color, format string, args ...any) {
+	if color.A == 0xff {
+		c.printf(format, args...)
+		return
+	}
+	c.printf("q /%s gs ", c.resources.alpha(color.A))
+	c.printf(format, args...)
+	c.printf("Q\n")
+}
+
+func (c *pdfCanvas) DrawImage(img image.Image, bounds rl.Rectangle) {
+	name := c.resources.image(img)
+	c.printf("q %s 0 0 %s %s %s cm /%s Do Q\n", pdfNumber(bounds.Width), pdfNumber(bounds.Height),
+		pdfNumber(bounds.X), pdfNumber(c.height-bounds.Y-bounds.Height), name)
+}
+
+func (c *pdfCanvas) PushClip(bounds rl.Rectangle) {
+	c.printf("q %s re W n\n", c.rect(bounds))
+}
+
+func (c *pdfCanvas) PopClip() {
+	c.printf("Q\n")
+}
+
+// rect returns the operands of re for bounds.
+func (c *pdfCanvas) rect(bounds rl.Rectangle) string {
+	return fmt.Sprintf("%s %s %s %s", pdfNumber(bounds.X), pdfNumber(c.height-bounds.Y-bounds.Height),
+		pdfNumber(bounds.Width), pdfNumber(bounds.Height))
+}
+
+// pdfColor returns the operands of rg or RG for color, leaving out its
+// alpha, which paint sets.
+func pdfColor(color rl.Color) string {
+	channel := func(v uint8) string {
+		return strconv.FormatFloat(float64(v)/255, 'f', 3, 64)
+	}
+	return channel(color.R) + " " + channel(color.G) + " " + channel(color.B)
+}
+
+func pdfNumber(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
+
+// pdfString returns s as a PDF text string: a literal string when it is
+// plain ASCII, or UTF-16 with a byte order mark otherwise.
+func pdfString(s string) string {
+	for _, r := range s {
+		if r > '~' {
+			return "<FEFF" + utf16Hex(s) + ">"
+		}
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", `\r`, "\n", `\n`)
+	return "(" + replacer.Replace(s) + ")"
+}
+
+// utf16Hex returns s in UTF-16BE as hexadecimal digits.
+func utf16Hex(s string) string {
+	var hex strings.Builder
+	for _, r := range s {
+		if r >= 0x10000 {
+			r -= 0x10000
+			fmt.Fprintf(&hex, "%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			continue
+		}
+		fmt.Fprintf(&hex, "%04X", r)
+	}
+	return hex.String()
+}
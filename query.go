@@ -0,0 +1,185 @@
+package marquee
+
+import (
+	"strings"
+)
+
+// WalkAction tells Walk how to continue after a node has been entered.
+type WalkAction int
+
+const (
+	// WalkContinue visits the node's children next.
+	WalkContinue WalkAction = iota
+	// WalkSkipChildren goes on with the node's next sibling.
+	WalkSkipChildren
+	// WalkStop ends the walk. No further callbacks are made.
+	WalkStop
+)
+
+// Walk visits n and everything under it in document order. enter is called
+// before a node's children and exit after them; exit is also called for a
+// node whose children were skipped. Either callback may be nil, and a nil
+// enter visits everything. The nodes passed are the ones in the tree, so
+// callbacks may change them.
+func (n *HTMLNode) Walk(enter func(node *HTMLNode) WalkAction, exit func(node *HTMLNode)) {
+	walkNode(n, enter, exit)
+}
+
+func walkNode(node *HTMLNode, enter func(*HTMLNode) WalkAction, exit func(*HTMLNode)) bool {
+	action := WalkContinue
+	if enter != nil {
+		action = enter(node)
+	}
+
+	switch action {
+	case WalkStop:
+		return false
+	case WalkContinue:
+		for i := range node.Children {
+			if !walkNode(&node.Children[i], enter, exit) {
+				return false
+			}
+		}
+	}
+
+	if exit != nil {
+		exit(node)
+	}
+	return true
+}
+
+// ID returns the element's id attribute, or "".
+func (n *HTMLNode) ID() string {
+	return n.Attributes["id"]
+}
+
+// HasClass reports whether the element's class attribute lists name.
+// Class names are case-sensitive.
+func (n *HTMLNode) HasClass(name string) bool {
+	for _, class := range splitHTMLWords(n.Attributes["class"]) {
+		if class == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TextContent returns the text of n and everything under it, as written in
+// the document.
+func (n *HTMLNode) TextContent() string {
+	return nodeText(*n)
+}
+
+// GetElementByID returns the first element under n whose id is id, or nil
+// if there is none.
+func (n *HTMLNode) GetElementByID(id string) *HTMLNode {
+	var found *HTMLNode
+	n.Walk(func(node *HTMLNode) WalkAction {
+		if node != n && node.Type == NodeTypeElement && node.Attributes["id"] == id {
+			found = node
+			return WalkStop
+		}
+		return WalkContinue
+	}, nil)
+	return found
+}
+
+// GetElementsByTagName returns the elements under n with the given tag name,
+// in document order. The name is not case-sensitive, and "*" matches every
+// element.
+func (n *HTMLNode) GetElementsByTagName(tagName string) []*HTMLNode {
+	tagName = strings.ToLower(tagName)
+	return n.collectElements(func(node *HTMLNode) bool {
+		return tagName == "*" || node.Tag == tagName
+	})
+}
+
+// GetElementsByClassName returns the elements under n that have all of the
+// space-separated class names in names, in document order.
+func (n *HTMLNode) GetElementsByClassName(names string) []*HTMLNode {
+	classes := splitHTMLWords(names)
+	if len(classes) == 0 {
+		return nil
+	}
+	return n.collectElements(func(node *HTMLNode) bool {
+		for _, class := range classes {
+			if !node.HasClass(class) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// QuerySelector returns the first element under n that matches the CSS
+// selector, or nil. See Selector for the supported syntax.
+func (n *HTMLNode) QuerySelector(selector string) (*HTMLNode, error) {
+	parsed, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.MatchFirst(n), nil
+}
+
+// QuerySelectorAll returns the elements under n that match the CSS selector,
+// in document order.
+func (n *HTMLNode) QuerySelectorAll(selector string) ([]*HTMLNode, error) {
+	parsed, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return parsed.MatchAll(n), nil
+}
+
+// collectElements returns the elements below n for which match is true.
+func (n *HTMLNode) collectElements(match func(node *HTMLNode) bool) []*HTMLNode {
+	var elements []*HTMLNode
+	n.Walk(func(node *HTMLNode) WalkAction {
+		if node != n && node.Type == NodeTypeElement && match(node) {
+			elements = append(elements, node)
+		}
+		return WalkContinue
+	}, nil)
+	return elements
+}
+
+// GetElementByID returns the first element in the document whose id is id,
+// or nil.
+func (d *HTMLDocument) GetElementByID(id string) *HTMLNode {
+	d.linkRoot()
+	return d.Root.GetElementByID(id)
+}
+
+// GetElementsByTagName returns the document's elements with the given tag
+// name.
+func (d *HTMLDocument) GetElementsByTagName(tagName string) []*HTMLNode {
+	d.linkRoot()
+	return d.Root.GetElementsByTagName(tagName)
+}
+
+// GetElementsByClassName returns the document's elements that have all of
+// the given class names.
+func (d *HTMLDocument) GetElementsByClassName(names string) []*HTMLNode {
+	d.linkRoot()
+	return d.Root.GetElementsByClassName(names)
+}
+
+// QuerySelector returns the first element in the document that matches the
+// CSS selector, or nil.
+func (d *HTMLDocument) QuerySelector(selector string) (*HTMLNode, error) {
+	d.linkRoot()
+	return d.Root.QuerySelector(selector)
+}
+
+// QuerySelectorAll returns the document's elements that match the CSS
+// selector, in document order.
+func (d *HTMLDocument) QuerySelectorAll(selector string) ([]*HTMLNode, error) {
+	d.linkRoot()
+	return d.Root.QuerySelectorAll(selector)
+}
+
+// Walk visits every node of the document; see HTMLNode.Walk.
+func (d *HTMLDocument) Walk(enter func(node *HTMLNode) WalkAction, exit func(node *HTMLNode)) {
+	d.linkRoot()
+	d.Root.Walk(enter, exit)
+}
@@ -0,0 +1,162 @@
+package marquee
+
+import (
+	"strings"
+	"testing"
+)
+
+const queryDocument = `<div id="main" class="page wide">
+<h1 id="title" lang="en-GB">Title</h1>
+<ul id="toc" class="toc">
+<li id="l1" class="item"><a id="a1" href="#one">One</a></li>
+<li id="l2" class="item current"><a id="a2" href="https://example.com/two.html">Two</a></li>
+<li id="l3" class="item"><a id="a3" href="#three" data-help="export-dialog">Three</a></li>
+<li id="l4"><ul id="sub"><li id="l5">Five</li></ul></li>
+</ul>
+<p id="p1">Text <a id="a4" href="#one">again</a></p>
+</div>`
+
+// ids returns the id attributes of nodes.
+func ids(nodes []*HTMLNode) []string {
+	var list []string
+	for _, node := range nodes {
+		list = append(list, node.ID())
+	}
+	return list
+}
+
+func TestQuerySelectorAll(t *testing.T) {
+	doc := parse(t, queryDocument)
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"#toc", []string{"toc"}},
+		{"li", []string{"l1", "l2", "l3", "l4", "l5"}},
+		{"LI.item", []string{"l1", "l2", "l3"}},
+		{".item.current", []string{"l2"}},
+		{"div a", []string{"a1", "a2", "a3", "a4"}},
+		{"#toc > li", []string{"l1", "l2", "l3", "l4"}},
+		{"ul li li", []string{"l5"}},
+		{"p > a, h1", []string{"title", "a4"}},
+		{"[data-help]", []string{"a3"}},
+		{`a[href="#one"]`, []string{"a1", "a4"}},
+		{"a[href^=https]", []string{"a2"}},
+		{"a[href$='.html']", []string{"a2"}},
+		{"a[href*=three]", []string{"a3"}},
+		{"[class~=current]", []string{"l2"}},
+		{"[lang|=en]", []string{"title"}},
+		{"a[href^=HTTPS i]", []string{"a2"}},
+		{"li:first-child", []string{"l1", "l5"}},
+		{"li:last-child", []string{"l4", "l5"}},
+		{"#toc > li:nth-child(odd)", []string{"l1", "l3"}},
+		{"#toc > li:nth-child(2n)", []string{"l2", "l4"}},
+		{"#toc > li:nth-child(-n+2)", []string{"l1", "l2"}},
+		{"#toc > li:nth-last-child(1)", []string{"l4"}},
+		{"* > h1", []string{"title"}},
+		{"table", nil},
+	}
+
+	for _, test := range tests {
+		nodes, err := doc.QuerySelectorAll(test.selector)
+		if err != nil {
+			t.Errorf("%s: %v", test.selector, err)
+			continue
+		}
+		if got := ids(nodes); !equalStrings(got, test.want) {
+			t.Errorf("%s matched %v, want %v", test.selector, got, test.want)
+		}
+	}
+
+	if node, _ := doc.QuerySelector("li.item a"); node == nil || node.ID() != "a1" {
+		t.Errorf("QuerySelector(li.item a) = %v, want #a1", node)
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, selector := range []string{"", "a >", "[href", "a:hover", "li:nth-child(x)", "a,,b", "#"} {
+		if _, err := ParseSelector(selector); err == nil {
+			t.Errorf("ParseSelector(%q) succeeded, want an error", selector)
+		}
+	}
+}
+
+func TestSelectorSpecificity(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     Specificity
+	}{
+		{"li", Specificity{0, 0, 1}},
+		{"ul li.item", Specificity{0, 1, 2}},
+		{"#toc > li:first-child a[href]", Specificity{1, 2, 2}},
+		{"*", Specificity{0, 0, 0}},
+	}
+
+	for _, test := range tests {
+		selector := MustParseSelector(test.selector)
+		if got := selector.selectors[0].specificity; got != test.want {
+			t.Errorf("specificity of %s = %v, want %v", test.selector, got, test.want)
+		}
+	}
+	if !(Specificity{0, 9, 9}).Less(Specificity{1, 0, 0}) || (Specificity{0, 1, 0}).Less(Specificity{0, 0, 5}) {
+		t.Error("Less does not order IDs before classes before types")
+	}
+}
+
+func TestElementLookup(t *testing.T) {
+	doc := parse(t, queryDocument)
+
+	if got := doc.GetElementByID("a3"); got == nil || got.TextContent() != "Three" || got.Attributes["data-help"] != "export-dialog" {
+		t.Errorf("GetElementByID(a3) = %v", got)
+	}
+	if got := doc.GetElementByID("missing"); got != nil {
+		t.Errorf("GetElementByID(missing) = %v, want nil", got)
+	}
+	if got := ids(doc.GetElementsByTagName("UL")); !equalStrings(got, []string{"toc", "sub"}) {
+		t.Errorf("GetElementsByTagName(UL) = %v", got)
+	}
+	if got := len(doc.GetElementsByTagName("*")); got != 14 {
+		t.Errorf("GetElementsByTagName(*) found %d elements, want 14", got)
+	}
+	if got := ids(doc.GetElementsByClassName("current item")); !equalStrings(got, []string{"l2"}) {
+		t.Errorf("GetElementsByClassName(current item) = %v", got)
+	}
+	if got := ids(doc.GetElementsByClassName("  ")); got != nil {
+		t.Errorf("GetElementsByClassName with no names = %v", got)
+	}
+	if toc := doc.GetElementByID("toc"); toc.GetElementByID("toc") != nil || len(toc.GetElementsByTagName("a")) != 3 {
+		t.Error("lookup from an element does not search only below it")
+	}
+}
+
+func TestWalk(t *testing.T) {
+	doc := parse(t, "<div><p>a<b>b</b></p><ul><li>c</li></ul></div><p>d</p>")
+
+	var events []string
+	name := func(node *HTMLNode) string {
+		if node.Type == NodeTypeText {
+			return node.Content
+		}
+		return node.Tag
+	}
+	doc.Walk(func(node *HTMLNode) WalkAction {
+		events = append(events, "+"+name(node))
+		switch node.Tag {
+		case "ul":
+			return WalkSkipChildren
+		case "p":
+			if node.Parent == &doc.Root {
+				return WalkStop
+			}
+		}
+		return WalkContinue
+	}, func(node *HTMLNode) {
+		events = append(events, "-"+name(node))
+	})
+
+	want := "+ +div +p +a -a +b +b -b -b -p +ul -ul -div +p"
+	if got := strings.Join(events, " "); got != want {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
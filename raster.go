@@ -0,0 +1,217 @@
+package marquee
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// ImageCanvas is a Canvas that paints into an image.RGBA in software, for
+// screenshots and visual tests on machines without a display. Text is drawn
+// with the fonts of a TTFMeasurer and placed glyph by glyph at the advances
+// the measurer reports, so it lines up with a layout made with the same
+// measurer.
+type ImageCanvas struct {
+	Image *image.RGBA
+
+	measurer *TTFMeasurer
+	faces    map[Font]font.Face
+	clips    []image.Rectangle
+}
+
+// NewImageCanvas returns a canvas that paints into img with the fonts of
+// measurer, or with the font files the widget draws with when measurer is
+// nil.
+func NewImageCanvas(img *image.RGBA, measurer *TTFMeasurer) *ImageCanvas {
+	if measurer == nil {
+		measurer = systemMeasurer()
+	}
+	return &ImageCanvas{
+		Image:    img,
+		measurer: measurer,
+		faces:    make(map[Font]font.Face),
+	}
+}
+
+// target returns the part of the image inside the current clip. Drawing
+// into it leaves the rest of the image alone.
+func (c *ImageCanvas) target() *image.RGBA {
+	if len(c.clips) == 0 {
+		return c.Image
+	}
+	return c.Image.SubImage(c.clips[len(c.clips)-1]).(*image.RGBA)
+}
+
+// face returns the font face for f at its size, creating it on first use.
+func (c *ImageCanvas) face(f Font) font.Face {
+	if face, exists := c.faces[f]; exists {
+		return face
+	}
+
+	face, err := opentype.NewFace(c.measurer.faceFor(f).font, &opentype.FaceOptions{
+		Size:    float64(f.Size),
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+	if err != nil {
+		face = nil
+	}
+	c.faces[f] = face
+	return face
+}
+
+func (c *ImageCanvas) DrawText(text string, x, y float32, f Font, color rl.Color) {
+	face := c.face(f)
+	if face == nil {
+		return
+	}
+
+	dst := c.target()
+	src := image.NewUniform(toNRGBA(color))
+	baseline := y + c.measurer.Metrics(f).Ascent
+
+	for _, r := range text {
+		dot := fixed.Point26_6{X: toFixed(x), Y: toFixed(baseline)}
+		if bounds, mask, maskPoint, _, ok := face.Glyph(dot, r); ok {
+			xdraw.DrawMask(dst, bounds, src, image.Point{}, mask, maskPoint, xdraw.Over)
+		}
+		x += c.measurer.MeasureText(f, string(r))
+	}
+}
+
+func (c *ImageCanvas) FillRect(bounds rl.Rectangle, color rl.Color) {
+	dst := c.target()
+	xdraw.Draw(dst, pixelRect(bounds), image.NewUniform(toNRGBA(color)), image.Point{}, xdraw.Over)
+}
+
+// StrokeRect draws the border inside bounds, as raylib does.
+func (c *ImageCanvas) StrokeRect(bounds rl.Rectangle, width float32, color rl.Color) {
+	c.FillRect(rl.NewRectangle(bounds.X, bounds.Y, bounds.Width, width), color)
+	c.FillRect(rl.NewRectangle(bounds.X, bounds.Y+bounds.Height-width, bounds.Width, width), color)
+	c.FillRect(rl.NewRectangle(bounds.X, bounds.Y+width, width, bounds.Height-2*width), color)
+	c.FillRect(rl.NewRectangle(bounds.X+bounds.Width-width, bounds.Y+width, width, bounds.Height-2*width), color)
+}
+
+// DrawLine draws the line as an antialiased quadrilateral width pixels wide.
+func (c *ImageCanvas) DrawLine(x1, y1, x2, y2, width float32, color rl.Color) {
+	dx, dy := x2-x1, y2-y1
+	length := float32(math.Hypot(float64(dx), float64(dy)))
+	if length == 0 {
+		return
+	}
+	// The offset from the center of the line to each of its edges.
+	nx, ny := -dy/length*width/2, dx/length*width/2
+
+	area := image.Rect(
+		int(math.Floor(float64(min(x1, x2)-width))), int(math.Floor(float64(min(y1, y2)-width))),
+		int(math.Ceil(float64(max(x1, x2)+width))), int(math.Ceil(float64(max(y1, y2)+width))))
+	ox, oy := float32(area.Min.X), float32(area.Min.Y)
+
+	rasterizer := vector.NewRasterizer(area.Dx(), area.Dy())
+	rasterizer.MoveTo(x1+nx-ox, y1+ny-oy)
+	rasterizer.LineTo(x2+nx-ox, y2+ny-oy)
+	rasterizer.LineTo(x2-nx-ox, y2-ny-oy)
+	rasterizer.LineTo(x1-nx-ox, y1-ny-oy)
+	rasterizer.ClosePath()
+
+	mask := image.NewAlpha(image.Rect(0, 0, area.Dx(), area.Dy()))
+	rasterizer.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+
+	xdraw.DrawMask(c.target(), area, image.NewUniform(toNRGBA(color)), image.Point{}, mask, image.Point{}, xdraw.Over)
+}
+
+// DrawImage scales img to fill bounds.
+func (c *ImageCanvas) DrawImage(img image.Image, bounds rl.Rectangle) {
+	xdraw.ApproxBiLinear.Scale(c.target(), pixelRect(bounds), img, img.Bounds(), xdraw.Over, nil)
+}
+
+func (c *ImageCanvas) PushClip(bounds rl.Rectangle) {
+	clip := pixelRect(bounds).Intersect(c.target().Bounds())
+	c.clips = append(c.clips, clip)
+}
+
+func (c *ImageCanvas) PopClip() {
+	if len(c.clips) > 0 {
+		c.clips = c.clips[:len(c.clips)-1]
+	}
+}
+
+// pixelRect rounds a rectangle to whole pixels.
+func pixelRect(r rl.Rectangle) image.Rectangle {
+	return image.Rect(
+		int(math.Round(float64(r.X))), int(math.Round(float64(r.Y))),
+		int(math.Round(float64(r.X+r.Width))), int(math.Round(float64(r.Y+r.Height))))
+}
+
+// toNRGBA reads a raylib color, which is not premultiplied, as such.
+func toNRGBA(c rl.Color) color.NRGBA {
+	return color.NRGBA{R: c.R, G: c.G, B: c.B, A: c.A}
+}
+
+func toFixed(v float32) fixed.Int26_6 {
+	return fixed.Int26_6(math.Round(float64(v) * 64))
+}
+
+// The snapshot is framed like the widget: a page in the theme's background
+// and border colors, and the content inset by the widget's default margin
+// and padding.
+const (
+	snapshotBorder = 1
+	snapshotInset  = 25
+)
+
+// snapshotLayout lays document out for a snapshot width pixels wide and
+// returns the layout, the measurer it was made with and the height of the
+// snapshot. Text is measured, and drawn, with the fonts of r.Measurer if it
+// is a TTFMeasurer, and otherwise with the font files the widget loads, or
+// the Go fonts when those are not installed.
+func (r *HTMLRenderer) snapshotLayout(document HTMLDocument, width int) (*DocumentLayout, *TTFMeasurer, int) {
+	renderer, measurer := r.withTTFMeasurer()
+	layout := renderer.LayoutDocument(document, RenderContext{
+		Width:       float32(width - 2*snapshotInset),
+		RightMargin: snapshotInset,
+	})
+
+	height := int(math.Ceil(float64(layout.Height))) + 2*snapshotInset
+	return layout, measurer, height
+}
+
+// RenderImage lays document out at width pixels and paints it into an image
+// as tall as the content, framed the way the widget shows it.
+func (r *HTMLRenderer) RenderImage(document HTMLDocument, width int) *image.RGBA {
+	layout, measurer, height := r.snapshotLayout(document, width)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := rl.NewRectangle(0, 0, float32(width), float32(height))
+
+	canvas := NewImageCanvas(img, measurer)
+	canvas.FillRect(bounds, r.Theme.Colors.Background)
+	canvas.StrokeRect(bounds, snapshotBorder, r.Theme.Colors.Border)
+	layout.Root.Paint(canvas, snapshotInset, snapshotInset, bounds)
+	return img
+}
+
+// RenderToImage renders document with the default renderer; see
+// HTMLRenderer.RenderImage.
+func RenderToImage(document HTMLDocument, width int) *image.RGBA {
+	return NewHTMLRenderer().RenderImage(document, width)
+}
+
+// RenderToPNG renders document with the default renderer and returns it
+// encoded as a PNG.
+func RenderToPNG(document HTMLDocument, width int) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, RenderToImage(document, width)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
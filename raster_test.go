@@ -0,0 +1,126 @@
+package marquee
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"path/filepath"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+// The documents are drawn with the Go fonts, which are part of the module,
+// so the images are the same on every machine.
+var goldenDocuments = []struct {
+	name string
+	html string
+}{
+	{"text", `<h1>Heading</h1>
+<p>A paragraph with <b>bold</b>, <i>italic</i>, <code>code</code> and a <a href="#x">link</a>.</p>
+<ul><li>First item</li><li>Second item</li></ul>
+<pre>preformatted
+  text</pre>`},
+	{"boxes", `<div style="background: #fff3c4; border: 2px solid #e0a800; padding: 8px">
+<p style="text-align: center; color: #663c00">A centered note in a box.</p></div>
+<table border="1"><tr><th>Name</th><th>Value</th></tr><tr><td>one</td><td>1</td></tr></table>
+<hr>`},
+}
+
+func TestRenderGolden(t *testing.T) {
+	for _, document := range goldenDocuments {
+		for _, scheme := range []ColorScheme{ColorSchemeLight, ColorSchemeDark} {
+			name := document.name + "-light"
+			r := NewHTMLRenderer()
+			if scheme == ColorSchemeDark {
+				name = document.name + "-dark"
+				r.Theme = DarkTheme()
+			}
+			r.ColorScheme = scheme
+			r.Measurer = GoFontMeasurer()
+
+			t.Run(name, func(t *testing.T) {
+				img := r.RenderImage(parse(t, document.html), 400)
+				path := filepath.Join("testdata", name+".png")
+				if *update {
+					if err := WriteGolden(path, img); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				if err := CompareGolden(path, img, 16); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+func TestRenderToPNG(t *testing.T) {
+	data, err := RenderToPNG(parse(t, "<p>Hello</p>"), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() != 200 || bounds.Dy() < 2*snapshotInset+16 {
+		t.Errorf("image is %dx%d, want 200 wide and tall enough for a line", bounds.Dx(), bounds.Dy())
+	}
+	if got, want := color.NRGBAModel.Convert(img.At(snapshotInset, bounds.Dy()-snapshotInset/2)), toNRGBA(LightTheme().Colors.Background); got != want {
+		t.Errorf("background = %v, want %v", got, want)
+	}
+}
+
+func TestSnapshotFonts(t *testing.T) {
+	doc := parse(t, "<p>Hello</p>")
+	if _, measurer, _ := NewHTMLRenderer().snapshotLayout(doc, 200); measurer != systemMeasurer() {
+		t.Error("a snapshot is not drawn with the fonts the widget loads by default")
+	}
+
+	r := NewHTMLRenderer()
+	r.Measurer = GoFontMeasurer()
+	if _, measurer, _ := r.snapshotLayout(doc, 200); measurer != GoFontMeasurer() {
+		t.Error("a snapshot is not drawn with the renderer's own TTFMeasurer")
+	}
+}
+
+func TestCompareImages(t *testing.T) {
+	want := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	got := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	got.Set(2, 3, color.RGBA{R: 10, A: 10})
+	got.Set(7, 8, color.RGBA{G: 200, A: 200})
+
+	if diff := CompareImages(got, want, 10); diff.DifferentPixels != 1 || diff.Bounds != image.Rect(7, 8, 8, 9) || diff.MaxDelta != 200 {
+		t.Errorf("CompareImages = %+v, want one pixel at (7,8) differing by 200", diff)
+	}
+	if diff := CompareImages(got, want, 200); !diff.Match() {
+		t.Errorf("CompareImages with tolerance 200 = %v, want a match", diff)
+	}
+	if diff := CompareImages(got, image.NewRGBA(image.Rect(0, 0, 10, 11)), 255); !diff.SizeMismatch || diff.Match() {
+		t.Errorf("CompareImages of different sizes = %+v", diff)
+	}
+}
+
+func TestImageCanvasClips(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	canvas := NewImageCanvas(img, nil)
+
+	canvas.PushClip(rl.NewRectangle(0, 0, 5, 10))
+	canvas.FillRect(rl.NewRectangle(0, 0, 10, 10), rl.NewColor(255, 0, 0, 255))
+	canvas.PopClip()
+
+	if got := img.RGBAAt(4, 5); got != (color.RGBA{R: 255, A: 255}) {
+		t.Errorf("pixel inside the clip = %v, want red", got)
+	}
+	if got := img.RGBAAt(5, 5); got != (color.RGBA{}) {
+		t.Errorf("pixel outside the clip = %v, want untouched", got)
+	}
+}
@@ -8,43 +8,109 @@ import (
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
+// RenderContext is where a node is laid out: the position and width it
+// has, and the font and color it inherits.
 type RenderContext struct {
 	X, Y, Width float32
-	ParentFont  rl.Font
+	ParentFont  Font
 	ParentColor rl.Color
 	Indent      int
 	LineHeight  float32
-	Widget      *HTMLWidget
+
+	// ParentBackground is the background of the inline element the content
+	// is in, drawn behind its text.
+	ParentBackground rl.Color
+
+	// RightMargin is kept clear at the right of blocks that fill the width.
+	RightMargin float32
+
+	// MarginAbove is the bottom margin of the block just above, which Y
+	// already includes. The top margin of the next block collapses with
+	// it, so the larger of the two separates them.
+	MarginAbove float32
+
+	// Renderer is the renderer doing the layout, which handlers use to
+	// measure text and lay out children. Widget is the widget the layout is
+	// for, or nil when the document is laid out on its own.
+	Renderer *HTMLRenderer
+	Widget   *HTMLWidget
 
 	CurrentX      float32
 	MaxLineHeight float32
+
+	// anchors collects the anchors of the top-level node being laid out.
+	anchors *[]layoutAnchor
 }
 
+// RenderResult is the space a node takes up: the box holding what it draws
+// and where the content after it goes.
 type RenderResult struct {
-	NextY     float32
-	LinkAreas []LinkArea
-	Height    float32
+	Box    LayoutBox
+	NextY  float32
+	Height float32
+
+	// MarginBottom is the margin at the bottom of the node, which NextY
+	// includes and the top margin of the block after it collapses with.
+	MarginBottom float32
 
 	NextX      float32
 	LineHeight float32
 }
 
+// RenderHandler lays out the elements it is registered for. Render places
+// node at the position in ctx and returns its box; it does not draw
+// anything. The widget paints the boxes every frame and only asks for them
+// again when the content, the width or the fonts change.
 type RenderHandler interface {
 	CanRender(node HTMLNode) bool
 	Render(node HTMLNode, ctx RenderContext) RenderResult
 }
 
+// BlockRenderHandler is a RenderHandler for blocks that keep space above
+// and below them, such as paragraphs and headings. Margins returns that
+// space, and the renderer places the block between its margins, using the
+// node's margin-top and margin-bottom instead where its style sets them.
+// Render lays the block out from ctx.Y and returns its bottom edge as
+// NextY, without the margins.
+type BlockRenderHandler interface {
+	RenderHandler
+	Margins(node HTMLNode, ctx RenderContext) VerticalMargins
+}
+
 type HTMLRenderer struct {
+	// Theme gives the fonts text is laid out in, the colors it is drawn in
+	// and the spacing between blocks.
+	Theme Theme
+
+	// Measurer measures text for the layout. When it is nil, text is
+	// measured with the Go fonts, so a renderer made with NewHTMLRenderer
+	// can lay documents out without a window.
+	Measurer TextMeasurer
+
+	// StyleSheet holds style rules for every document the renderer lays
+	// out, applied before those of the document's own <style> elements, so
+	// that a rule in the document wins over one here that is as specific.
+	// It may be nil.
+	StyleSheet *StyleSheet
+
+	// ColorScheme says which @media (prefers-color-scheme) rules apply. It
+	// does not change Theme. ColorSchemeAuto, the zero value, is light,
+	// since there is no host to ask.
+	ColorScheme ColorScheme
+
 	handlers map[string]RenderHandler
 }
 
 func NewHTMLRenderer() *HTMLRenderer {
 	r := &HTMLRenderer{
+		Theme:    LightTheme(),
 		handlers: make(map[string]RenderHandler),
 	}
 
 	r.RegisterHandler("text", &TextRenderHandler{})
-	r.RegisterHandler("span", &SpanRenderHandler{})
+	for tag := range inlineFormattingTags {
+		r.RegisterHandler(tag, &SpanRenderHandler{})
+	}
 	r.RegisterHandler("a", &LinkRenderHandler{})
 	r.RegisterHandler("h1", &HeadingRenderHandler{})
 	r.RegisterHandler("h2", &HeadingRenderHandler{})
@@ -75,25 +141,178 @@ func (r *HTMLRenderer) RegisterHandler(tag string, handler RenderHandler) {
 	r.handlers[tag] = handler
 }
 
+func (r *HTMLRenderer) measurer() TextMeasurer {
+	if r.Measurer == nil {
+		return GoFontMeasurer()
+	}
+	return r.Measurer
+}
+
+// MeasureText returns the width of text set in font.
+func (r *HTMLRenderer) MeasureText(font Font, text string) float32 {
+	return r.measurer().MeasureText(font, text)
+}
+
+// lineHeight returns the distance between lines of text set in font: the
+// height of a line its metrics give times the theme's LineHeightScale, or
+// the theme's LineHeight when it has no scale.
+func (r *HTMLRenderer) lineHeight(font Font) float32 {
+	spacing := r.Theme.Spacing
+	return r.scaledLineHeight(font, spacing.LineHeight, spacing.LineHeightScale)
+}
+
+// scaledLineHeight returns the height of a line of font, as its metrics
+// give it, times scale, or pixels when scale is not positive.
+func (r *HTMLRenderer) scaledLineHeight(font Font, pixels, scale float32) float32 {
+	if scale <= 0 {
+		return pixels
+	}
+	return r.measurer().Metrics(font).LineHeight() * scale
+}
+
+// advance moves ctx below result, the node just laid out at ctx, and keeps
+// the margin the node ends with for the next block to collapse with. A node
+// that takes up no room, such as the whitespace between two blocks, leaves
+// the margin above it as it is.
+func (ctx *RenderContext) advance(result RenderResult) {
+	if result.NextY != ctx.Y || result.MarginBottom != 0 {
+		ctx.MarginAbove = result.MarginBottom
+	}
+	ctx.Y = result.NextY
+}
+
+// collapseMargins returns the space two adjoining vertical margins leave
+// between blocks, as CSS collapses them: the larger when both are positive,
+// the more negative when both are negative, and their sum otherwise.
+func collapseMargins(a, b float32) float32 {
+	switch {
+	case a >= 0 && b >= 0:
+		return max(a, b)
+	case a < 0 && b < 0:
+		return min(a, b)
+	default:
+		return a + b
+	}
+}
+
 func (r *HTMLRenderer) RenderNode(node HTMLNode, ctx RenderContext) RenderResult {
+	if node.Type == NodeTypeElement && node.Style.Hidden {
+		return RenderResult{NextY: ctx.Y, NextX: ctx.CurrentX}
+	}
+
+	if ctx.anchors != nil && node.Type == NodeTypeElement {
+		if name := anchorName(node); name != "" {
+			*ctx.anchors = append(*ctx.anchors, layoutAnchor{name: name, y: ctx.Y})
+		}
+	}
+
 	if handler, exists := r.handlers[node.Tag]; exists && handler.CanRender(node) {
+		if block, ok := handler.(BlockRenderHandler); ok && node.Context != ContextInline {
+			return r.renderBlock(node, ctx, block.Margins(node, ctx), handler.Render)
+		}
+		if isBlockLevel(node) {
+			return r.renderBlock(node, ctx, VerticalMargins{}, handler.Render)
+		}
 		return handler.Render(node, ctx)
 	}
 
+	if node.Type == NodeTypeComment {
+		return RenderResult{NextY: ctx.Y, NextX: ctx.CurrentX}
+	}
+
+	if node.Type == NodeTypeElement {
+		if isMetadataElement(node.Tag) {
+			// head, title, style, script and friends are not content.
+			return RenderResult{NextY: ctx.Y, NextX: ctx.CurrentX}
+		}
+		if isBlockLevel(node) {
+			return r.renderBlock(node, ctx, VerticalMargins{}, r.renderChildren)
+		}
+		return r.renderChildren(node, ctx)
+	}
+
 	return r.handlers["text"].Render(node, ctx)
 }
 
-func (r *HTMLRenderer) RenderDocument(document HTMLDocument, ctx RenderContext) RenderResult {
+// renderBlock lays out a block element with render, between its margins and
+// inside the border and padding its style gives it. margins is the space
+// the block keeps above and below it unless its style says otherwise. The
+// top margin collapses with the margin above the block, and when nothing
+// is drawn around the content, the margins of the first and last blocks in
+// it collapse with those of the block.
+func (r *HTMLRenderer) renderBlock(node HTMLNode, ctx RenderContext, margins VerticalMargins, render func(HTMLNode, RenderContext) RenderResult) RenderResult {
+	style := node.Style
+	available := ctx.Width - ctx.RightMargin
+	margin := style.Margin.resolve(available, insets{top: margins.Top, bottom: margins.Bottom})
+
+	above := collapseMargins(ctx.MarginAbove, margin.top)
+	inner := ctx
+	inner.Y = ctx.Y - ctx.MarginAbove + above
+	if !style.hasBox() {
+		inner.MarginAbove = above
+		result := render(node, inner)
+		below := collapseMargins(result.MarginBottom, margin.bottom)
+		result.NextY += below - result.MarginBottom
+		result.MarginBottom = below
+		result.Height = result.NextY - ctx.Y
+		return result
+	}
+	inner.MarginAbove = 0
+
+	// The content is as wide as the space left inside the margins, border
+	// and padding, unless the style asks for less.
+	border := style.Border.Width.resolve(available, 0)
+	padding := style.Padding.resolve(available, insets{})
+	width := available - margin.left - margin.right - 2*border - padding.left - padding.right
+	width = style.Width.resolve(available, width)
+	if style.MaxWidth.IsSet() {
+		width = min(width, style.MaxWidth.resolve(available, width))
+	}
+	width = max(width, 0)
+
+	inner.X = ctx.X + margin.left + border + padding.left
+	inner.Y += border + padding.top
+	inner.Width = width
+	inner.RightMargin = 0
+	inner.CurrentX = inner.X
+	result := render(node, inner)
+
+	top := ctx.Y - ctx.MarginAbove + above
+	bottom := result.NextY + padding.bottom + border
+	if style.Background.A != 0 || border > 0 {
+		box := LayoutBox{
+			Bounds:      rl.NewRectangle(ctx.X+margin.left, top, padding.left+width+padding.right+2*border, bottom-top),
+			Background:  style.Background,
+			BorderColor: r.borderColor(style),
+			BorderWidth: border,
+		}
+		box.appendChild(result.Box)
+		result.Box = box
+	}
+
+	result.NextY = bottom + margin.bottom
+	result.MarginBottom = margin.bottom
+	result.Height = result.NextY - ctx.Y
+	return result
+}
+
+// renderChildren lays out the children of an element that has no handler of
+// its own, such as html, body or section, one under another.
+func (r *HTMLRenderer) renderChildren(node HTMLNode, ctx RenderContext) RenderResult {
+	startY := ctx.Y
 	result := RenderResult{NextY: ctx.Y}
+	result.Box.Tag = node.Tag
 
-	for _, child := range document.Root.Children {
+	for _, child := range node.Children {
 		childResult := r.RenderNode(child, ctx)
-		ctx.Y = childResult.NextY
+		ctx.advance(childResult)
 		result.NextY = childResult.NextY
-		result.LinkAreas = append(result.LinkAreas, childResult.LinkAreas...)
+		result.Box.appendChild(childResult.Box)
 	}
 
-	result.Height = result.NextY - ctx.Y
+	result.MarginBottom = ctx.MarginAbove
+	result.Height = result.NextY - startY
+	result.Box.Bounds = rl.NewRectangle(ctx.X, startY, ctx.Width, result.Height)
 	return result
 }
 
@@ -105,38 +324,41 @@ func (h *TextRenderHandler) CanRender(node HTMLNode) bool {
 
 func (h *TextRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
 	content := node.Content
-	if content == "" {
+	if strings.TrimFunc(content, isHTMLSpace) == "" {
 		return RenderResult{NextY: ctx.Y}
 	}
 
-	nextY := ctx.Widget.renderText(content, ctx.X, ctx.Y, ctx.Width, ctx.ParentFont, ctx.ParentColor)
+	spacing := ctx.Renderer.Theme.Spacing
+	segments := []inlineSegment{{text: content, font: ctx.ParentFont, color: ctx.ParentColor}}
+	lineHeight := ctx.Renderer.lineHeight(ctx.ParentFont)
+	lines := ctx.Renderer.layoutInlineLines(segments, ctx.X, ctx.Y, ctx.Width-ctx.RightMargin, lineHeight, node.Style.TextAlign)
+
+	// The space after a run of text is a margin, which the next block's
+	// collapses with.
+	nextY := linesBottom(lines, ctx.Y) + spacing.BlockSpacing
 	return RenderResult{
-		NextY:  nextY,
-		Height: nextY - ctx.Y,
+		Box: LayoutBox{
+			Bounds: rl.NewRectangle(ctx.X, ctx.Y, ctx.Width, nextY-ctx.Y),
+			Lines:  lines,
+		},
+		NextY:        nextY,
+		Height:       nextY - ctx.Y,
+		MarginBottom: spacing.BlockSpacing,
 	}
 }
 
+// SpanRenderHandler renders span and the other inline formatting elements,
+// such as b, i, em and strong, using their computed style.
 type SpanRenderHandler struct{}
 
 func (h *SpanRenderHandler) CanRender(node HTMLNode) bool {
-	return node.Tag == "span"
+	return isInlineFormattingTag(node.Tag)
 }
 
 func (h *SpanRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
 
-	font := ctx.ParentFont
-	color := ctx.ParentColor
-
-	if style, exists := node.Attributes["style"]; exists {
-		if strings.Contains(style, "font-weight: bold") {
-			font = ctx.Widget.Fonts.Bold
-			color = rl.DarkBlue
-		}
-		if strings.Contains(style, "font-style: italic") {
-			font = ctx.Widget.Fonts.Italic
-			color = rl.DarkGreen
-		}
-	}
+	font := ctx.Renderer.styledFont(node.Style, ctx.ParentFont)
+	color := ctx.Renderer.styledColor(node.Style, ctx.ParentColor)
 
 	if node.Context == ContextInline {
 
@@ -151,17 +373,27 @@ func (h *SpanRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResul
 			return RenderResult{NextY: ctx.Y, NextX: ctx.CurrentX}
 		}
 
-		text := content.String()
-		fontSize := float32(font.BaseSize)
-		if fontSize == 0 {
-			fontSize = 16
+		segment := inlineSegment{
+			text:        content.String(),
+			font:        font,
+			color:       color,
+			background:  styledBackground(node.Style, ctx.ParentBackground),
+			underline:   node.Style.Underline,
+			lineThrough: node.Style.LineThrough,
 		}
-
-		ctx.Widget.renderTextWithUnicode(text, ctx.CurrentX, ctx.Y, font, color)
-
-		textWidth := ctx.Widget.measureTextWidth(font, text, fontSize)
+		fontSize := font.Size
+		textWidth := ctx.Renderer.MeasureText(font, segment.text)
+		bounds := rl.NewRectangle(ctx.CurrentX, ctx.Y, textWidth, fontSize)
 
 		return RenderResult{
+			Box: LayoutBox{
+				Tag:    node.Tag,
+				Bounds: bounds,
+				Lines: []LineBox{{
+					Bounds:    bounds,
+					Fragments: []TextFragment{segment.fragment(ctx.CurrentX, ctx.Y, textWidth)},
+				}},
+			},
 			NextY:      ctx.Y,
 			NextX:      ctx.CurrentX + textWidth,
 			Height:     fontSize,
@@ -172,21 +404,24 @@ func (h *SpanRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResul
 	return h.renderBlockChildren(node, ctx, font, color)
 }
 
-func (h *SpanRenderHandler) renderBlockChildren(node HTMLNode, ctx RenderContext, font rl.Font, color rl.Color) RenderResult {
+func (h *SpanRenderHandler) renderBlockChildren(node HTMLNode, ctx RenderContext, font Font, color rl.Color) RenderResult {
 	childCtx := ctx
 	childCtx.ParentFont = font
 	childCtx.ParentColor = color
 
 	result := RenderResult{NextY: ctx.Y}
+	result.Box.Tag = node.Tag
 
 	for _, child := range node.Children {
-		childResult := ctx.Widget.renderer.RenderNode(child, childCtx)
-		childCtx.Y = childResult.NextY
+		childResult := ctx.Renderer.RenderNode(child, childCtx)
+		childCtx.advance(childResult)
 		result.NextY = childResult.NextY
-		result.LinkAreas = append(result.LinkAreas, childResult.LinkAreas...)
+		result.Box.appendChild(childResult.Box)
 	}
 
+	result.MarginBottom = childCtx.MarginAbove
 	result.Height = result.NextY - ctx.Y
+	result.Box.Bounds = rl.NewRectangle(ctx.X, ctx.Y, ctx.Width, result.Height)
 	return result
 }
 
@@ -210,51 +445,44 @@ func (h *LinkRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResul
 		return RenderResult{NextY: ctx.Y, NextX: ctx.CurrentX}
 	}
 
-	font := ctx.ParentFont
-	fontSize := float32(font.BaseSize)
-	if fontSize == 0 {
-		fontSize = 16
+	segment := inlineSegment{
+		text:        content.String(),
+		font:        ctx.Renderer.styledFont(node.Style, ctx.ParentFont),
+		color:       ctx.Renderer.styledColor(node.Style, ctx.ParentColor),
+		background:  styledBackground(node.Style, ctx.ParentBackground),
+		href:        href,
+		underline:   node.Style.Underline,
+		lineThrough: node.Style.LineThrough,
 	}
+	font := segment.font
+	textWidth := ctx.Renderer.MeasureText(font, segment.text)
 
-	text := content.String()
-	textSize := ctx.Widget.measureText(font, text, fontSize)
-
-	color := rl.Blue
-
-	bounds := rl.NewRectangle(ctx.CurrentX, ctx.Y, textSize.X, textSize.Y)
-	linkArea := LinkArea{Bounds: bounds, URL: href}
-
+	blockSpacing := ctx.Renderer.Theme.Spacing.BlockSpacing
+	lineHeight := ctx.Renderer.lineHeight(font)
+	x, nextY := ctx.X, ctx.Y+lineHeight+blockSpacing
 	if node.Context == ContextInline {
+		x, nextY = ctx.CurrentX, ctx.Y
+	}
 
-		ctx.Widget.renderTextWithUnicode(text, ctx.CurrentX, ctx.Y, font, color)
-
-		rl.DrawLineEx(
-			rl.NewVector2(ctx.CurrentX, ctx.Y+textSize.Y),
-			rl.NewVector2(ctx.CurrentX+textSize.X, ctx.Y+textSize.Y),
-			1, color)
+	bounds := rl.NewRectangle(x, ctx.Y, textWidth, font.Size)
+	result := RenderResult{
+		Box: LayoutBox{
+			Tag:    node.Tag,
+			Bounds: bounds,
+			Lines:  []LineBox{{Bounds: bounds, Fragments: []TextFragment{segment.fragment(x, ctx.Y, textWidth)}}},
+		},
+		NextY: nextY,
+	}
 
-		return RenderResult{
-			NextY:      ctx.Y,
-			NextX:      ctx.CurrentX + textSize.X,
-			LinkAreas:  []LinkArea{linkArea},
-			Height:     textSize.Y,
-			LineHeight: textSize.Y,
-		}
+	if node.Context == ContextInline {
+		result.NextX = x + textWidth
+		result.Height = font.Size
+		result.LineHeight = font.Size
 	} else {
-
-		ctx.Widget.renderTextWithUnicode(text, ctx.X, ctx.Y, font, color)
-
-		rl.DrawLineEx(
-			rl.NewVector2(ctx.X, ctx.Y+textSize.Y),
-			rl.NewVector2(ctx.X+textSize.X, ctx.Y+textSize.Y),
-			1, color)
-
-		return RenderResult{
-			NextY:     ctx.Y + textSize.Y + 5,
-			LinkAreas: []LinkArea{linkArea},
-			Height:    textSize.Y + 5,
-		}
+		result.Height = lineHeight + blockSpacing
+		result.MarginBottom = blockSpacing
 	}
+	return result
 }
 
 type HeadingRenderHandler struct{}
@@ -263,70 +491,132 @@ func (h *HeadingRenderHandler) CanRender(node HTMLNode) bool {
 	return strings.HasPrefix(node.Tag, "h") && len(node.Tag) == 2
 }
 
+func (h *HeadingRenderHandler) Margins(node HTMLNode, ctx RenderContext) VerticalMargins {
+	level, _ := strconv.Atoi(node.Tag[1:])
+	return ctx.Renderer.Theme.Spacing.Headings[level-1]
+}
+
+// headingScales are the sizes of h1 to h6 relative to the text around them,
+// for themes whose fonts do not give them a size.
+var headingScales = [6]float32{2, 1.75, 1.5, 1.25, 1.125, 1}
+
 func (h *HeadingRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
 	level, _ := strconv.Atoi(node.Tag[1:])
 
-	var font rl.Font
+	fonts := ctx.Renderer.Theme.Fonts
+	var font Font
 	switch level {
 	case 1:
-		font = ctx.Widget.Fonts.H1
+		font = fonts.H1
 	case 2:
-		font = ctx.Widget.Fonts.H2
+		font = fonts.H2
 	case 3:
-		font = ctx.Widget.Fonts.H3
+		font = fonts.H3
 	case 4:
-		font = ctx.Widget.Fonts.H4
+		font = fonts.H4
 	case 5:
-		font = ctx.Widget.Fonts.H5
+		font = fonts.H5
 	case 6:
-		font = ctx.Widget.Fonts.H6
+		font = fonts.H6
 	default:
-		font = ctx.Widget.Fonts.Regular
+		font = fonts.Regular
 	}
 
-	spacingBefore := float32([]int{25, 20, 18, 15, 12, 10}[level-1])
-	spacingAfter := float32([]int{15, 12, 10, 8, 6, 5}[level-1])
-
-	y := ctx.Y + spacingBefore
-
-	var content strings.Builder
-	for _, child := range node.Children {
-		if child.Type == NodeTypeText {
-			content.WriteString(child.Content)
-		}
+	if font.Size == 0 {
+		font.Size = ctx.ParentFont.Size * headingScales[level-1]
 	}
+	font = node.Style.ownFont(font)
 
-	fontSize := float32(font.BaseSize)
-	if fontSize == 0 {
-		fontSize = float32([]int{32, 28, 24, 20, 18, 16}[level-1])
+	color := ctx.Renderer.Theme.Colors.Heading
+	if node.Style.HasColor() {
+		color = node.Style.Color
 	}
 
-	ctx.Widget.renderTextWithUnicode(content.String(), ctx.X, y, font, rl.DarkBlue)
+	// A heading too long for the width wraps. One without text still
+	// takes up a line.
+	ctx.ParentFont, ctx.ParentColor = font, color
+	segments := h.inlineSegments(node, ctx, "", nil)
+	lineHeight := ctx.Renderer.lineHeight(font)
+	lines := ctx.Renderer.layoutInlineLines(segments, ctx.X, ctx.Y, ctx.Width-ctx.RightMargin, lineHeight, node.Style.TextAlign)
 
+	nextY := linesBottom(lines, ctx.Y)
+	if len(lines) == 0 {
+		nextY += lineHeight
+	}
 	return RenderResult{
-		NextY:  y + fontSize + spacingAfter,
-		Height: fontSize + spacingBefore + spacingAfter,
+		Box: LayoutBox{
+			Tag:    node.Tag,
+			Bounds: rl.NewRectangle(ctx.X, ctx.Y, ctx.Width, nextY-ctx.Y),
+			Lines:  lines,
+		},
+		NextY:  nextY,
+		Height: nextY - ctx.Y,
 	}
 }
 
+// inlineSegments appends the text of the inline content of node to
+// segments, styled as its elements' computed styles say. The text stays at
+// the size of the heading unless an element sets a font size of its own.
+func (h *HeadingRenderHandler) inlineSegments(node HTMLNode, ctx RenderContext, href string, segments []inlineSegment) []inlineSegment {
+	for _, child := range node.Children {
+		switch {
+		case child.Type == NodeTypeText:
+			segments = append(segments, inlineSegment{
+				text:        child.Content,
+				font:        ctx.ParentFont,
+				color:       ctx.ParentColor,
+				background:  ctx.ParentBackground,
+				href:        href,
+				underline:   node.Style.Underline,
+				lineThrough: node.Style.LineThrough,
+			})
+		case child.Tag == "br":
+			segments = append(segments, inlineSegment{lineBreak: true})
+		case child.Type == NodeTypeElement && !isMetadataElement(child.Tag) && !child.Style.Hidden:
+			childCtx := ctx
+			childCtx.ParentFont = ctx.Renderer.styledFont(child.Style, ctx.ParentFont)
+			if !child.Style.ownFontSize {
+				childCtx.ParentFont.Size = ctx.ParentFont.Size
+			}
+			childCtx.ParentColor = ctx.Renderer.styledColor(child.Style, ctx.ParentColor)
+			childCtx.ParentBackground = styledBackground(child.Style, ctx.ParentBackground)
+
+			childHref := href
+			if child.Tag == "a" && child.Attributes["href"] != "" {
+				childHref = child.Attributes["href"]
+			}
+			segments = h.inlineSegments(child, childCtx, childHref, segments)
+		}
+	}
+	return segments
+}
+
 type ParagraphRenderHandler struct{}
 
 func (h *ParagraphRenderHandler) CanRender(node HTMLNode) bool {
 	return node.Tag == "p"
 }
 
+func (h *ParagraphRenderHandler) Margins(node HTMLNode, ctx RenderContext) VerticalMargins {
+	return VerticalMargins{Bottom: ctx.Renderer.Theme.Spacing.BlockSpacing}
+}
+
 func (h *ParagraphRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
 
-	if ctx.ParentFont.BaseSize == 0 {
-		ctx.ParentFont = ctx.Widget.Fonts.Regular
+	if ctx.ParentFont.Size == 0 {
+		ctx.ParentFont = ctx.Renderer.Theme.Fonts.Regular
 	}
 	if ctx.ParentColor.R == 0 && ctx.ParentColor.G == 0 && ctx.ParentColor.B == 0 && ctx.ParentColor.A == 0 {
-		ctx.ParentColor = rl.Black
+		ctx.ParentColor = ctx.Renderer.Theme.Colors.Text
 	}
+	ctx.ParentFont = ctx.Renderer.styledFont(node.Style, ctx.ParentFont)
+	ctx.ParentColor = ctx.Renderer.styledColor(node.Style, ctx.ParentColor)
 
 	segments := h.buildInlineSegments(node, ctx)
 
-	return h.renderSegmentsWithWrapping(segments, ctx)
+	result := h.renderSegmentsWithWrapping(segments, ctx, node.Style.TextAlign)
+	result.Box.Tag = node.Tag
+	return result
 }
 
 func (h *ParagraphRenderHandler) buildInlineSegments(node HTMLNode, ctx RenderContext) []inlineSegment {
@@ -335,9 +625,12 @@ func (h *ParagraphRenderHandler) buildInlineSegments(node HTMLNode, ctx RenderCo
 	for _, child := range node.Children {
 		if child.Type == NodeTypeText {
 			segments = append(segments, inlineSegment{
-				text:  child.Content,
-				font:  ctx.ParentFont,
-				color: ctx.ParentColor,
+				text:        child.Content,
+				font:        ctx.ParentFont,
+				color:       ctx.ParentColor,
+				background:  ctx.ParentBackground,
+				underline:   node.Style.Underline,
+				lineThrough: node.Style.LineThrough,
 			})
 		} else if child.Type == NodeTypeElement && child.Context == ContextInline {
 			childSegments := h.getSegmentsFromElement(child, ctx)
@@ -349,24 +642,16 @@ func (h *ParagraphRenderHandler) buildInlineSegments(node HTMLNode, ctx RenderCo
 }
 
 func (h *ParagraphRenderHandler) getSegmentsFromElement(node HTMLNode, ctx RenderContext) []inlineSegment {
-	font := ctx.ParentFont
-	color := ctx.ParentColor
-
-	if node.Tag == "span" {
-		if style, exists := node.Attributes["style"]; exists {
-			if strings.Contains(style, "font-weight: bold") {
-				font = ctx.Widget.Fonts.Bold
-				color = rl.DarkBlue
-			}
-			if strings.Contains(style, "font-style: italic") {
-				font = ctx.Widget.Fonts.Italic
-				color = rl.DarkGreen
-			}
-		}
-	} else if node.Tag == "a" {
-
-		color = rl.Blue
+	if node.Tag == "br" {
+		return []inlineSegment{{lineBreak: true}}
 	}
+	if isMetadataElement(node.Tag) || node.Style.Hidden {
+		return nil
+	}
+
+	font := ctx.Renderer.styledFont(node.Style, ctx.ParentFont)
+	color := ctx.Renderer.styledColor(node.Style, ctx.ParentColor)
+	background := styledBackground(node.Style, ctx.ParentBackground)
 
 	var segments []inlineSegment
 	href, _ := node.Attributes["href"]
@@ -374,16 +659,20 @@ func (h *ParagraphRenderHandler) getSegmentsFromElement(node HTMLNode, ctx Rende
 	for _, child := range node.Children {
 		if child.Type == NodeTypeText {
 			segments = append(segments, inlineSegment{
-				text:  child.Content,
-				font:  font,
-				color: color,
-				href:  href,
+				text:        child.Content,
+				font:        font,
+				color:       color,
+				background:  background,
+				href:        href,
+				underline:   node.Style.Underline,
+				lineThrough: node.Style.LineThrough,
 			})
 		} else if child.Type == NodeTypeElement && child.Context == ContextInline {
 
 			nestedCtx := ctx
 			nestedCtx.ParentFont = font
 			nestedCtx.ParentColor = color
+			nestedCtx.ParentBackground = background
 			nestedSegments := h.getSegmentsFromElement(child, nestedCtx)
 			segments = append(segments, nestedSegments...)
 		}
@@ -392,144 +681,23 @@ func (h *ParagraphRenderHandler) getSegmentsFromElement(node HTMLNode, ctx Rende
 	return segments
 }
 
-func (h *ParagraphRenderHandler) renderSegmentsWithWrapping(segments []inlineSegment, ctx RenderContext) RenderResult {
-	result := RenderResult{NextY: ctx.Y}
-
-	currentY := ctx.Y
-	lineHeight := float32(20)
-	currentLineSegments := []inlineSegment{}
-	currentLineWidth := float32(0)
-
-	rightMargin := ctx.Widget.BodyMargin + ctx.Widget.BodyPadding
-
-	for _, segment := range segments {
-
-		words := h.intelligentWordSplit(segment.text)
-
-		for _, word := range words {
-			wordSegment := inlineSegment{
-				text:  word,
-				font:  segment.font,
-				color: segment.color,
-				href:  segment.href,
-			}
-
-			wordWidth := ctx.Widget.measureTextWidth(segment.font, word, float32(segment.font.BaseSize))
-
-			availableWidth := ctx.Width - rightMargin
-			if currentLineWidth+wordWidth > availableWidth && len(currentLineSegments) > 0 {
-
-				h.renderInlineSegments(currentLineSegments, ctx.X, currentY, ctx.Widget, &result)
-
-				currentY += lineHeight
-				currentLineSegments = []inlineSegment{wordSegment}
-				currentLineWidth = wordWidth
-			} else {
-
-				if len(currentLineSegments) > 0 {
-					spaceSegment := inlineSegment{
-						text:  " ",
-						font:  segment.font,
-						color: segment.color,
-					}
-					currentLineSegments = append(currentLineSegments, spaceSegment)
-					currentLineWidth += ctx.Widget.measureTextWidth(segment.font, " ", float32(segment.font.BaseSize))
-				}
-
-				currentLineSegments = append(currentLineSegments, wordSegment)
-				currentLineWidth += wordWidth
-			}
-		}
-	}
-
-	if len(currentLineSegments) > 0 {
-		h.renderInlineSegments(currentLineSegments, ctx.X, currentY, ctx.Widget, &result)
-		currentY += lineHeight
-	}
-
-	result.NextY = currentY + 5
-	result.Height = result.NextY - ctx.Y
-	return result
-}
-
-func (h *ParagraphRenderHandler) intelligentWordSplit(text string) []string {
-	words := strings.Fields(text)
-	var result []string
-
-	for _, word := range words {
-
-		if len(word) > 40 && (strings.Contains(word, "://") || strings.Contains(word, ".com") || strings.Contains(word, ".org") || strings.Contains(word, "/")) {
-
-			breakPoints := []string{"/", "?", "&", "=", ".", "-"}
-			segments := h.splitAtBreakPoints(word, breakPoints)
-			result = append(result, segments...)
-		} else if len(word) > 30 {
-
-			for i := 0; i < len(word); i += 25 {
-				end := i + 25
-				if end > len(word) {
-					end = len(word)
-				}
-				result = append(result, word[i:end])
-			}
-		} else {
-			result = append(result, word)
-		}
-	}
-
-	return result
-}
-
-func (h *ParagraphRenderHandler) splitAtBreakPoints(text string, breakPoints []string) []string {
-	segments := []string{text}
-
-	for _, breakPoint := range breakPoints {
-		var newSegments []string
-		for _, segment := range segments {
-			if len(segment) > 30 && strings.Contains(segment, breakPoint) {
-				parts := strings.Split(segment, breakPoint)
-				for i, part := range parts {
-					if i > 0 {
-						newSegments = append(newSegments, breakPoint+part)
-					} else {
-						newSegments = append(newSegments, part)
-					}
-				}
-			} else {
-				newSegments = append(newSegments, segment)
-			}
-		}
-		segments = newSegments
-	}
-
-	return segments
-}
-
-func (h *ParagraphRenderHandler) renderInlineSegments(segments []inlineSegment, x, y float32, widget *HTMLWidget, result *RenderResult) {
-	currentX := x
-
-	for _, segment := range segments {
-		widget.renderTextWithUnicode(segment.text, currentX, y, segment.font, segment.color)
+// renderSegmentsWithWrapping lays segments out in wrapped lines at ctx.X and
+// ctx.Y, placed as align says, and returns a box holding them.
+func (h *ParagraphRenderHandler) renderSegmentsWithWrapping(segments []inlineSegment, ctx RenderContext, align TextAlign) RenderResult {
+	lineHeight := ctx.Renderer.lineHeight(ctx.ParentFont)
 
-		segmentWidth := widget.measureTextWidth(segment.font, segment.text, float32(segment.font.BaseSize))
-
-		if segment.href != "" {
-			fontSize := float32(segment.font.BaseSize)
-			if fontSize == 0 {
-				fontSize = 16
-			}
+	availableWidth := ctx.Width - ctx.RightMargin
 
-			bounds := rl.NewRectangle(currentX, y, segmentWidth, fontSize)
-			linkArea := LinkArea{Bounds: bounds, URL: segment.href}
-			result.LinkAreas = append(result.LinkAreas, linkArea)
+	lines := ctx.Renderer.layoutInlineLines(segments, ctx.X, ctx.Y, availableWidth, lineHeight, align)
 
-			rl.DrawLineEx(
-				rl.NewVector2(currentX, y+fontSize),
-				rl.NewVector2(currentX+segmentWidth, y+fontSize),
-				1, segment.color)
-		}
-
-		currentX += segmentWidth
+	nextY := linesBottom(lines, ctx.Y)
+	return RenderResult{
+		Box: LayoutBox{
+			Bounds: rl.NewRectangle(ctx.X, ctx.Y, ctx.Width, nextY-ctx.Y),
+			Lines:  lines,
+		},
+		NextY:  nextY,
+		Height: nextY - ctx.Y,
 	}
 }
 
@@ -539,69 +707,105 @@ func (h *ListRenderHandler) CanRender(node HTMLNode) bool {
 	return node.Tag == "ul" || node.Tag == "ol" || node.Tag == "li"
 }
 
+// Margins leaves space above a list and below each of its items.
+func (h *ListRenderHandler) Margins(node HTMLNode, ctx RenderContext) VerticalMargins {
+	spacing := ctx.Renderer.Theme.Spacing
+	if node.Tag == "li" {
+		return VerticalMargins{Bottom: spacing.BlockSpacing}
+	}
+	return VerticalMargins{Top: spacing.ListMargin}
+}
+
 func (h *ListRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
 	if node.Tag == "li" {
 
 		return h.renderListItem(node, ctx, "ul", 0)
 	}
 
-	result := RenderResult{NextY: ctx.Y + 10}
+	spacing := ctx.Renderer.Theme.Spacing
+	result := RenderResult{NextY: ctx.Y}
+	result.Box.Tag = node.Tag
 
-	currentY := result.NextY
-	for i, child := range node.Children {
-		if child.Tag == "li" {
+	// Items are laid out one under another, their margins collapsing as
+	// those of other blocks do.
+	itemCtx := ctx
+	index := 0
+	for _, child := range node.Children {
+		if child.Tag == "li" && !child.Style.Hidden {
 
-			childCtx := ctx
-			baseIndent := float32(25)
-			nestedIndent := float32(ctx.Indent * 20)
+			childCtx := itemCtx
+			baseIndent := spacing.ListIndent
+			nestedIndent := float32(ctx.Indent) * spacing.NestedListIndent
 			childCtx.X = ctx.X + baseIndent + nestedIndent
-			childCtx.Y = currentY
-			childCtx.Width = ctx.Width - baseIndent - nestedIndent - ctx.Widget.BodyMargin
+			childCtx.Width = ctx.Width - baseIndent - nestedIndent - ctx.RightMargin
 			childCtx.Indent = ctx.Indent + 1
 			childCtx.ParentFont = ctx.ParentFont
 			childCtx.ParentColor = ctx.ParentColor
 
-			listItemResult := h.renderListItem(child, childCtx, node.Tag, i)
-			currentY = listItemResult.NextY
+			listType, itemIndex := node.Tag, index
+			renderItem := func(item HTMLNode, ctx RenderContext) RenderResult {
+				return h.renderListItem(item, ctx, listType, itemIndex)
+			}
+			listItemResult := ctx.Renderer.renderBlock(child, childCtx, h.Margins(child, childCtx), renderItem)
+			index++
+			itemCtx.advance(listItemResult)
 			result.NextY = listItemResult.NextY
-			result.LinkAreas = append(result.LinkAreas, listItemResult.LinkAreas...)
+			result.Box.appendChild(listItemResult.Box)
 		}
 	}
 
+	result.MarginBottom = itemCtx.MarginAbove
 	result.Height = result.NextY - ctx.Y
+	result.Box.Bounds = rl.NewRectangle(ctx.X, ctx.Y, ctx.Width, result.Height)
 	return result
 }
 
 func (h *ListRenderHandler) renderListItem(node HTMLNode, ctx RenderContext, listType string, index int) RenderResult {
-
-	bulletFont := ctx.Widget.Fonts.Regular
-	if listType == "ol" {
-		marker := fmt.Sprintf("%d.", index+1)
-		rl.DrawTextEx(bulletFont, marker, rl.NewVector2(ctx.X-20, ctx.Y), 16, 1, rl.Black)
-	} else {
-
-		bulletRune := rune(0x2022)
-		bulletStr := string(bulletRune)
-		rl.DrawTextEx(bulletFont, bulletStr, rl.NewVector2(ctx.X-15, ctx.Y), 18, 1, rl.Black)
-	}
-
 	contentCtx := ctx
 	contentCtx.CurrentX = ctx.X
 
-	if contentCtx.ParentFont.BaseSize == 0 {
-		contentCtx.ParentFont = ctx.Widget.Fonts.Regular
+	if contentCtx.ParentFont.Size == 0 {
+		contentCtx.ParentFont = ctx.Renderer.Theme.Fonts.Regular
 	}
 	if contentCtx.ParentColor.R == 0 && contentCtx.ParentColor.G == 0 && contentCtx.ParentColor.B == 0 && contentCtx.ParentColor.A == 0 {
-		contentCtx.ParentColor = rl.Black
+		contentCtx.ParentColor = ctx.Renderer.Theme.Colors.Text
+	}
+	contentCtx.ParentFont = ctx.Renderer.styledFont(node.Style, contentCtx.ParentFont)
+	contentCtx.ParentColor = ctx.Renderer.styledColor(node.Style, contentCtx.ParentColor)
+
+	result := h.renderListItemContent(node, contentCtx)
+	result.Box.Tag = node.Tag
+	result.Box.Lines = append([]LineBox{h.marker(node, contentCtx.ParentFont, ctx, listType, index)}, result.Box.Lines...)
+	return result
+}
+
+// bulletScale is the size of a bullet relative to the text of its item,
+// which it would otherwise look too small beside.
+const bulletScale = 1.125
+
+// marker returns the bullet or number of a list item whose text is set in
+// font. It sits in the indent, ending ListMarkerGap short of the text.
+func (h *ListRenderHandler) marker(node HTMLNode, font Font, ctx RenderContext, listType string, index int) LineBox {
+	text := "\u2022"
+	if listType == "ol" {
+		text = fmt.Sprintf("%d.", index+1)
+	} else {
+		font.Size *= bulletScale
+	}
+
+	color := ctx.Renderer.Theme.Colors.ListMarker
+	if node.Style.HasColor() {
+		color = node.Style.Color
 	}
 
-	return h.renderListItemContent(node, contentCtx)
+	x := ctx.X - ctx.Renderer.Theme.Spacing.ListMarkerGap - ctx.Renderer.MeasureText(font, text)
+	return ctx.Renderer.textLine(text, x, ctx.Y, font, color)
 }
 
 func (h *ListRenderHandler) renderListItemContent(node HTMLNode, ctx RenderContext) RenderResult {
 
 	segments := h.buildListItemSegments(node, ctx)
-	return h.renderListItemSegments(segments, ctx)
+	return h.renderListItemSegments(segments, ctx, node.Style.TextAlign)
 }
 
 func (h *ListRenderHandler) buildListItemSegments(node HTMLNode, ctx RenderContext) []inlineSegment {
@@ -610,9 +814,12 @@ func (h *ListRenderHandler) buildListItemSegments(node HTMLNode, ctx RenderConte
 	for _, child := range node.Children {
 		if child.Type == NodeTypeText {
 			segments = append(segments, inlineSegment{
-				text:  child.Content,
-				font:  ctx.ParentFont,
-				color: ctx.ParentColor,
+				text:        child.Content,
+				font:        ctx.ParentFont,
+				color:       ctx.ParentColor,
+				background:  ctx.ParentBackground,
+				underline:   node.Style.Underline,
+				lineThrough: node.Style.LineThrough,
 			})
 		} else if child.Type == NodeTypeElement && child.Context == ContextInline {
 			childSegments := h.getListItemSegmentsFromElement(child, ctx)
@@ -624,24 +831,16 @@ func (h *ListRenderHandler) buildListItemSegments(node HTMLNode, ctx RenderConte
 }
 
 func (h *ListRenderHandler) getListItemSegmentsFromElement(node HTMLNode, ctx RenderContext) []inlineSegment {
-	font := ctx.ParentFont
-	color := ctx.ParentColor
-
-	if node.Tag == "span" {
-		if style, exists := node.Attributes["style"]; exists {
-			if strings.Contains(style, "font-weight: bold") {
-				font = ctx.Widget.Fonts.Bold
-				color = rl.DarkBlue
-			}
-			if strings.Contains(style, "font-style: italic") {
-				font = ctx.Widget.Fonts.Italic
-				color = rl.DarkGreen
-			}
-		}
-	} else if node.Tag == "a" {
-
-		color = rl.Blue
+	if node.Tag == "br" {
+		return []inlineSegment{{lineBreak: true}}
 	}
+	if isMetadataElement(node.Tag) || node.Style.Hidden {
+		return nil
+	}
+
+	font := ctx.Renderer.styledFont(node.Style, ctx.ParentFont)
+	color := ctx.Renderer.styledColor(node.Style, ctx.ParentColor)
+	background := styledBackground(node.Style, ctx.ParentBackground)
 
 	var segments []inlineSegment
 	href, _ := node.Attributes["href"]
@@ -649,16 +848,20 @@ func (h *ListRenderHandler) getListItemSegmentsFromElement(node HTMLNode, ctx Re
 	for _, child := range node.Children {
 		if child.Type == NodeTypeText {
 			segments = append(segments, inlineSegment{
-				text:  child.Content,
-				font:  font,
-				color: color,
-				href:  href,
+				text:        child.Content,
+				font:        font,
+				color:       color,
+				background:  background,
+				href:        href,
+				underline:   node.Style.Underline,
+				lineThrough: node.Style.LineThrough,
 			})
 		} else if child.Type == NodeTypeElement && child.Context == ContextInline {
 
 			nestedCtx := ctx
 			nestedCtx.ParentFont = font
 			nestedCtx.ParentColor = color
+			nestedCtx.ParentBackground = background
 			nestedSegments := h.getListItemSegmentsFromElement(child, nestedCtx)
 			segments = append(segments, nestedSegments...)
 		}
@@ -667,181 +870,95 @@ func (h *ListRenderHandler) getListItemSegmentsFromElement(node HTMLNode, ctx Re
 	return segments
 }
 
-func (h *ListRenderHandler) renderListItemSegments(segments []inlineSegment, ctx RenderContext) RenderResult {
-	result := RenderResult{NextY: ctx.Y}
-
-	currentY := ctx.Y
-	lineHeight := float32(20)
-	currentLineSegments := []inlineSegment{}
-	currentLineWidth := float32(0)
-
-	rightMargin := ctx.Widget.BodyMargin + ctx.Widget.BodyPadding
-
-	for _, segment := range segments {
-
-		words := h.intelligentWordSplit(segment.text)
-
-		for _, word := range words {
-			wordSegment := inlineSegment{
-				text:  word,
-				font:  segment.font,
-				color: segment.color,
-				href:  segment.href,
-			}
-
-			wordWidth := ctx.Widget.measureTextWidth(segment.font, word, float32(segment.font.BaseSize))
+func (h *ListRenderHandler) renderListItemSegments(segments []inlineSegment, ctx RenderContext, align TextAlign) RenderResult {
+	ph := &ParagraphRenderHandler{}
+	return ph.renderSegmentsWithWrapping(segments, ctx, align)
+}
 
-			availableWidth := ctx.Width - rightMargin
-			if currentLineWidth+wordWidth > availableWidth && len(currentLineSegments) > 0 {
+type HRRenderHandler struct{}
 
-				h.renderListItemInlineSegments(currentLineSegments, ctx.X, currentY, ctx.Widget, &result)
+func (h *HRRenderHandler) CanRender(node HTMLNode) bool {
+	return node.Tag == "hr"
+}
 
-				currentY += lineHeight
-				currentLineSegments = []inlineSegment{wordSegment}
-				currentLineWidth = wordWidth
-			} else {
+func (h *HRRenderHandler) Margins(node HTMLNode, ctx RenderContext) VerticalMargins {
+	return ctx.Renderer.Theme.Spacing.RuleMargin
+}
 
-				if len(currentLineSegments) > 0 {
-					spaceSegment := inlineSegment{
-						text:  " ",
-						font:  segment.font,
-						color: segment.color,
-					}
-					currentLineSegments = append(currentLineSegments, spaceSegment)
-					currentLineWidth += ctx.Widget.measureTextWidth(segment.font, " ", float32(segment.font.BaseSize))
-				}
+func (h *HRRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
+	lineWidth := ctx.Width - ctx.RightMargin
 
-				currentLineSegments = append(currentLineSegments, wordSegment)
-				currentLineWidth += wordWidth
-			}
-		}
+	color := ctx.Renderer.Theme.Colors.Rule
+	if node.Style.HasColor() {
+		color = node.Style.Color
 	}
 
-	if len(currentLineSegments) > 0 {
-		h.renderListItemInlineSegments(currentLineSegments, ctx.X, currentY, ctx.Widget, &result)
-		currentY += lineHeight
+	// The rule is a 2 pixel line centered on the top of the block.
+	return RenderResult{
+		Box: LayoutBox{
+			Tag:        node.Tag,
+			Bounds:     rl.NewRectangle(ctx.X, ctx.Y-1, lineWidth, 2),
+			Background: color,
+		},
+		NextY: ctx.Y,
 	}
-
-	result.NextY = currentY + 5
-	result.Height = result.NextY - ctx.Y
-	return result
 }
 
-func (h *ListRenderHandler) intelligentWordSplit(text string) []string {
-	words := strings.Fields(text)
-	var result []string
-
-	for _, word := range words {
-
-		if len(word) > 40 && (strings.Contains(word, "://") || strings.Contains(word, ".com") || strings.Contains(word, ".org") || strings.Contains(word, "/")) {
+type BreakRenderHandler struct{}
 
-			breakPoints := []string{"/", "?", "&", "=", ".", "-"}
-			segments := h.splitAtBreakPoints(word, breakPoints)
-			result = append(result, segments...)
-		} else if len(word) > 30 {
+func (h *BreakRenderHandler) CanRender(node HTMLNode) bool {
+	return node.Tag == "br"
+}
 
-			for i := 0; i < len(word); i += 25 {
-				end := i + 25
-				if end > len(word) {
-					end = len(word)
-				}
-				result = append(result, word[i:end])
-			}
-		} else {
-			result = append(result, word)
-		}
+func (h *BreakRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
+	lineHeight := ctx.Renderer.lineHeight(ctx.ParentFont)
+	return RenderResult{
+		NextY:  ctx.Y + lineHeight,
+		Height: lineHeight,
 	}
-
-	return result
 }
 
-func (h *ListRenderHandler) splitAtBreakPoints(text string, breakPoints []string) []string {
-	segments := []string{text}
-
-	for _, breakPoint := range breakPoints {
-		var newSegments []string
-		for _, segment := range segments {
-			if len(segment) > 30 && strings.Contains(segment, breakPoint) {
-				parts := strings.Split(segment, breakPoint)
-				for i, part := range parts {
-					if i > 0 {
-						newSegments = append(newSegments, breakPoint+part)
-					} else {
-						newSegments = append(newSegments, part)
-					}
-				}
-			} else {
-				newSegments = append(newSegments, segment)
-			}
-		}
-		segments = newSegments
+// nodeText returns the text of node and all of its descendants, as written.
+func nodeText(node HTMLNode) string {
+	if node.Type == NodeTypeText {
+		return node.Content
 	}
 
-	return segments
+	var text strings.Builder
+	for _, child := range node.Children {
+		text.WriteString(nodeText(child))
+	}
+	return text.String()
 }
 
-func (h *ListRenderHandler) renderListItemInlineSegments(segments []inlineSegment, x, y float32, widget *HTMLWidget, result *RenderResult) {
-	currentX := x
-
-	for _, segment := range segments {
-		widget.renderTextWithUnicode(segment.text, currentX, y, segment.font, segment.color)
+// preformattedLines splits preformatted text into display lines. Tabs are
+// expanded to 8-column stops and a trailing newline does not start an empty
+// line, matching how browsers show pre blocks.
+func preformattedLines(content string) []string {
+	content = strings.TrimSuffix(content, "\n")
+	lines := strings.Split(content, "\n")
 
-		segmentWidth := widget.measureTextWidth(segment.font, segment.text, float32(segment.font.BaseSize))
+	for i, line := range lines {
+		if !strings.Contains(line, "\t") {
+			continue
+		}
 
-		if segment.href != "" {
-			fontSize := float32(segment.font.BaseSize)
-			if fontSize == 0 {
-				fontSize = 16
+		var expanded strings.Builder
+		column := 0
+		for _, r := range line {
+			if r == '\t' {
+				spaces := 8 - column%8
+				expanded.WriteString(strings.Repeat(" ", spaces))
+				column += spaces
+				continue
 			}
-
-			bounds := rl.NewRectangle(currentX, y, segmentWidth, fontSize)
-			linkArea := LinkArea{Bounds: bounds, URL: segment.href}
-			result.LinkAreas = append(result.LinkAreas, linkArea)
-
-			rl.DrawLineEx(
-				rl.NewVector2(currentX, y+fontSize),
-				rl.NewVector2(currentX+segmentWidth, y+fontSize),
-				1, segment.color)
+			expanded.WriteRune(r)
+			column++
 		}
-
-		currentX += segmentWidth
-	}
-}
-
-type HRRenderHandler struct{}
-
-func (h *HRRenderHandler) CanRender(node HTMLNode) bool {
-	return node.Tag == "hr"
-}
-
-func (h *HRRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
-	y := ctx.Y + 10
-
-	rightMargin := ctx.Widget.BodyMargin + ctx.Widget.BodyPadding
-	lineWidth := ctx.Width - rightMargin
-
-	rl.DrawLineEx(
-		rl.NewVector2(ctx.X, y),
-		rl.NewVector2(ctx.X+lineWidth, y),
-		2, rl.Gray)
-
-	return RenderResult{
-		NextY:  y + 15,
-		Height: 25,
+		lines[i] = expanded.String()
 	}
-}
 
-type BreakRenderHandler struct{}
-
-func (h *BreakRenderHandler) CanRender(node HTMLNode) bool {
-	return node.Tag == "br"
-}
-
-func (h *BreakRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
-	return RenderResult{
-		NextY:  ctx.Y + 20,
-		Height: 20,
-	}
+	return lines
 }
 
 type PreRenderHandler struct{}
@@ -850,41 +967,70 @@ func (h *PreRenderHandler) CanRender(node HTMLNode) bool {
 	return node.Tag == "pre"
 }
 
+func (h *PreRenderHandler) Margins(node HTMLNode, ctx RenderContext) VerticalMargins {
+	return preformattedMargins(ctx)
+}
+
 func (h *PreRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
 
-	var content strings.Builder
-	for _, child := range node.Children {
-		if child.Type == NodeTypeText {
-			content.WriteString(child.Content)
-		}
+	content := nodeText(node)
+	if content == "" {
+		return RenderResult{NextY: ctx.Y}
 	}
 
-	if content.Len() == 0 {
-		return RenderResult{NextY: ctx.Y}
+	return renderPreformattedBlock(node, content, ctx)
+}
+
+// preformattedMargins is the space kept above and below pre and block-level
+// code.
+func preformattedMargins(ctx RenderContext) VerticalMargins {
+	margin := ctx.Renderer.Theme.Spacing.PreMargin
+	return VerticalMargins{Top: margin, Bottom: margin}
+}
+
+// renderPreformattedBlock lays out preformatted text line by line in a
+// shaded, bordered block, as pre and block-level code are shown. A
+// background or border in the node's style replaces the theme's.
+func renderPreformattedBlock(node HTMLNode, content string, ctx RenderContext) RenderResult {
+	spacing := ctx.Renderer.Theme.Spacing
+	colors := ctx.Renderer.Theme.Colors.Pre
+	if node.Style.HasColor() {
+		colors.Text = node.Style.Color
 	}
 
-	y := ctx.Y + 10
-	lines := strings.Split(content.String(), "\n")
-	lineHeight := float32(18)
-	padding := float32(12)
+	font := node.Style.ownFont(ctx.Renderer.Theme.Fonts.MonospaceLarge)
+	y := ctx.Y
+	lines := preformattedLines(content)
+	lineHeight := ctx.Renderer.scaledLineHeight(font, spacing.PreLineHeight, spacing.PreLineHeightScale)
+	padding := spacing.PrePadding
 	blockHeight := float32(len(lines))*lineHeight + 2*padding
 
-	rightMargin := ctx.Widget.BodyMargin + ctx.Widget.BodyPadding
-	blockWidth := ctx.Width - rightMargin
+	blockWidth := ctx.Width - ctx.RightMargin
 
-	backgroundRect := rl.NewRectangle(ctx.X, y, blockWidth, blockHeight)
-	rl.DrawRectangleRec(backgroundRect, rl.Color{R: 248, G: 248, B: 248, A: 255})
-	rl.DrawRectangleLinesEx(backgroundRect, 1, rl.Color{R: 220, G: 220, B: 220, A: 255})
+	box := LayoutBox{
+		Tag:         node.Tag,
+		Bounds:      rl.NewRectangle(ctx.X, y, blockWidth, blockHeight),
+		Background:  colors.Background,
+		BorderColor: colors.Border,
+		BorderWidth: 1,
+	}
+	if node.Style.Background.A != 0 {
+		box.Background = rl.Color{}
+	}
+	if node.Style.Border.Width.IsSet() {
+		box.BorderWidth = 0
+	}
 
 	currentY := y + padding
 	for _, line := range lines {
-		ctx.Widget.renderTextWithUnicode(line, ctx.X+padding, currentY, ctx.Widget.Fonts.MonospaceLarge, rl.Color{R: 40, G: 40, B: 40, A: 255})
+		box.Lines = append(box.Lines, ctx.Renderer.textLine(line, ctx.X+padding, currentY, font, colors.Text))
 		currentY += lineHeight
 	}
 
 	return RenderResult{
-		NextY:  y + blockHeight + 10,
-		Height: blockHeight + 20,
+		Box:    box,
+		NextY:  y + blockHeight,
+		Height: blockHeight,
 	}
 }
 
@@ -894,6 +1040,11 @@ func (h *CodeRenderHandler) CanRender(node HTMLNode) bool {
 	return node.Tag == "code"
 }
 
+// Margins is only asked for block-level code, which is laid out like pre.
+func (h *CodeRenderHandler) Margins(node HTMLNode, ctx RenderContext) VerticalMargins {
+	return preformattedMargins(ctx)
+}
+
 func (h *CodeRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
 
 	var content strings.Builder
@@ -909,72 +1060,60 @@ func (h *CodeRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResul
 
 	switch node.Context {
 	case ContextBlock:
-		return h.renderCodeBlock(content.String(), ctx)
+		return h.renderCodeBlock(node, content.String(), ctx)
 	default:
-		return h.renderInlineCode(content.String(), ctx)
+		return h.renderInlineCode(node, content.String(), ctx)
 	}
 }
 
-func (h *CodeRenderHandler) renderCodeBlock(content string, ctx RenderContext) RenderResult {
-	y := ctx.Y + 10
-	lines := strings.Split(content, "\n")
-	lineHeight := float32(18)
-	padding := float32(12)
-	blockHeight := float32(len(lines))*lineHeight + 2*padding
-
-	rightMargin := ctx.Widget.BodyMargin + ctx.Widget.BodyPadding
-	blockWidth := ctx.Width - rightMargin
-
-	backgroundRect := rl.NewRectangle(ctx.X, y, blockWidth, blockHeight)
-	rl.DrawRectangleRec(backgroundRect, rl.Color{R: 248, G: 248, B: 248, A: 255})
-	rl.DrawRectangleLinesEx(backgroundRect, 1, rl.Color{R: 220, G: 220, B: 220, A: 255})
-
-	currentY := y + padding
-	for _, line := range lines {
-		ctx.Widget.renderTextWithUnicode(line, ctx.X+padding, currentY, ctx.Widget.Fonts.MonospaceLarge, rl.Color{R: 40, G: 40, B: 40, A: 255})
-		currentY += lineHeight
-	}
-
-	return RenderResult{
-		NextY:  y + blockHeight + 10,
-		Height: blockHeight + 20,
-	}
+func (h *CodeRenderHandler) renderCodeBlock(node HTMLNode, content string, ctx RenderContext) RenderResult {
+	return renderPreformattedBlock(node, content, ctx)
 }
 
-func (h *CodeRenderHandler) renderInlineCode(content string, ctx RenderContext) RenderResult {
-	font := ctx.Widget.Fonts.Monospace
-	fontSize := float32(font.BaseSize)
-	if fontSize == 0 {
-		fontSize = 14
-	}
-
-	textSize := ctx.Widget.measureText(font, content, fontSize)
-	padding := float32(4)
-
+// renderInlineCode renders code set in a run of text, in the monospace
+// font and the theme's colors for code unless its style sets others.
+func (h *CodeRenderHandler) renderInlineCode(node HTMLNode, content string, ctx RenderContext) RenderResult {
+	font := ctx.Renderer.styledFont(node.Style, ctx.ParentFont)
+	textSize := rl.NewVector2(ctx.Renderer.MeasureText(font, content), font.Size)
+	padding := ctx.Renderer.Theme.Spacing.CodePadding
+	colors := ctx.Renderer.Theme.Colors.Code
+	textColor := ctx.Renderer.styledColor(node.Style, colors.Text)
+	background := styledBackground(node.Style, colors.Background)
+
+	// Code that continues a line goes where the line left off; otherwise
+	// it starts a line of its own.
 	renderX := ctx.CurrentX
-	if renderX == 0 {
+	if renderX < ctx.X {
 		renderX = ctx.X
 	}
 
-	backgroundRect := rl.NewRectangle(renderX-padding, ctx.Y-2, textSize.X+2*padding, textSize.Y+4)
-	rl.DrawRectangleRec(backgroundRect, rl.Color{R: 240, G: 240, B: 240, A: 255})
-	rl.DrawRectangleLinesEx(backgroundRect, 1, rl.Color{R: 220, G: 220, B: 220, A: 255})
-
-	ctx.Widget.renderTextWithUnicode(content, renderX, ctx.Y, font, rl.Color{R: 40, G: 40, B: 40, A: 255})
+	box := LayoutBox{
+		Tag:         "code",
+		Bounds:      rl.NewRectangle(renderX-padding, ctx.Y-2, textSize.X+2*padding, textSize.Y+4),
+		Background:  background,
+		BorderColor: colors.Border,
+		BorderWidth: 1,
+		Lines:       []LineBox{ctx.Renderer.textLine(content, renderX, ctx.Y, font, textColor)},
+	}
 
-	if ctx.CurrentX > 0 {
+	blockSpacing := ctx.Renderer.Theme.Spacing.BlockSpacing
+	if ctx.CurrentX > ctx.X {
 
 		return RenderResult{
+			Box:        box,
 			NextY:      ctx.Y,
 			NextX:      renderX + textSize.X + 2*padding,
-			Height:     textSize.Y + 5,
+			Height:     textSize.Y + blockSpacing,
 			LineHeight: textSize.Y,
 		}
 	} else {
 
+		lineHeight := ctx.Renderer.lineHeight(font)
 		return RenderResult{
-			NextY:  ctx.Y + textSize.Y + 5,
-			Height: textSize.Y + 5,
+			Box:          box,
+			NextY:        ctx.Y + lineHeight + blockSpacing,
+			Height:       lineHeight + blockSpacing,
+			MarginBottom: blockSpacing,
 		}
 	}
 }
@@ -0,0 +1,568 @@
+package marquee
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Selector is a parsed CSS selector list such as "ul.toc > li a[href]". The
+// supported subset is:
+//
+//   - type selectors and "*"
+//   - #id and .class
+//   - [attr], [attr=value], [attr~=value], [attr|=value], [attr^=value],
+//     [attr$=value] and [attr*=value], with an optional " i" flag for
+//     case-insensitive values
+//   - :first-child, :last-child and :nth-child(an+b), including odd and
+//     even
+//   - the descendant (space) and child (">") combinators
+//   - lists of selectors separated by commas
+type Selector struct {
+	source    string
+	selectors []complexSelector
+}
+
+// complexSelector is one entry of a selector list: compound selectors joined
+// by combinators, stored left to right.
+type complexSelector struct {
+	compounds   []compoundSelector
+	specificity Specificity
+}
+
+// compoundSelector is a run of simple selectors that all apply to the same
+// element. combinator joins it to the compound before it.
+type compoundSelector struct {
+	combinator byte
+	tag        string
+	id         string
+	classes    []string
+	attributes []attributeSelector
+	pseudo     []pseudoClass
+}
+
+type attributeSelector struct {
+	name       string
+	operator   string
+	value      string
+	ignoreCase bool
+}
+
+// pseudoClass is a structural pseudo-class, matching elements whose 1-based
+// position among their element siblings is a*n+b for some n >= 0. fromEnd
+// counts positions from the last sibling.
+type pseudoClass struct {
+	a, b    int
+	fromEnd bool
+}
+
+// Specificity orders selectors as CSS does: by IDs, then by classes,
+// attributes and pseudo-classes, then by type selectors.
+type Specificity [3]int
+
+// Less reports whether s is less specific than other.
+func (s Specificity) Less(other Specificity) bool {
+	for i := range s {
+		if s[i] != other[i] {
+			return s[i] < other[i]
+		}
+	}
+	return false
+}
+
+// ParseSelector parses a selector list.
+func ParseSelector(selector string) (*Selector, error) {
+	p := selectorParser{input: selector}
+	parsed := &Selector{source: selector}
+
+	for {
+		p.skipSpace()
+		complex, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		parsed.selectors = append(parsed.selectors, complex)
+
+		p.skipSpace()
+		if p.done() {
+			return parsed, nil
+		}
+		if p.peek() != ',' {
+			return nil, p.errorf("unexpected %q", p.peek())
+		}
+		p.position++
+	}
+}
+
+// MustParseSelector is like ParseSelector but panics if the selector is
+// invalid. It is meant for selectors written into the program.
+func MustParseSelector(selector string) *Selector {
+	parsed, err := ParseSelector(selector)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func (s *Selector) String() string {
+	return s.source
+}
+
+// MatchAll returns the elements under root that match the selector, in
+// document order. root itself is not a candidate, but its descendants are
+// matched against the whole subtree, root included.
+func (s *Selector) MatchAll(root *HTMLNode) []*HTMLNode {
+	var matches []*HTMLNode
+	walkElementPaths(root, func(path []elementPosition) bool {
+		if _, ok := s.matchPath(path); ok {
+			matches = append(matches, path[len(path)-1].node)
+		}
+		return true
+	})
+	return matches
+}
+
+// MatchFirst returns the first element under root that matches the
+// selector, or nil.
+func (s *Selector) MatchFirst(root *HTMLNode) *HTMLNode {
+	var match *HTMLNode
+	walkElementPaths(root, func(path []elementPosition) bool {
+		if _, ok := s.matchPath(path); ok {
+			match = path[len(path)-1].node
+			return false
+		}
+		return true
+	})
+	return match
+}
+
+// elementPosition is one element on the path from the root of a match to
+// the candidate element, with its 1-based position among its element
+// siblings.
+type elementPosition struct {
+	node  *HTMLNode
+	index int
+	count int
+}
+
+// walkElementPaths calls visit with the path to every element below root,
+// in document order, until visit returns false. Elements are only ever
+// matched through such a path, since Parent pointers are not kept up to date
+// when the tree is copied.
+func walkElementPaths(root *HTMLNode, visit func(path []elementPosition) bool) {
+	var path []elementPosition
+	if root.Type == NodeTypeElement {
+		path = append(path, elementPosition{node: root, index: 1, count: 1})
+	}
+
+	var walk func(node *HTMLNode) bool
+	walk = func(node *HTMLNode) bool {
+		count := 0
+		for i := range node.Children {
+			if node.Children[i].Type == NodeTypeElement {
+				count++
+			}
+		}
+
+		index := 0
+		for i := range node.Children {
+			child := &node.Children[i]
+			if child.Type != NodeTypeElement {
+				continue
+			}
+			index++
+
+			path = append(path, elementPosition{node: child, index: index, count: count})
+			if !visit(path) || !walk(child) {
+				return false
+			}
+			path = path[:len(path)-1]
+		}
+		return true
+	}
+	walk(root)
+}
+
+// matchPath reports whether the last element of path matches any selector
+// in the list, and the specificity of the most specific one that does.
+func (s *Selector) matchPath(path []elementPosition) (Specificity, bool) {
+	var best Specificity
+	matched := false
+	for _, complex := range s.selectors {
+		if complex.matches(path, len(complex.compounds)-1, len(path)-1) {
+			if !matched || best.Less(complex.specificity) {
+				best = complex.specificity
+			}
+			matched = true
+		}
+	}
+	return best, matched
+}
+
+// matches reports whether compound i matches path[at] and the compounds
+// before it match the elements its combinators point at.
+func (c complexSelector) matches(path []elementPosition, i, at int) bool {
+	compound := c.compounds[i]
+	if !compound.matches(path[at]) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	switch compound.combinator {
+	case '>':
+		return at > 0 && c.matches(path, i-1, at-1)
+	default:
+		for ancestor := at - 1; ancestor >= 0; ancestor-- {
+			if c.matches(path, i-1, ancestor) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (c compoundSelector) matches(position elementPosition) bool {
+	node := position.node
+
+	if c.tag != "" && c.tag != "*" && c.tag != node.Tag {
+		return false
+	}
+	if c.id != "" && node.Attributes["id"] != c.id {
+		return false
+	}
+	for _, class := range c.classes {
+		if !node.HasClass(class) {
+			return false
+		}
+	}
+	for _, attribute := range c.attributes {
+		if !attribute.matches(node) {
+			return false
+		}
+	}
+	for _, pseudo := range c.pseudo {
+		if !pseudo.matches(position) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a attributeSelector) matches(node *HTMLNode) bool {
+	actual, exists := node.Attributes[a.name]
+	if !exists {
+		return false
+	}
+
+	want := a.value
+	if a.ignoreCase {
+		actual = strings.ToLower(actual)
+		want = strings.ToLower(want)
+	}
+
+	switch a.operator {
+	case "":
+		return true
+	case "=":
+		return actual == want
+	case "~=":
+		for _, word := range splitHTMLWords(actual) {
+			if word == want {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return actual == want || strings.HasPrefix(actual, want+"-")
+	case "^=":
+		return want != "" && strings.HasPrefix(actual, want)
+	case "$=":
+		return want != "" && strings.HasSuffix(actual, want)
+	case "*=":
+		return want != "" && strings.Contains(actual, want)
+	}
+	return false
+}
+
+func (p pseudoClass) matches(position elementPosition) bool {
+	index := position.index
+	if p.fromEnd {
+		index = position.count - position.index + 1
+	}
+
+	if p.a == 0 {
+		return index == p.b
+	}
+	n := index - p.b
+	return n%p.a == 0 && n/p.a >= 0
+}
+
+// selectorParser reads a selector list one character at a time.
+type selectorParser struct {
+	input    string
+	position int
+}
+
+func (p *selectorParser) done() bool {
+	return p.position >= len(p.input)
+}
+
+func (p *selectorParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.input[p.position]
+}
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.position
+	for !p.done() && isSelectorSpace(p.peek()) {
+		p.position++
+	}
+	return p.position > start
+}
+
+func (p *selectorParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("marquee: invalid selector %q at offset %d: %s",
+		p.input, p.position, fmt.Sprintf(format, args...))
+}
+
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	var complex complexSelector
+	combinator := byte(0)
+
+	for {
+		compound, err := p.parseCompound()
+		if err != nil {
+			return complex, err
+		}
+		compound.combinator = combinator
+		complex.compounds = append(complex.compounds, compound)
+
+		complex.specificity[2] += boolToInt(compound.tag != "" && compound.tag != "*")
+		complex.specificity[0] += boolToInt(compound.id != "")
+		complex.specificity[1] += len(compound.classes) + len(compound.attributes) + len(compound.pseudo)
+
+		sawSpace := p.skipSpace()
+		switch {
+		case p.done() || p.peek() == ',':
+			return complex, nil
+		case p.peek() == '>':
+			p.position++
+			p.skipSpace()
+			combinator = '>'
+		case sawSpace:
+			combinator = ' '
+		default:
+			return complex, p.errorf("unexpected %q", p.peek())
+		}
+	}
+}
+
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var compound compoundSelector
+	start := p.position
+
+	if p.peek() == '*' {
+		p.position++
+		compound.tag = "*"
+	} else if isSelectorNameChar(p.peek()) {
+		compound.tag = strings.ToLower(p.readName())
+	}
+
+	for !p.done() {
+		switch p.peek() {
+		case '#':
+			p.position++
+			name := p.readName()
+			if name == "" {
+				return compound, p.errorf("expected an id after \"#\"")
+			}
+			compound.id = name
+		case '.':
+			p.position++
+			name := p.readName()
+			if name == "" {
+				return compound, p.errorf("expected a class name after \".\"")
+			}
+			compound.classes = append(compound.classes, name)
+		case '[':
+			attribute, err := p.parseAttribute()
+			if err != nil {
+				return compound, err
+			}
+			compound.attributes = append(compound.attributes, attribute)
+		case ':':
+			pseudo, err := p.parsePseudo()
+			if err != nil {
+				return compound, err
+			}
+			compound.pseudo = append(compound.pseudo, pseudo)
+		default:
+			if p.position == start {
+				return compound, p.errorf("expected a selector")
+			}
+			return compound, nil
+		}
+	}
+
+	if p.position == start {
+		return compound, p.errorf("expected a selector")
+	}
+	return compound, nil
+}
+
+func (p *selectorParser) parseAttribute() (attributeSelector, error) {
+	var attribute attributeSelector
+	p.position++ // "["
+
+	p.skipSpace()
+	attribute.name = strings.ToLower(p.readName())
+	if attribute.name == "" {
+		return attribute, p.errorf("expected an attribute name")
+	}
+	p.skipSpace()
+
+	if p.peek() == ']' {
+		p.position++
+		return attribute, nil
+	}
+
+	for _, operator := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.input[p.position:], operator) {
+			attribute.operator = operator
+			p.position += len(operator)
+			break
+		}
+	}
+	if attribute.operator == "" {
+		return attribute, p.errorf("expected \"]\" or an attribute operator")
+	}
+
+	p.skipSpace()
+	if quote := p.peek(); quote == '"' || quote == '\'' {
+		end := strings.IndexByte(p.input[p.position+1:], quote)
+		if end < 0 {
+			return attribute, p.errorf("unterminated string")
+		}
+		attribute.value = p.input[p.position+1 : p.position+1+end]
+		p.position += end + 2
+	} else {
+		attribute.value = p.readName()
+		if attribute.value == "" {
+			return attribute, p.errorf("expected an attribute value")
+		}
+	}
+
+	p.skipSpace()
+	if p.peek() == 'i' || p.peek() == 'I' {
+		attribute.ignoreCase = true
+		p.position++
+		p.skipSpace()
+	}
+
+	if p.peek() != ']' {
+		return attribute, p.errorf("expected \"]\"")
+	}
+	p.position++
+	return attribute, nil
+}
+
+func (p *selectorParser) parsePseudo() (pseudoClass, error) {
+	p.position++ // ":"
+	name := strings.ToLower(p.readName())
+
+	switch name {
+	case "first-child":
+		return pseudoClass{b: 1}, nil
+	case "last-child":
+		return pseudoClass{b: 1, fromEnd: true}, nil
+	case "nth-child", "nth-last-child":
+		if p.peek() != '(' {
+			return pseudoClass{}, p.errorf("expected \"(\" after :%s", name)
+		}
+		end := strings.IndexByte(p.input[p.position:], ')')
+		if end < 0 {
+			return pseudoClass{}, p.errorf("expected \")\"")
+		}
+		a, b, ok := parseNth(p.input[p.position+1 : p.position+end])
+		if !ok {
+			return pseudoClass{}, p.errorf("invalid :%s argument", name)
+		}
+		p.position += end + 1
+		return pseudoClass{a: a, b: b, fromEnd: name == "nth-last-child"}, nil
+	}
+	return pseudoClass{}, p.errorf("unsupported pseudo-class :%s", name)
+}
+
+// parseNth parses the an+b argument of :nth-child, such as "odd", "3",
+// "2n+1" or "-n + 3".
+func parseNth(argument string) (a, b int, ok bool) {
+	argument = strings.ToLower(strings.Join(strings.Fields(argument), ""))
+
+	switch argument {
+	case "odd":
+		return 2, 1, true
+	case "even":
+		return 2, 0, true
+	case "":
+		return 0, 0, false
+	}
+
+	n := strings.IndexByte(argument, 'n')
+	if n < 0 {
+		b, err := strconv.Atoi(argument)
+		return 0, b, err == nil
+	}
+
+	switch coefficient := argument[:n]; coefficient {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		var err error
+		if a, err = strconv.Atoi(coefficient); err != nil {
+			return 0, 0, false
+		}
+	}
+
+	if rest := argument[n+1:]; rest != "" {
+		if rest[0] != '+' && rest[0] != '-' {
+			return 0, 0, false
+		}
+		var err error
+		if b, err = strconv.Atoi(rest); err != nil {
+			return 0, 0, false
+		}
+	}
+	return a, b, true
+}
+
+// readName reads an identifier: letters, digits, "-", "_" and any non-ASCII
+// character.
+func (p *selectorParser) readName() string {
+	start := p.position
+	for !p.done() && isSelectorNameChar(p.peek()) {
+		p.position++
+	}
+	return p.input[start:p.position]
+}
+
+func isSelectorNameChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '_' || c >= 0x80
+}
+
+func isSelectorSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
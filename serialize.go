@@ -0,0 +1,285 @@
+package marquee
+
+import (
+	"sort"
+	"strings"
+)
+
+// SerializeOptions controls how a document is written back out as HTML. The
+// zero value writes the tree exactly as it is, with every end tag and quoted
+// attribute values; parsing the output gives the same tree back.
+type SerializeOptions struct {
+	// Pretty puts block-level elements on lines of their own, indented by
+	// Indent ("  " when empty). Text, inline content and the contents of
+	// pre and textarea are never reflowed, but the added line breaks come
+	// back as whitespace text inside elements such as div, so the output is
+	// for reading rather than for saving a document.
+	Pretty bool
+	Indent string
+
+	// Minimal leaves out the end tags HTML lets a parser infer, such as
+	// </li> before the next <li> and </p> at the end of its parent, writes
+	// attributes like "disabled" without a value, and quotes attribute
+	// values only when they need it.
+	Minimal bool
+}
+
+// Serialize writes the document as HTML, starting with its DOCTYPE if it
+// had one.
+func (d HTMLDocument) Serialize(options SerializeOptions) string {
+	s := newSerializer(options)
+
+	if d.Metadata.DocType != "" {
+		s.out.WriteString("<!DOCTYPE ")
+		s.out.WriteString(d.Metadata.DocType)
+		s.out.WriteString(">")
+		if options.Pretty && len(d.Root.Children) > 0 {
+			s.out.WriteString("\n")
+		}
+	}
+
+	s.writeNode(d.Root, nil, nil, 0)
+	return s.out.String()
+}
+
+// SerializeNode writes node and everything under it as HTML. For the
+// document node only its children are written.
+func SerializeNode(node HTMLNode, options SerializeOptions) string {
+	s := newSerializer(options)
+	s.writeNode(node, nil, nil, 0)
+	return s.out.String()
+}
+
+// blockLevelElements are the elements Pretty output puts on lines of their
+// own. Whitespace between them does not change how the document renders.
+var blockLevelElements = extendScope(closesParagraph,
+	"html", "head", "body", "title", "meta", "link", "style", "script",
+	"base", "template", "caption", "colgroup", "col", "thead", "tbody",
+	"tfoot", "tr", "td", "th", "legend")
+
+// verbatimElements hold text that is written exactly as it was parsed,
+// without escaping.
+var verbatimElements = map[string]bool{
+	"script": true, "style": true,
+}
+
+type serializer struct {
+	options      SerializeOptions
+	out          strings.Builder
+	preformatted int
+}
+
+func newSerializer(options SerializeOptions) *serializer {
+	if options.Indent == "" {
+		options.Indent = "  "
+	}
+	return &serializer{options: options}
+}
+
+// writeNode writes node, whose parent is parent and whose next sibling is
+// next, at nesting depth depth.
+func (s *serializer) writeNode(node HTMLNode, parent, next *HTMLNode, depth int) {
+	switch node.Type {
+	case NodeTypeDocument:
+		s.writeChildren(node, depth-1)
+	case NodeTypeText:
+		if parent != nil && verbatimElements[parent.Tag] {
+			s.out.WriteString(node.Content)
+		} else {
+			s.out.WriteString(escapeHTMLText(node.Content))
+		}
+	case NodeTypeComment:
+		s.out.WriteString("<!--")
+		s.out.WriteString(node.Content)
+		s.out.WriteString("-->")
+	case NodeTypeElement:
+		s.writeElement(node, parent, next, depth)
+	}
+}
+
+func (s *serializer) writeElement(node HTMLNode, parent, next *HTMLNode, depth int) {
+	s.out.WriteString("<")
+	s.out.WriteString(node.Tag)
+	s.writeAttributes(node.Attributes)
+	s.out.WriteString(">")
+
+	if isVoidElement(node.Tag) {
+		return
+	}
+
+	preformatted := node.Tag == "pre" || node.Tag == "textarea" || node.Tag == "listing"
+	if preformatted {
+		// The parser drops a newline straight after the start tag, so one
+		// that belongs to the content needs another in front of it.
+		if len(node.Children) > 0 && node.Children[0].Type == NodeTypeText &&
+			strings.HasPrefix(node.Children[0].Content, "\n") {
+			s.out.WriteString("\n")
+		}
+		s.preformatted++
+	}
+
+	s.writeChildren(node, depth)
+
+	if preformatted {
+		s.preformatted--
+	}
+
+	if s.options.Minimal && s.canOmitEndTag(node, parent, next) {
+		return
+	}
+	s.out.WriteString("</")
+	s.out.WriteString(node.Tag)
+	s.out.WriteString(">")
+}
+
+// writeChildren writes the children of node. With Pretty set, block-level
+// children go on lines of their own, one level deeper than depth, and runs
+// of inline content between them stay together on one line.
+func (s *serializer) writeChildren(node HTMLNode, depth int) {
+	pretty := s.options.Pretty && s.preformatted == 0
+	lastBlock := false
+
+	for i, child := range node.Children {
+		var next *HTMLNode
+		if i+1 < len(node.Children) {
+			next = &node.Children[i+1]
+		}
+
+		block := isBlockLevel(child)
+		newline := pretty && (block || lastBlock) && (i > 0 || node.Type != NodeTypeDocument)
+
+		if pretty && child.Type == NodeTypeText && !verbatimElements[node.Tag] {
+			// The line breaks stand in for whitespace at the edges of text.
+			if newline || i == 0 && node.Type == NodeTypeDocument {
+				child.Content = strings.TrimLeftFunc(child.Content, isHTMLSpace)
+			}
+			if next == nil && node.Type == NodeTypeDocument || next != nil && isBlockLevel(*next) {
+				child.Content = strings.TrimRightFunc(child.Content, isHTMLSpace)
+			}
+			if child.Content == "" {
+				continue
+			}
+		}
+
+		if newline {
+			s.writeNewline(depth + 1)
+		}
+		s.writeNode(child, &node, next, depth+1)
+		lastBlock = block
+	}
+
+	if pretty && lastBlock && node.Type != NodeTypeDocument {
+		s.writeNewline(depth)
+	}
+}
+
+func (s *serializer) writeNewline(depth int) {
+	s.out.WriteString("\n")
+	for i := 0; i < depth; i++ {
+		s.out.WriteString(s.options.Indent)
+	}
+}
+
+func isBlockLevel(node HTMLNode) bool {
+	return node.Type == NodeTypeElement && blockLevelElements[node.Tag]
+}
+
+// writeAttributes writes attributes in name order, so the output does not
+// depend on map iteration.
+func (s *serializer) writeAttributes(attributes map[string]string) {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := attributes[name]
+
+		s.out.WriteString(" ")
+		s.out.WriteString(name)
+
+		switch {
+		case s.options.Minimal && value == name:
+			// The parser gives a valueless attribute its own name as value.
+		case s.options.Minimal && value != "" && !strings.ContainsAny(value, " \t\n\f\r\"'=<>`"):
+			s.out.WriteString("=")
+			s.out.WriteString(escapeHTMLAttribute(value))
+		default:
+			s.out.WriteString(`="`)
+			s.out.WriteString(escapeHTMLAttribute(value))
+			s.out.WriteString(`"`)
+		}
+	}
+}
+
+// canOmitEndTag reports whether the end tag of node can be left out because
+// the parser infers it from what follows, as listed in the HTML5 spec under
+// "optional tags". Only an element sibling or the end of the parent counts;
+// text or a comment after the element keeps the end tag.
+func (s *serializer) canOmitEndTag(node HTMLNode, parent, next *HTMLNode) bool {
+	last := next == nil
+	nextTag := ""
+	if next != nil {
+		if next.Type != NodeTypeElement {
+			return false
+		}
+		nextTag = next.Tag
+	}
+
+	switch node.Tag {
+	case "html", "body":
+		return last
+	case "li":
+		return last || nextTag == "li"
+	case "dt":
+		return nextTag == "dt" || nextTag == "dd"
+	case "dd":
+		return last || nextTag == "dt" || nextTag == "dd"
+	case "p":
+		if last {
+			if parent == nil {
+				return true
+			}
+			switch parent.Tag {
+			case "a", "audio", "del", "ins", "map", "noscript", "video":
+				return false
+			}
+			return true
+		}
+		return closesParagraph[nextTag]
+	case "option":
+		return last || nextTag == "option" || nextTag == "optgroup"
+	case "optgroup":
+		return last || nextTag == "optgroup"
+	case "thead":
+		return nextTag == "tbody" || nextTag == "tfoot"
+	case "tbody":
+		return last || nextTag == "tbody" || nextTag == "tfoot"
+	case "tfoot":
+		return last
+	case "tr":
+		return last || nextTag == "tr"
+	case "td", "th":
+		return last || nextTag == "td" || nextTag == "th"
+	}
+	return false
+}
+
+var (
+	htmlTextEscaper = strings.NewReplacer(
+		"&", "&amp;", "<", "&lt;", ">", "&gt;", "\u00a0", "&nbsp;")
+	htmlAttributeEscaper = strings.NewReplacer(
+		"&", "&amp;", `"`, "&quot;", "\u00a0", "&nbsp;")
+)
+
+// escapeHTMLText escapes text content so it reads back as the same text.
+func escapeHTMLText(text string) string {
+	return htmlTextEscaper.Replace(text)
+}
+
+// escapeHTMLAttribute escapes an attribute value for use inside double
+// quotes or, when it has no quotes or spaces, without them.
+func escapeHTMLAttribute(value string) string {
+	return htmlAttributeEscaper.Replace(value)
+}
@@ -0,0 +1,44 @@
+package marquee
+
+import "testing"
+
+func TestSerializeRoundTrip(t *testing.T) {
+	documents := []string{
+		`<!DOCTYPE html><html><head><title>T</title></head><body><div><p>one <b>two</b> three</p><ul><li>a</li><li>b</li></ul></div></body></html>`,
+		"<p>a &amp; b &lt;c&gt;</p><pre>  keep\n    this</pre>",
+		`<p><a href="/x?a=1&amp;b=2" title="say &quot;hi&quot;">link</a><br><img src="i.png" alt=""></p>`,
+		"<dl><dt>term</dt><dd>one</dd><dd>two</dd></dl><table><tbody><tr><td>1</td><td>2</td></tr></tbody></table>",
+		`<select><option selected>a</option><option>b</option></select><!-- note --><script>if (a < b) {}</script>`,
+	}
+
+	for _, options := range []SerializeOptions{{}, {Minimal: true}} {
+		for _, html := range documents {
+			want := parse(t, html)
+			out := want.Serialize(options)
+			if got := parse(t, out).Serialize(SerializeOptions{}); got != want.Serialize(SerializeOptions{}) {
+				t.Errorf("%+v: %q serialized as %q, which parses as %q", options, html, out, got)
+			}
+		}
+	}
+}
+
+func TestSerializeEscaping(t *testing.T) {
+	doc := HTMLDocument{Root: HTMLNode{Type: NodeTypeDocument, Children: []HTMLNode{
+		NewElement("p", map[string]string{"title": `a "b" & <c>`, "class": "x"},
+			NewText(`1 < 2 & "3" > 0`)),
+		NewElement("script", nil, NewText("a < b && c")),
+	}}}
+
+	tests := []struct {
+		options SerializeOptions
+		want    string
+	}{
+		{SerializeOptions{}, `<p class="x" title="a &quot;b&quot; &amp; <c>">1 &lt; 2 &amp; "3" &gt; 0</p><script>a < b && c</script>`},
+		{SerializeOptions{Minimal: true}, `<p class=x title="a &quot;b&quot; &amp; <c>">1 &lt; 2 &amp; "3" &gt; 0</p><script>a < b && c</script>`},
+	}
+	for _, test := range tests {
+		if got := doc.Serialize(test.options); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.options, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package marquee
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrStreamClosed is returned by StreamParser.Write after Close.
+var ErrStreamClosed = errors.New("marquee: write to closed StreamParser")
+
+// StreamParser parses a document that arrives in pieces, such as an HTTP
+// response body. Feed it with Write or ReadFrom and call Close at the end of
+// the input. Document returns the tree parsed so far at any time, so the
+// document can be shown while the rest is still loading.
+//
+// A StreamParser is safe for use by one writer and any number of readers at
+// the same time.
+type StreamParser struct {
+	mu         sync.Mutex
+	parser     *StateMachineParser
+	generation int
+	closed     bool
+	document   HTMLDocument
+}
+
+// NewStreamParser creates a StreamParser that enforces the limits in
+// options.
+func NewStreamParser(options ParserOptions) *StreamParser {
+	parser := NewStateMachineParser(options)
+	parser.begin()
+	return &StreamParser{parser: parser}
+}
+
+// ParseReader parses the whole of r. On a read error it returns the document
+// parsed up to that point along with the error.
+func ParseReader(r io.Reader, options ParserOptions) (HTMLDocument, error) {
+	stream := NewStreamParser(options)
+	_, err := stream.ReadFrom(r)
+	stream.Close()
+	return stream.Document(), err
+}
+
+// Options returns the limits the parser enforces.
+func (s *StreamParser) Options() ParserOptions {
+	return s.parser.Options()
+}
+
+// Write parses the next piece of the document. Pieces may end anywhere,
+// including inside a tag or a multi-byte character. Input past MaxLength is
+// accepted and dropped.
+func (s *StreamParser) Write(data []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrStreamClosed
+	}
+	if len(data) > 0 {
+		s.parser.write(data)
+		s.generation++
+	}
+	return len(data), nil
+}
+
+// WriteString is like Write but takes a string.
+func (s *StreamParser) WriteString(data string) (int, error) {
+	return s.Write([]byte(data))
+}
+
+// ReadFrom parses everything r returns until io.EOF or an error. It does not
+// call Close.
+func (s *StreamParser) ReadFrom(r io.Reader) (int64, error) {
+	buffer := make([]byte, 32*1024)
+	var total int64
+
+	for {
+		n, err := r.Read(buffer)
+		if n > 0 {
+			if _, werr := s.Write(buffer[:n]); werr != nil {
+				return total, werr
+			}
+			total += int64(n)
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// Close ends the input: elements that are still open are closed and the
+// document is complete. Closing twice does nothing.
+func (s *StreamParser) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.document = s.parser.end()
+	linkParents(&s.document.Root)
+	s.closed = true
+	s.generation++
+	return nil
+}
+
+// Document returns the document parsed so far. Before Close, elements that
+// are still open appear with the children read up to now, and text that
+// is not yet followed by a tag is left out. The result is a copy that later
+// writes do not change.
+func (s *StreamParser) Document() HTMLDocument {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return s.document
+	}
+	return s.parser.snapshot()
+}
+
+// Generation returns a number that changes whenever the document does, so
+// callers can tell when Document has something new.
+func (s *StreamParser) Generation() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+// Closed reports whether Close has been called.
+func (s *StreamParser) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// streamLookahead is how many runes past the current one any tokenizer state
+// may look at, as in "<![CDATA[" or "</textarea>". Input that close to the
+// end of what has arrived waits for the next write.
+const streamLookahead = 16
+
+// begin prepares the parser for a new document.
+func (p *StateMachineParser) begin() {
+	p.Reset()
+	p.root = &HTMLNode{
+		Type:       NodeTypeDocument,
+		Context:    ContextRoot,
+		Attributes: make(map[string]string),
+		Children:   make([]HTMLNode, 0),
+	}
+	p.nodeStack = []NodeStackEntry{{Node: p.root, OriginalTag: "document"}}
+}
+
+// write adds data to the input and parses as far as is safe without knowing
+// what comes next.
+func (p *StateMachineParser) write(data []byte) {
+	if p.truncated {
+		return
+	}
+
+	if room := p.maxLength - p.written; len(data) > room {
+		cut := room
+		for cut > 0 && !utf8.RuneStart(data[cut]) {
+			cut--
+		}
+		data = data[:cut]
+		p.truncated = true
+	}
+	p.written += len(data)
+
+	p.cursor.source = append(p.cursor.source, data...)
+	p.pending = append(p.pending, data...)
+	p.decode(false)
+
+	limit := len(p.input) - streamLookahead
+	if limit > p.inputEnd {
+		limit = p.inputEnd
+	}
+	p.run(limit)
+}
+
+// decode moves complete characters from the pending bytes to the input,
+// turning "\r\n" and "\r" into "\n". Unless final is set, a partial UTF-8
+// sequence or a trailing "\r" waits for the next write.
+func (p *StateMachineParser) decode(final bool) {
+	i := 0
+	for i < len(p.pending) {
+		rest := p.pending[i:]
+		if !final && !utf8.FullRune(rest) {
+			break
+		}
+
+		r, size := utf8.DecodeRune(rest)
+		if r == '\r' {
+			if len(rest) == 1 && !final {
+				break
+			}
+			if len(rest) > 1 && rest[1] == '\n' {
+				size++
+			}
+			r = '\n'
+		}
+
+		i += size
+		p.appendRune(r, size)
+	}
+	p.pending = append(p.pending[:0], p.pending[i:]...)
+}
+
+// appendRune adds one decoded character of size source bytes to the input.
+// Leading whitespace is skipped and trailing whitespace is only parsed once
+// something follows it, as if the document were trimmed.
+func (p *StateMachineParser) appendRune(r rune, size int) {
+	if !p.started {
+		if unicode.IsSpace(r) {
+			p.skippedBytes += size
+			return
+		}
+		p.started = true
+		p.cursor.reset(p.skippedBytes)
+	}
+
+	p.input = append(p.input, r)
+	if !unicode.IsSpace(r) {
+		p.inputEnd = len(p.input)
+	}
+}
+
+// end parses the rest of the input, closes what is still open and returns
+// the finished document.
+func (p *StateMachineParser) end() HTMLDocument {
+	if p.truncated && !utf8.FullRune(p.pending) {
+		// The cut fell inside a character that began in an earlier write.
+		p.cursor.source = p.cursor.source[:len(p.cursor.source)-len(p.pending)]
+		p.pending = nil
+	}
+	p.decode(true)
+
+	if p.written == 0 {
+		return HTMLDocument{Root: HTMLNode{Type: NodeTypeDocument, Context: ContextRoot}}
+	}
+
+	p.input = p.input[:p.inputEnd]
+	p.run(len(p.input))
+
+	if p.textBuffer.Len() > 0 {
+		p.addTextNode(p.textBuffer.String())
+	}
+
+	p.finishMarkupAtEOF()
+
+	p.reportUnclosed(1)
+	for len(p.nodeStack) > 1 {
+		p.nodeStack = p.nodeStack[:len(p.nodeStack)-1]
+	}
+
+	if p.truncated {
+		p.addDiagnostic(SeverityError, DiagnosticTruncatedInput, len(p.input), "",
+			fmt.Sprintf("input is longer than %d bytes; the rest was not parsed", p.maxLength))
+	}
+
+	trimDocumentWhitespace(p.root)
+	linkParents(p.root)
+	metadata := collectMetadata(p.root)
+	metadata.DocType = p.docType
+
+	return HTMLDocument{Root: *p.root, Metadata: metadata, Diagnostics: p.diagnostics}
+}
+
+// snapshot returns a copy of the document parsed so far.
+func (p *StateMachineParser) snapshot() HTMLDocument {
+	root := cloneNode(*p.root)
+	linkParents(&root)
+
+	metadata := collectMetadata(&root)
+	metadata.DocType = p.docType
+
+	return HTMLDocument{
+		Root:        root,
+		Metadata:    metadata,
+		Diagnostics: append([]ParseDiagnostic(nil), p.diagnostics...),
+	}
+}
+
+// cloneNode returns a deep copy of node. The Parent pointers of the copy
+// still point into the original tree; see linkParents.
+func cloneNode(node HTMLNode) HTMLNode {
+	clone := node
+
+	if node.Attributes != nil {
+		clone.Attributes = make(map[string]string, len(node.Attributes))
+		for k, v := range node.Attributes {
+			clone.Attributes[k] = v
+		}
+	}
+
+	if node.Children != nil {
+		clone.Children = make([]HTMLNode, len(node.Children))
+		for i, child := range node.Children {
+			clone.Children[i] = cloneNode(child)
+		}
+	}
+
+	return clone
+}
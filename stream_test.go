@@ -0,0 +1,46 @@
+package marquee
+
+import "testing"
+
+func TestStreamParserMatchesParse(t *testing.T) {
+	const html = "<p>café &amp; <b>bar</b></p>\n<ul><li>one<li>two</ul> <i>x</i>"
+	want := parse(t, html).Serialize(SerializeOptions{})
+
+	for size := 1; size <= len(html); size++ {
+		stream := NewStreamParser(DefaultParserOptions())
+		for start := 0; start < len(html); start += size {
+			end := start + size
+			if end > len(html) {
+				end = len(html)
+			}
+			stream.WriteString(html[start:end])
+		}
+		stream.Close()
+
+		if got := stream.Document().Serialize(SerializeOptions{}); got != want {
+			t.Errorf("chunks of %d bytes: got %q, want %q", size, got, want)
+		}
+	}
+}
+
+func TestStreamParserLinksParents(t *testing.T) {
+	stream := NewStreamParser(DefaultParserOptions())
+	stream.WriteString("<p>one</p><p>two <b>three</b></p>")
+	stream.Close()
+
+	root := &stream.document.Root
+	for i := range root.Children {
+		if root.Children[i].Parent != root {
+			t.Errorf("child %d: Parent is not the document root", i)
+		}
+	}
+	p := &root.Children[1]
+	if b := &p.Children[1]; b.Parent != p {
+		t.Errorf("<b>: Parent is not its <p>")
+	}
+
+	doc := stream.Document()
+	if !doc.contains(&doc.Root.Children[1].Children[1]) {
+		t.Errorf("document does not contain its own <b>")
+	}
+}
@@ -0,0 +1,406 @@
+package marquee
+
+import (
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ComputedStyle is the presentation resolved for a node: what its tag
+// implies, combined with the style rules that match it, its style attribute
+// and what it inherits from its ancestors. Renderers read it instead of
+// looking at tag names, so <b>, <strong> and style="font-weight: bold" all
+// render the same way.
+type ComputedStyle struct {
+	Bold        bool
+	Italic      bool
+	Underline   bool
+	LineThrough bool
+	Monospace   bool
+
+	// FontSize is the size of the text in pixels set by the node or an
+	// ancestor. It is zero when nothing sets one, and text keeps the size
+	// of its theme font.
+	FontSize float32
+
+	// Color is the text color set by the node or an ancestor, which may be
+	// transparent. When nothing sets one, as HasColor reports, renderers use
+	// the color of ColorRole in their theme, or their own default.
+	Color rl.Color
+
+	// ColorRole says which theme color the text takes when Color is not
+	// set: that of a link or of code, or of text that became italic or bold
+	// here or in an ancestor. Keeping the role rather than the color lets
+	// the theme change without styling the document again.
+	ColorRole ColorRole
+
+	// TextAlign places the lines of text in a block.
+	TextAlign TextAlign
+
+	// hasColor records that Color was set, since a color such as
+	// transparent is zero as well.
+	hasColor bool
+
+	// The fields below belong to the node alone and are not inherited.
+
+	// Hidden is set by display: none. The node and everything in it are
+	// left out of the layout.
+	Hidden bool
+
+	// Background fills the box of a block, or sits behind the text of an
+	// inline element. It is zero when not set.
+	Background rl.Color
+
+	// Margin, Border and Padding surround the content of a block. Sides of
+	// the margin that are not set keep the space the renderer leaves around
+	// the block.
+	Margin  Edges
+	Border  Border
+	Padding Edges
+
+	// Width and MaxWidth limit the width of the content of a block, which
+	// otherwise fills the space it is given.
+	Width    Length
+	MaxWidth Length
+
+	// ownFontSize records that FontSize was set on the node itself rather
+	// than inherited, so elements with a font of their own, such as
+	// headings, take it.
+	ownFontSize bool
+}
+
+// ColorRole names a text color of the theme.
+type ColorRole int
+
+const (
+	// RoleNone leaves the text the color of its surroundings.
+	RoleNone ColorRole = iota
+	RoleLink
+	RoleItalic
+	RoleBold
+	RoleCode
+)
+
+// HasColor reports whether Color was set.
+func (s ComputedStyle) HasColor() bool {
+	return s.hasColor
+}
+
+// inherited returns the part of the style a child inherits.
+func (s ComputedStyle) inherited() ComputedStyle {
+	return ComputedStyle{
+		Bold:        s.Bold,
+		Italic:      s.Italic,
+		Underline:   s.Underline,
+		LineThrough: s.LineThrough,
+		Monospace:   s.Monospace,
+		FontSize:    s.FontSize,
+		Color:       s.Color,
+		ColorRole:   s.ColorRole,
+		TextAlign:   s.TextAlign,
+		hasColor:    s.hasColor,
+	}
+}
+
+// fontSize returns the size em is relative to for the node.
+func (s ComputedStyle) fontSize() float32 {
+	if s.FontSize > 0 {
+		return s.FontSize
+	}
+	return mediumFontSize
+}
+
+// hasBox reports whether the style gives a block anything beyond its
+// vertical margins: a background, border or padding, side margins or a
+// width.
+func (s ComputedStyle) hasBox() bool {
+	return s.Background.A != 0 || s.Border.Width.IsSet() || s.Padding.isSet() ||
+		s.Margin.Left.IsSet() || s.Margin.Right.IsSet() ||
+		s.Width.IsSet() || s.MaxWidth.IsSet()
+}
+
+// inlineFormattingTags are the phrasing elements whose only effect is on the
+// computed style of their text.
+var inlineFormattingTags = map[string]bool{
+	"span": true, "b": true, "strong": true, "i": true, "em": true,
+	"cite": true, "var": true, "dfn": true, "u": true, "ins": true,
+	"s": true, "strike": true, "del": true, "kbd": true, "samp": true,
+	"tt": true, "mark": true, "small": true, "abbr": true, "q": true,
+	"sub": true, "sup": true,
+}
+
+func isInlineFormattingTag(tagName string) bool {
+	return inlineFormattingTags[tagName]
+}
+
+// computeStyle resolves the style of a single node from its tag, the
+// properties the cascade gives it and the style of its parent.
+func computeStyle(node HTMLNode, parent ComputedStyle, properties map[string]string) ComputedStyle {
+	if node.Type != NodeTypeElement {
+		return parent.inherited()
+	}
+
+	style := parent.inherited()
+
+	switch node.Tag {
+	case "b", "strong":
+		style.Bold = true
+	case "i", "em", "cite", "var", "dfn":
+		style.Italic = true
+	case "u", "ins":
+		style.Underline = true
+	case "s", "strike", "del":
+		style.LineThrough = true
+	case "code", "kbd", "samp", "tt":
+		style.Monospace = true
+	}
+
+	if len(properties) > 0 {
+		applyStyleDeclarations(&style, properties)
+	}
+
+	// Links, code outside links, and text that becomes italic or bold, take
+	// the theme's color for them.
+	switch {
+	case node.Tag == "a" && node.Attributes["href"] != "":
+		style.ColorRole = RoleLink
+	case style.Monospace && !parent.Monospace && parent.ColorRole != RoleLink:
+		style.ColorRole = RoleCode
+	case style.Italic && !parent.Italic:
+		style.ColorRole = RoleItalic
+	case style.Bold && !parent.Bold:
+		style.ColorRole = RoleBold
+	}
+
+	return style
+}
+
+// applyStyleDeclarations applies the properties the renderers understand.
+// Values that are not understood are ignored, as browsers do.
+func applyStyleDeclarations(style *ComputedStyle, properties map[string]string) {
+	// The font size comes first, since em in the other lengths is relative
+	// to it.
+	if size, exists := properties["font-size"]; exists {
+		if pixels, ok := parseFontSize(size, style.fontSize()); ok {
+			style.FontSize = pixels
+			style.ownFontSize = true
+		}
+	}
+	fontSize := style.fontSize()
+
+	if weight, exists := properties["font-weight"]; exists {
+		switch weight {
+		case "bold", "bolder":
+			style.Bold = true
+		case "normal", "lighter":
+			style.Bold = false
+		default:
+			if n, err := strconv.Atoi(weight); err == nil {
+				style.Bold = n >= 600
+			}
+		}
+	}
+
+	if fontStyle, exists := properties["font-style"]; exists {
+		switch fontStyle {
+		case "italic", "oblique":
+			style.Italic = true
+		case "normal":
+			style.Italic = false
+		}
+	}
+
+	for _, name := range []string{"text-decoration", "text-decoration-line"} {
+		decoration, exists := properties[name]
+		if !exists {
+			continue
+		}
+		if decoration == "none" {
+			style.Underline = false
+			style.LineThrough = false
+		}
+		for _, value := range strings.Fields(decoration) {
+			switch value {
+			case "underline":
+				style.Underline = true
+			case "line-through":
+				style.LineThrough = true
+			}
+		}
+	}
+
+	if value, exists := properties["color"]; exists {
+		if color, ok := parseColor(value); ok {
+			style.Color, style.hasColor = color, true
+		}
+	}
+
+	// The background shorthand sets the color and nothing else marquee
+	// draws, so images and positions in it are skipped.
+	for _, name := range []string{"background", "background-color"} {
+		value, exists := properties[name]
+		if !exists {
+			continue
+		}
+		for _, field := range fieldsOutsideParens(value) {
+			if color, ok := parseColor(field); ok {
+				style.Background = color
+			}
+		}
+	}
+
+	if align, exists := properties["text-align"]; exists {
+		switch align {
+		case "left", "start":
+			style.TextAlign = AlignLeft
+		case "center":
+			style.TextAlign = AlignCenter
+		case "right", "end":
+			style.TextAlign = AlignRight
+		case "justify":
+			style.TextAlign = AlignJustify
+		}
+	}
+
+	applyEdges(&style.Margin, properties, "margin", fontSize, true)
+	applyEdges(&style.Padding, properties, "padding", fontSize, false)
+	applyBorder(style, properties, fontSize)
+
+	if value, exists := properties["width"]; exists {
+		if width, ok := parseLength(value, fontSize); ok && width.Value >= 0 {
+			style.Width = width
+		}
+	}
+	if value, exists := properties["max-width"]; exists {
+		if width, ok := parseLength(value, fontSize); ok && width.Value >= 0 {
+			style.MaxWidth = width
+		}
+	}
+
+	if properties["display"] == "none" {
+		style.Hidden = true
+	}
+}
+
+// applyEdges applies the shorthand property name, such as "margin", and
+// then its longhands, such as "margin-top", to edges.
+func applyEdges(edges *Edges, properties map[string]string, name string, fontSize float32, negative bool) {
+	if value, exists := properties[name]; exists {
+		if parsed, ok := parseEdges(value, fontSize, negative); ok {
+			*edges = parsed
+		}
+	}
+
+	sides := []struct {
+		name   string
+		length *Length
+	}{
+		{"-top", &edges.Top}, {"-right", &edges.Right},
+		{"-bottom", &edges.Bottom}, {"-left", &edges.Left},
+	}
+	for _, side := range sides {
+		if value, exists := properties[name+side.name]; exists {
+			if length, ok := parseEdge(value, fontSize, negative); ok {
+				*side.length = length
+			}
+		}
+	}
+}
+
+// applyBorder applies the border shorthand and then border-width,
+// border-style and border-color.
+func applyBorder(style *ComputedStyle, properties map[string]string, fontSize float32) {
+	var parts borderParts
+	given := false
+
+	if value, exists := properties["border"]; exists {
+		if parsed, ok := parseBorder(value); ok {
+			parts, given = parsed, true
+		}
+	}
+	if width, exists := properties["border-width"]; exists && (borderWidths[width] != 0 || isLength(width)) {
+		parts.width, given = width, true
+	}
+	if borderStyle, exists := properties["border-style"]; exists && borderStyles[borderStyle] {
+		parts.style, given = borderStyle, true
+	}
+	if color, exists := properties["border-color"]; exists {
+		if _, ok := parseColor(color); ok {
+			parts.color, given = color, true
+		}
+	}
+
+	if given {
+		style.Border = parts.border(fontSize)
+	}
+}
+
+// styledFont returns the font for style, or base when the style asks for
+// nothing beyond the surrounding text.
+func (r *HTMLRenderer) styledFont(style ComputedStyle, base Font) Font {
+	fonts := r.Theme.Fonts
+	font := base
+	switch {
+	case style.Monospace:
+		font = fonts.Monospace
+	case style.Bold && style.Italic:
+		font = fonts.BoldItalic
+	case style.Bold:
+		font = fonts.Bold
+	case style.Italic:
+		font = fonts.Italic
+	}
+	if style.FontSize > 0 {
+		font.Size = style.FontSize
+	}
+	return font
+}
+
+// ownFont returns font, the font an element such as a heading is set in
+// whatever surrounds it, at the size the element's own style gives it.
+func (s ComputedStyle) ownFont(font Font) Font {
+	if s.ownFontSize {
+		font.Size = s.FontSize
+	}
+	return font
+}
+
+// styledBackground returns the background for the text of an inline
+// element with style, inside text whose background is base.
+func styledBackground(style ComputedStyle, base rl.Color) rl.Color {
+	if style.Background.A != 0 {
+		return style.Background
+	}
+	return base
+}
+
+// borderColor returns the color of the border style draws: its own, or
+// the color of the text.
+func (r *HTMLRenderer) borderColor(style ComputedStyle) rl.Color {
+	if style.Border.Color.A != 0 {
+		return style.Border.Color
+	}
+	return r.styledColor(style, r.Theme.Colors.Text)
+}
+
+// styledColor returns the color for style: the color it sets, or the
+// theme's color for its role, or base when it has neither.
+func (r *HTMLRenderer) styledColor(style ComputedStyle, base rl.Color) rl.Color {
+	if style.HasColor() {
+		return style.Color
+	}
+
+	colors := r.Theme.Colors
+	switch style.ColorRole {
+	case RoleLink:
+		return colors.Link
+	case RoleItalic:
+		return colors.Italic
+	case RoleBold:
+		return colors.Bold
+	case RoleCode:
+		return colors.Code.Text
+	}
+	return base
+}
@@ -0,0 +1,91 @@
+package marquee
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// computedStyle returns the computed style of the first element matching
+// selector in html, styled with sheet, which may be nil.
+func computedStyle(t *testing.T, html, selector string, sheet *StyleSheet, scheme ColorScheme) ComputedStyle {
+	t.Helper()
+	doc := parse(t, html)
+	styleDocument(&doc, sheet, scheme)
+	node, err := doc.QuerySelector(selector)
+	if err != nil || node == nil {
+		t.Fatalf("no element matches %q in %q (%v)", selector, html, err)
+	}
+	return node.Style
+}
+
+func TestFormattingTagsKeepTheirNames(t *testing.T) {
+	doc := parse(t, `<p><b>1</b><i>2</i><strong>3</strong><em>4</em><u>5</u><s>6</s><code>7</code></p>`)
+
+	var tags []string
+	for _, child := range doc.Root.Children[0].Children {
+		tags = append(tags, child.Tag)
+	}
+	if want := []string{"b", "i", "strong", "em", "u", "s", "code"}; !equalStrings(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+	if b := doc.Root.Children[0].Children[0]; len(b.Attributes) != 0 {
+		t.Errorf("<b> has attributes %v, want none", b.Attributes)
+	}
+}
+
+func TestComputedFormatting(t *testing.T) {
+	type flags struct{ bold, italic, underline, lineThrough, monospace bool }
+	tests := []struct {
+		html string
+		want flags
+	}{
+		{`<b><i id="x">x</i></b>`, flags{bold: true, italic: true}},
+		{`<span id="x" style="font-weight:bold;font-style:italic">x</span>`, flags{bold: true, italic: true}},
+		{`<strong><em><span id="x">x</span></em></strong>`, flags{bold: true, italic: true}},
+		{`<b><span id="x" style="font-weight: normal">x</span></b>`, flags{}},
+		{`<span id="x" style="font-weight: 700">x</span>`, flags{bold: true}},
+		{`<u><s id="x">x</s></u>`, flags{underline: true, lineThrough: true}},
+		{`<span id="x" style="text-decoration: underline line-through">x</span>`, flags{underline: true, lineThrough: true}},
+		{`<kbd id="x">x</kbd>`, flags{monospace: true}},
+		{`<p id="x">x</p>`, flags{}},
+	}
+
+	for _, test := range tests {
+		style := computedStyle(t, test.html, "#x", nil, ColorSchemeLight)
+		got := flags{style.Bold, style.Italic, style.Underline, style.LineThrough, style.Monospace}
+		if got != test.want {
+			t.Errorf("%s: %+v, want %+v", test.html, got, test.want)
+		}
+	}
+}
+
+func TestComputedColor(t *testing.T) {
+	red := rl.Color{R: 255, A: 255}
+	tests := []struct {
+		html     string
+		hasColor bool
+		color    rl.Color
+		painted  rl.Color
+	}{
+		{`<p>x <span id="x">y</span></p>`, false, rl.Color{}, rl.Black},
+		{`<p>x <span id="x" style="color: #f00">y</span></p>`, true, red, red},
+		{`<p style="color: #f00">x <span id="x">y</span></p>`, true, red, red},
+		{`<p>x <span id="x" style="color: transparent">y</span></p>`, true, rl.Color{}, rl.Color{}},
+		{`<p style="color: transparent">x <b id="x">y</b></p>`, true, rl.Color{}, rl.Color{}},
+		{`<p>x <a id="x" href="/y" style="color: transparent">y</a></p>`, true, rl.Color{}, rl.Color{}},
+		{`<p>x <span id="x" style="color: nonsense">y</span></p>`, false, rl.Color{}, rl.Black},
+	}
+
+	for _, test := range tests {
+		style := computedStyle(t, test.html, "#x", nil, ColorSchemeLight)
+		if style.HasColor() != test.hasColor || style.Color != test.color {
+			t.Errorf("%s: HasColor %v, Color %v, want %v and %v", test.html, style.HasColor(), style.Color, test.hasColor, test.color)
+		}
+
+		layout := fixedLayout(t, test.html, 200)
+		if got := fragmentColors(layout.Root, map[string]rl.Color{})["y"]; got != test.painted {
+			t.Errorf("%s: y painted %v, want %v", test.html, got, test.painted)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package marquee
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// StyleSheet is a parsed CSS style sheet: rules that give the elements a
+// selector matches the properties of a style attribute. Selectors are those
+// ParseSelector understands. Rules inside @media blocks apply while the media
+// query matches; rules whose selector is outside the subset, and other
+// at-rules, are skipped, as browsers skip what they do not understand.
+type StyleSheet struct {
+	rules []styleRule
+}
+
+// styleRule is a selector and the declarations it applies. media holds the
+// query lists of the @media blocks the rule is nested in, all of which must
+// match for it to apply.
+type styleRule struct {
+	selector     *Selector
+	declarations []declaration
+	media        []string
+}
+
+// declaration sets a property, with its name and value in lower case.
+// Important declarations win over ordinary ones wherever they come from.
+type declaration struct {
+	name, value string
+	important   bool
+}
+
+// ParseStyleSheet parses css, such as the contents of a <style> element. It
+// cannot fail: what it cannot parse is left out.
+func ParseStyleSheet(css string) *StyleSheet {
+	sheet := &StyleSheet{}
+	sheet.parseRules(stripComments(css), nil)
+	return sheet
+}
+
+// parseRules adds the rules in css, nested in @media blocks with the query
+// lists in media.
+func (s *StyleSheet) parseRules(css string, media []string) {
+	for {
+		prelude, body, rest, found := nextRule(css)
+		if !found {
+			return
+		}
+		css = rest
+
+		if query, isMedia := cutAtRule(prelude, "@media"); isMedia {
+			s.parseRules(body, append(media[:len(media):len(media)], query))
+			continue
+		}
+		if prelude == "" || strings.HasPrefix(prelude, "@") {
+			continue
+		}
+		selector, err := ParseSelector(prelude)
+		if err != nil {
+			continue
+		}
+		s.rules = append(s.rules, styleRule{
+			selector:     selector,
+			declarations: parseDeclarations(body),
+			media:        media,
+		})
+	}
+}
+
+// cutAtRule returns what follows the keyword of the at-rule prelude if it
+// is name, ignoring case.
+func cutAtRule(prelude, name string) (string, bool) {
+	if len(prelude) < len(name) || !strings.EqualFold(prelude[:len(name)], name) {
+		return "", false
+	}
+	rest := prelude[len(name):]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' && rest[0] != '\n' && rest[0] != '(' {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// stripComments removes the /* */ comments from css, leaving strings alone.
+func stripComments(css string) string {
+	if !strings.Contains(css, "/*") {
+		return css
+	}
+
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(css) {
+				b.WriteByte(c)
+				i++
+				c = css[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && i+1 < len(css) && css[i+1] == '*':
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+			b.WriteByte(' ')
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// nextRule splits the first rule off css: the prelude before its block, the
+// contents of the block and what follows it. An at-rule that ends with a
+// semicolon, such as @import, has no block. A block left open at the end of
+// css is closed there, as in browsers.
+func nextRule(css string) (prelude, body, rest string, found bool) {
+	start := -1
+	depth := 0
+	var quote byte
+
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ';' && start < 0:
+			// A statement at-rule, or stray text, ends here.
+			return strings.TrimSpace(css[:i]), "", css[i+1:], true
+		case c == '{':
+			if start < 0 {
+				start = i
+			}
+			depth++
+		case c == '}':
+			if start < 0 {
+				// A stray closing brace ends the text before it.
+				return strings.TrimSpace(css[:i]), "", css[i+1:], true
+			}
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(css[:start]), css[start+1 : i], css[i+1:], true
+			}
+		}
+	}
+
+	if start < 0 {
+		return "", "", "", false
+	}
+	return strings.TrimSpace(css[:start]), css[start+1:], "", true
+}
+
+// parseDeclarations splits a block of declarations, such as the contents of
+// a style attribute, into properties and their values in lower case.
+func parseDeclarations(text string) []declaration {
+	var declarations []declaration
+
+	for _, part := range splitOutsideParens(text, ';') {
+		name, value, found := strings.Cut(part, ":")
+		if !found {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.ToLower(strings.TrimSpace(value))
+
+		important := false
+		if at := strings.LastIndexByte(value, '!'); at >= 0 && strings.TrimSpace(value[at+1:]) == "important" {
+			value = strings.TrimSpace(value[:at])
+			important = true
+		}
+
+		if name != "" && value != "" {
+			declarations = append(declarations, declaration{name: name, value: value, important: important})
+		}
+	}
+
+	return declarations
+}
+
+// splitOutsideParens splits s at each sep that is not inside parentheses or
+// quotes, so the semicolons of a data: URL stay in its value.
+func splitOutsideParens(s string, sep byte) []string {
+	var parts []string
+	depth := 0
+	var quote byte
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// longhands lists the properties a shorthand sets, which a later shorthand
+// overrides as well.
+var longhands = map[string][]string{
+	"margin":          {"margin-top", "margin-right", "margin-bottom", "margin-left"},
+	"padding":         {"padding-top", "padding-right", "padding-bottom", "padding-left"},
+	"border":          {"border-width", "border-style", "border-color"},
+	"background":      {"background-color"},
+	"text-decoration": {"text-decoration-line"},
+}
+
+// cascade is the style rules that apply to a document, in the order they
+// were declared: those of the style sheet the application supplies, then
+// those of the document's <style> elements.
+type cascade struct {
+	rules []styleRule
+}
+
+// newCascade collects the rules of sheet, which may be nil, and of the
+// <style> elements of document that apply to a screen in scheme, which is
+// light or dark.
+func newCascade(document *HTMLDocument, sheet *StyleSheet, scheme ColorScheme) cascade {
+	var c cascade
+	add := func(rules []styleRule) {
+		for _, rule := range rules {
+			if rule.appliesTo(scheme) {
+				c.rules = append(c.rules, rule)
+			}
+		}
+	}
+
+	if sheet != nil {
+		add(sheet.rules)
+	}
+	for _, info := range document.Metadata.StyleSheets {
+		if info.Href == "" && mediaMatches(info.Media, scheme) {
+			add(ParseStyleSheet(info.Content).rules)
+		}
+	}
+	return c
+}
+
+func (r styleRule) appliesTo(scheme ColorScheme) bool {
+	for _, media := range r.media {
+		if !mediaMatches(media, scheme) {
+			return false
+		}
+	}
+	return true
+}
+
+// mediaMatches reports whether the media query list media, from an @media
+// rule or the media attribute of a <style> element, matches a screen in
+// scheme. An empty list matches everything. Media types other than all and
+// screen do not match, and neither do features other than
+// prefers-color-scheme.
+func mediaMatches(media string, scheme ColorScheme) bool {
+	if strings.TrimSpace(media) == "" {
+		return true
+	}
+	for _, query := range strings.Split(media, ",") {
+		if mediaQueryMatches(strings.ToLower(strings.TrimSpace(query)), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// mediaQueryMatches evaluates one query such as
+// "screen and (prefers-color-scheme: dark)" or "not print".
+func mediaQueryMatches(query string, scheme ColorScheme) bool {
+	matches, negate := true, false
+
+	for query != "" {
+		if query[0] == '(' {
+			end := strings.IndexByte(query, ')')
+			if end < 0 {
+				return false
+			}
+			matches = matches && mediaFeatureMatches(query[1:end], scheme)
+			query = strings.TrimSpace(query[end+1:])
+			continue
+		}
+
+		word := query
+		if end := strings.IndexAny(query, " \t\n("); end >= 0 {
+			word = query[:end]
+		}
+		switch word {
+		case "not":
+			negate = true
+		case "only", "and", "all", "screen":
+		default:
+			matches = false
+		}
+		query = strings.TrimSpace(query[len(word):])
+	}
+
+	return matches != negate
+}
+
+// mediaFeatureMatches evaluates a media feature, without its parentheses.
+func mediaFeatureMatches(feature string, scheme ColorScheme) bool {
+	name, value, hasValue := strings.Cut(feature, ":")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+
+	switch name {
+	case "prefers-color-scheme":
+		switch {
+		case !hasValue:
+			return true
+		case value == "dark":
+			return scheme == ColorSchemeDark
+		case value == "light":
+			return scheme != ColorSchemeDark
+		}
+	}
+	return false
+}
+
+// styleDocument resolves the computed style of every node of document with
+// the rules of sheet, which may be nil, and of its <style> elements, for a
+// screen in scheme.
+func styleDocument(document *HTMLDocument, sheet *StyleSheet, scheme ColorScheme) {
+	newCascade(document, sheet, scheme).computeStyles(&document.Root, ComputedStyle{}, nil)
+}
+
+// styledCopy returns a copy of document styled as styleDocument does. The
+// nodes of a document passed by value are still shared with the caller,
+// such as a widget, whose styles are left as they are.
+func styledCopy(document HTMLDocument, sheet *StyleSheet, scheme ColorScheme) HTMLDocument {
+	document.Root = cloneNode(document.Root)
+	linkParents(&document.Root)
+	styleDocument(&document, sheet, scheme)
+	return document
+}
+
+// computeStyles resolves the computed style of every node under root, which
+// inherits parent. path leads from the top of the document to root when
+// root is an element, and is what the selectors of the rules are matched
+// against.
+func (c cascade) computeStyles(root *HTMLNode, parent ComputedStyle, path []elementPosition) {
+	root.Style = computeStyle(*root, parent, c.properties(*root, path))
+
+	count := 0
+	for i := range root.Children {
+		if root.Children[i].Type == NodeTypeElement {
+			count++
+		}
+	}
+
+	index := 0
+	for i := range root.Children {
+		child := &root.Children[i]
+		childPath := path
+		if child.Type == NodeTypeElement {
+			index++
+			childPath = append(path, elementPosition{node: child, index: index, count: count})
+		}
+		c.computeStyles(child, root.Style, childPath)
+	}
+}
+
+// elementPath returns the path computeStyles matches node against: the
+// elements from the top of the document down to node, or to its parent when
+// node is not an element, following Parent pointers.
+func elementPath(node *HTMLNode) []elementPosition {
+	var path []elementPosition
+	for ; node != nil && node.Parent != nil; node = node.Parent {
+		if node.Type != NodeTypeElement {
+			continue
+		}
+
+		position := elementPosition{node: node}
+		for i := range node.Parent.Children {
+			sibling := &node.Parent.Children[i]
+			if sibling.Type != NodeTypeElement {
+				continue
+			}
+			position.count++
+			if sibling == node {
+				position.index = position.count
+			}
+		}
+		path = append(path, position)
+	}
+
+	slices.Reverse(path)
+	return path
+}
+
+// properties returns the properties that apply to node, at the end of path,
+// once the cascade has settled which declarations win: important ones over
+// ordinary ones, then the style attribute over the rules, then the more
+// specific rule, then the later one.
+func (c cascade) properties(node HTMLNode, path []elementPosition) map[string]string {
+	if node.Type != NodeTypeElement {
+		return nil
+	}
+
+	type match struct {
+		specificity Specificity
+		rule        *styleRule
+	}
+	var matches []match
+	if len(path) > 0 {
+		for i := range c.rules {
+			if specificity, ok := c.rules[i].selector.matchPath(path); ok {
+				matches = append(matches, match{specificity, &c.rules[i]})
+			}
+		}
+	}
+	// The sort is stable, so rules of equal specificity stay in the order
+	// they were declared.
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].specificity.Less(matches[j].specificity)
+	})
+
+	inline := parseDeclarations(node.Attributes["style"])
+	if len(matches) == 0 && len(inline) == 0 {
+		return nil
+	}
+
+	properties := make(map[string]string)
+	set := func(declarations []declaration, important bool) {
+		for _, d := range declarations {
+			if d.important != important {
+				continue
+			}
+			for _, longhand := range longhands[d.name] {
+				delete(properties, longhand)
+			}
+			properties[d.name] = d.value
+		}
+	}
+	for _, important := range []bool{false, true} {
+		for _, m := range matches {
+			set(m.rule.declarations, important)
+		}
+		set(inline, important)
+	}
+
+	return properties
+}
@@ -0,0 +1,148 @@
+package marquee
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestCascade(t *testing.T) {
+	red := rl.Color{R: 255, A: 255}
+	blue := rl.Color{B: 255, A: 255}
+	tests := []struct {
+		name  string
+		sheet string
+		html  string
+		want  rl.Color
+	}{
+		{"type", "",
+			`<style>p { color: #f00 }</style><p id="x">x</p>`, red},
+		{"class beats type", "",
+			`<style>.a { color: #f00 } p { color: #00f }</style><p id="x" class="a">x</p>`, red},
+		{"id beats class", "",
+			`<style>#x { color: #f00 } p.a.b { color: #00f }</style><p id="x" class="a b">x</p>`, red},
+		{"later rule wins", "",
+			`<style>p { color: #f00 } p { color: #00f }</style><p id="x">x</p>`, blue},
+		{"later style element wins", "",
+			`<style>p { color: #f00 }</style><style>p { color: #00f }</style><p id="x">x</p>`, blue},
+		{"descendant", "",
+			`<style>div p { color: #f00 }</style><div><section><p id="x">x</p></section></div>`, red},
+		{"descendant outside", "",
+			`<style>div p { color: #f00 }</style><p id="x">x</p>`, rl.Color{}},
+		{"child", "",
+			`<style>div > p { color: #f00 }</style><div><p id="x">x</p></div>`, red},
+		{"not a child", "",
+			`<style>div > p { color: #f00 }</style><div><section><p id="x">x</p></section></div>`, rl.Color{}},
+		{"selector list", "",
+			`<style>h1, p { color: #f00 }</style><p id="x">x</p>`, red},
+		{"inherited", "",
+			`<style>div { color: #f00 }</style><div><p id="x">x</p></div>`, red},
+		{"inline beats id", "",
+			`<style>#x { color: #f00 }</style><p id="x" style="color: #00f">x</p>`, blue},
+		{"important beats inline", "",
+			`<style>p { color: #f00 !important }</style><p id="x" style="color: #00f">x</p>`, red},
+		{"comments", "",
+			`<style>/* p { color: #00f } */ p { color: /* red */ #f00 }</style><p id="x">x</p>`, red},
+		{"application sheet", "p { color: #f00 }",
+			`<p id="x">x</p>`, red},
+		{"document after application", "p { color: #f00 }",
+			`<style>p { color: #00f }</style><p id="x">x</p>`, blue},
+		{"application more specific", "#x { color: #f00 }",
+			`<style>p { color: #00f }</style><p id="x">x</p>`, red},
+	}
+
+	for _, test := range tests {
+		var sheet *StyleSheet
+		if test.sheet != "" {
+			sheet = ParseStyleSheet(test.sheet)
+		}
+		if got := computedStyle(t, test.html, "#x", sheet, ColorSchemeLight).Color; got != test.want {
+			t.Errorf("%s: color %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSetStyleSheet(t *testing.T) {
+	w := newTestWidget(`<style>p { color: #00f }</style><p id="x">x</p><h2 id="y">y</h2>`)
+
+	tests := []struct {
+		css  string
+		p, h rl.Color
+	}{
+		{"p, h2 { color: #f00 }", rl.Color{B: 255, A: 255}, rl.Color{R: 255, A: 255}},
+		{"#x { color: #0f0 }", rl.Color{G: 255, A: 255}, rl.Color{}},
+		{"", rl.Color{B: 255, A: 255}, rl.Color{}},
+	}
+
+	for _, test := range tests {
+		w.SetStyleSheet(test.css)
+		p, _ := w.document.QuerySelector("#x")
+		h, _ := w.document.QuerySelector("#y")
+		if p.Style.Color != test.p || h.Style.Color != test.h {
+			t.Errorf("%q: p is %v and h2 %v, want %v and %v", test.css, p.Style.Color, h.Style.Color, test.p, test.h)
+		}
+	}
+}
+
+func TestMediaMatches(t *testing.T) {
+	tests := []struct {
+		media       string
+		light, dark bool
+	}{
+		{"", true, true},
+		{"all", true, true},
+		{"screen", true, true},
+		{"print", false, false},
+		{"not print", true, true},
+		{"(prefers-color-scheme: dark)", false, true},
+		{"(prefers-color-scheme: light)", true, false},
+		{"(prefers-color-scheme)", true, true},
+		{"screen and (prefers-color-scheme: dark)", false, true},
+		{"only screen and (PREFERS-COLOR-SCHEME: DARK)", false, true},
+		{"not all and (prefers-color-scheme: dark)", true, false},
+		{"print, (prefers-color-scheme: dark)", false, true},
+		{"(min-width: 600px)", false, false},
+		{"(prefers-color-scheme: dark", false, false},
+	}
+
+	for _, test := range tests {
+		if got := mediaMatches(test.media, ColorSchemeLight); got != test.light {
+			t.Errorf("mediaMatches(%q, light) = %v, want %v", test.media, got, test.light)
+		}
+		if got := mediaMatches(test.media, ColorSchemeDark); got != test.dark {
+			t.Errorf("mediaMatches(%q, dark) = %v, want %v", test.media, got, test.dark)
+		}
+	}
+}
+
+func TestPrefersColorScheme(t *testing.T) {
+	red := rl.Color{R: 255, A: 255}
+	blue := rl.Color{B: 255, A: 255}
+	tests := []struct {
+		name        string
+		html        string
+		light, dark rl.Color
+	}{
+		{"media rule",
+			`<style>p { color: #f00 } @media (prefers-color-scheme: dark) { p { color: #00f } }</style><p id="x">x</p>`,
+			red, blue},
+		{"nested media rules",
+			`<style>@media screen { @media (prefers-color-scheme: light) { p { color: #f00 } } }</style><p id="x">x</p>`,
+			red, rl.Color{}},
+		{"print rule",
+			`<style>p { color: #f00 } @media print { p { color: #00f } }</style><p id="x">x</p>`,
+			red, red},
+		{"style element media",
+			`<style media="(prefers-color-scheme: dark)">p { color: #00f }</style><p id="x">x</p>`,
+			rl.Color{}, blue},
+	}
+
+	for _, test := range tests {
+		if got := computedStyle(t, test.html, "#x", nil, ColorSchemeLight).Color; got != test.light {
+			t.Errorf("%s, light: color %v, want %v", test.name, got, test.light)
+		}
+		if got := computedStyle(t, test.html, "#x", nil, ColorSchemeDark).Color; got != test.dark {
+			t.Errorf("%s, dark: color %v, want %v", test.name, got, test.dark)
+		}
+	}
+}
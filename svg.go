@@ -0,0 +1,179 @@
+package marquee
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// The font families named in SVG output. The layout is measured with the
+// Go fonts, and each run of text is given its measured length, so other
+// fonts only change the shape of the letters, not where they are.
+const (
+	svgSansFamily      = "Go, 'Liberation Sans', Arial, sans-serif"
+	svgMonospaceFamily = "'Go Mono', 'DejaVu Sans Mono', monospace"
+)
+
+// RenderSVG lays document out at width pixels and writes it to w as an SVG
+// image as tall as the content, framed the way the widget shows it. Text
+// keeps its font, size, weight, style and color, backgrounds and borders
+// become rectangles, and links are wrapped in <a> elements so they can be
+// followed when the SVG is shown in a browser.
+func (r *HTMLRenderer) RenderSVG(w io.Writer, document HTMLDocument, width int) error {
+	layout, measurer, height := r.snapshotLayout(document, width)
+
+	out := &svgWriter{w: bufio.NewWriter(w), measurer: measurer}
+	out.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" xml:space="preserve">`+"\n",
+		width, height, width, height)
+
+	bounds := rl.NewRectangle(0, 0, float32(width), float32(height))
+	out.rect(bounds, r.Theme.Colors.Background, r.Theme.Colors.Border, snapshotBorder)
+	out.box(&layout.Root, snapshotInset, snapshotInset)
+
+	out.printf("</svg>\n")
+	if out.err != nil {
+		return out.err
+	}
+	return out.w.Flush()
+}
+
+// RenderToSVG renders document with the default renderer and returns it as
+// SVG; see HTMLRenderer.RenderSVG.
+func RenderToSVG(document HTMLDocument, width int) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := NewHTMLRenderer().RenderSVG(&buffer, document, width); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// svgWriter writes the elements of an SVG document, keeping the first write
+// error.
+type svgWriter struct {
+	w        *bufio.Writer
+	measurer TextMeasurer
+	err      error
+}
+
+func (s *svgWriter) printf(format string, args ...any) {
+	if s.err == nil {
+		_, s.err = fmt.Fprintf(s.w, format, args...)
+	}
+}
+
+// box writes the box and everything in it, moved by offsetX and offsetY, in
+// the order LayoutBox.Paint draws them.
+func (s *svgWriter) box(b *LayoutBox, offsetX, offsetY float32) {
+	bounds := b.Bounds
+	bounds.X += offsetX
+	bounds.Y += offsetY
+
+	borderWidth := b.BorderWidth
+	if b.BorderColor.A == 0 {
+		borderWidth = 0
+	}
+	if b.Background.A != 0 || borderWidth > 0 {
+		s.rect(bounds, b.Background, b.BorderColor, borderWidth)
+	}
+
+	for _, line := range b.Lines {
+		for _, fragment := range line.Fragments {
+			s.fragment(fragment, offsetX, offsetY)
+		}
+	}
+
+	for i := range b.Children {
+		s.box(&b.Children[i], offsetX, offsetY)
+	}
+}
+
+// rect writes a rectangle. The border is drawn inside bounds, as raylib
+// draws it, so the stroke is inset by half its width.
+func (s *svgWriter) rect(bounds rl.Rectangle, fill, stroke rl.Color, strokeWidth float32) {
+	if fill.A != 0 {
+		s.printf(`<rect x="%s" y="%s" width="%s" height="%s"%s/>`+"\n",
+			svgNumber(bounds.X), svgNumber(bounds.Y), svgNumber(bounds.Width), svgNumber(bounds.Height),
+			svgPaint("fill", fill))
+	}
+	if strokeWidth > 0 {
+		inset := strokeWidth / 2
+		s.printf(`<rect x="%s" y="%s" width="%s" height="%s" fill="none" stroke-width="%s"%s/>`+"\n",
+			svgNumber(bounds.X+inset), svgNumber(bounds.Y+inset),
+			svgNumber(bounds.Width-strokeWidth), svgNumber(bounds.Height-strokeWidth),
+			svgNumber(strokeWidth), svgPaint("stroke", stroke))
+	}
+}
+
+// fragment writes a run of text with its background and its underline or
+// line through, inside a link when it belongs to one.
+func (s *svgWriter) fragment(fragment TextFragment, offsetX, offsetY float32) {
+	x := fragment.Bounds.X + offsetX
+	y := fragment.Bounds.Y + offsetY
+	width := fragment.Bounds.Width
+	font := fragment.Font
+
+	if fragment.Background.A != 0 {
+		s.rect(rl.NewRectangle(x, y, width, font.Size), fragment.Background, rl.Color{}, 0)
+	}
+	if fragment.Href != "" {
+		s.printf(`<a href="%s">`, html.EscapeString(fragment.Href))
+	}
+
+	family := svgSansFamily
+	if font.Family == FamilyMonospace {
+		family = svgMonospaceFamily
+	}
+	weight, style := "normal", "normal"
+	if font.Bold {
+		weight = "bold"
+	}
+	if font.Italic {
+		style = "italic"
+	}
+
+	baseline := y + s.measurer.Metrics(font).Ascent
+	s.printf(`<text x="%s" y="%s" font-family="%s" font-size="%s" font-weight="%s" font-style="%s"%s`,
+		svgNumber(x), svgNumber(baseline), family, svgNumber(font.Size), weight, style,
+		svgPaint("fill", fragment.Color))
+	if width > 0 {
+		s.printf(` textLength="%s" lengthAdjust="spacingAndGlyphs"`, svgNumber(width))
+	}
+	s.printf(">%s</text>", html.EscapeString(fragment.Text))
+
+	if fragment.Href != "" || fragment.Underline {
+		s.line(x, y+font.Size, x+width, fragment.Color)
+	}
+	if fragment.LineThrough {
+		s.line(x, y+font.Size*0.55, x+width, fragment.Color)
+	}
+
+	if fragment.Href != "" {
+		s.printf("</a>")
+	}
+	s.printf("\n")
+}
+
+// line writes a horizontal line one pixel wide.
+func (s *svgWriter) line(x1, y, x2 float32, color rl.Color) {
+	s.printf(`<line x1="%s" y1="%s" x2="%s" y2="%s" stroke-width="1"%s/>`,
+		svgNumber(x1), svgNumber(y), svgNumber(x2), svgNumber(y), svgPaint("stroke", color))
+}
+
+// svgPaint returns the attribute setting a fill or stroke to color, with its
+// opacity when it is not opaque.
+func svgPaint(attribute string, color rl.Color) string {
+	paint := fmt.Sprintf(` %s="#%02x%02x%02x"`, attribute, color.R, color.G, color.B)
+	if color.A != 255 {
+		paint += fmt.Sprintf(` %s-opacity="%s"`, attribute, strconv.FormatFloat(float64(color.A)/255, 'f', 3, 64))
+	}
+	return paint
+}
+
+func svgNumber(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
@@ -0,0 +1,73 @@
+package marquee
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRenderSVG(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{"text", `<p>plain</p>`, []string{
+			`font-family="Go, 'Liberation Sans', Arial, sans-serif"`,
+			`font-size="16" font-weight="normal" font-style="normal" fill="#000000"`,
+			`>plain</text>`,
+		}},
+		{"bold", `<p><b>bold</b></p>`, []string{`font-weight="bold"`, `>bold</text>`}},
+		{"italic", `<p><i>italic</i></p>`, []string{`font-style="italic"`, `>italic</text>`}},
+		{"escaped text", `<p>a &lt;b&gt; &amp; c</p>`, []string{`>&lt;b&gt;</text>`, `>&amp;</text>`}},
+		{"link", `<p><a href="/x?a=1&amp;b=2">link</a></p>`, []string{
+			`<a href="/x?a=1&amp;b=2"><text`,
+			`>link</text><line`,
+			`</a>`,
+		}},
+		{"preformatted", `<pre>code</pre>`, []string{
+			`fill="#f8f8f8"/>`,
+			`stroke="#dcdcdc"/>`,
+			`font-family="'Go Mono', 'DejaVu Sans Mono', monospace"`,
+		}},
+		{"background", `<div style="background: #fff3c4; border: 2px solid #e0a800"><p>note</p></div>`, []string{
+			`fill="#fff3c4"/>`,
+			`stroke-width="2" stroke="#e0a800"/>`,
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := NewHTMLRenderer().RenderSVG(&b, parse(t, test.html), 300); err != nil {
+				t.Fatal(err)
+			}
+			got := b.String()
+			if !strings.HasPrefix(got, `<svg xmlns="http://www.w3.org/2000/svg" width="300"`) {
+				t.Errorf("output does not start with an svg element:\n%s", got)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("output lacks %s:\n%s", want, got)
+				}
+			}
+			if err := checkXML(got); err != nil {
+				t.Errorf("output is not well-formed: %v\n%s", err, got)
+			}
+		})
+	}
+}
+
+// checkXML reads s to the end as XML and returns the first syntax error.
+func checkXML(s string) error {
+	decoder := xml.NewDecoder(strings.NewReader(s))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
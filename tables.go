@@ -14,6 +14,11 @@ func (h *TableRenderHandler) CanRender(node HTMLNode) bool {
 		   node.Tag == "tr" || node.Tag == "th" || node.Tag == "td"
 }
 
+func (h *TableRenderHandler) Margins(node HTMLNode, ctx RenderContext) VerticalMargins {
+	margin := ctx.Renderer.Theme.Spacing.TableMargin
+	return VerticalMargins{Top: margin, Bottom: margin}
+}
+
 func (h *TableRenderHandler) Render(node HTMLNode, ctx RenderContext) RenderResult {
 	switch node.Tag {
 	case "table":
@@ -82,8 +87,16 @@ func (h *TableRenderHandler) renderTable(node HTMLNode, ctx RenderContext) Rende
 	h.calculateColumnWidths(&table, ctx)
 	h.calculateRowHeights(&table, ctx)
 
-	// Phase 3: Render the table
-	return h.renderTableContent(&table, ctx)
+	// Phase 3: Render the table, leaving the background and border to the
+	// node's style when it sets them
+	result := h.renderTableContent(&table, ctx)
+	if node.Style.Background.A != 0 {
+		result.Box.Background = rl.Color{}
+	}
+	if node.Style.Border.Width.IsSet() {
+		result.Box.BorderWidth = 0
+	}
+	return result
 }
 
 // Phase 1: Parse table structure into our data model
@@ -95,6 +108,9 @@ func (h *TableRenderHandler) parseTableStructure(node HTMLNode) Table {
 
 	// Process thead, tbody, or direct tr children
 	for _, child := range node.Children {
+		if child.Style.Hidden {
+			continue
+		}
 		switch child.Tag {
 		case "thead":
 			rows := h.parseTableSection(child, true)
@@ -125,7 +141,7 @@ func (h *TableRenderHandler) parseTableStructure(node HTMLNode) Table {
 func (h *TableRenderHandler) parseTableSection(section HTMLNode, isHeader bool) []TableRow {
 	var rows []TableRow
 	for _, child := range section.Children {
-		if child.Tag == "tr" {
+		if child.Tag == "tr" && !child.Style.Hidden {
 			row := h.parseTableRow(child, isHeader)
 			rows = append(rows, row)
 		}
@@ -140,7 +156,7 @@ func (h *TableRenderHandler) parseTableRow(tr HTMLNode, isHeader bool) TableRow
 	}
 
 	for _, child := range tr.Children {
-		if child.Tag == "th" || child.Tag == "td" {
+		if (child.Tag == "th" || child.Tag == "td") && !child.Style.Hidden {
 			cell := TableCell{
 				Content:   []HTMLNode{child}, // Wrap in slice for future nested content support
 				ColSpan:   1,                 // Future: parse colspan attribute
@@ -183,27 +199,22 @@ func (h *TableRenderHandler) measureCellContent(cell *TableCell, ctx RenderConte
 		return
 	}
 
-	font := ctx.Widget.Fonts.Regular
+	font := ctx.Renderer.Theme.Fonts.Regular
 	if cell.IsHeader {
-		font = ctx.Widget.Fonts.Bold
-	}
-
-	fontSize := float32(font.BaseSize)
-	if fontSize == 0 {
-		fontSize = 16
+		font = ctx.Renderer.Theme.Fonts.Bold
 	}
 
 	// Measure text dimensions
-	textSize := ctx.Widget.measureText(font, text, fontSize)
+	textSize := rl.NewVector2(ctx.Renderer.MeasureText(font, text), font.Size)
 	
 	// Add padding
-	padding := float32(12)
+	padding := ctx.Renderer.Theme.Spacing.CellPadding
 	cell.MinWidth = textSize.X + 2*padding
 	cell.PrefWidth = cell.MinWidth
 	
 	// For long text, allow wrapping by setting a reasonable preferred width
 	if len(text) > 20 {
-		words := strings.Fields(text)
+		words := splitHTMLWords(text)
 		if len(words) > 3 {
 			// Estimate width for ~3 words per line
 			avgWordWidth := textSize.X / float32(len(words))
@@ -244,8 +255,7 @@ func (h *TableRenderHandler) calculateColumnWidths(table *Table, ctx RenderConte
 	// Calculate available width (account for margins and borders)
 	borderWidth := float32(1)
 	totalBorderWidth := float32(table.ColumnCount+1) * borderWidth
-	rightMargin := ctx.Widget.BodyMargin + ctx.Widget.BodyPadding
-	availableWidth := ctx.Width - rightMargin - totalBorderWidth
+	availableWidth := ctx.Width - ctx.RightMargin - totalBorderWidth
 
 	// Collect minimum and preferred widths for each column
 	minWidths := make([]float32, table.ColumnCount)
@@ -339,82 +349,48 @@ func (h *TableRenderHandler) calculateRowHeights(table *Table, ctx RenderContext
 }
 
 func (h *TableRenderHandler) calculateCellHeight(cell *TableCell, ctx RenderContext) float32 {
-	if len(cell.Content) == 0 {
+	lines := h.renderCellContent(*cell, 0, 0, ctx)
+	if len(lines) == 0 {
 		return 30 // Minimum cell height
 	}
 
-	text := h.extractCellText(cell.Content[0])
-	if text == "" {
-		return 30
-	}
-
-	font := ctx.Widget.Fonts.Regular
-	if cell.IsHeader {
-		font = ctx.Widget.Fonts.Bold
-	}
-
-	fontSize := float32(font.BaseSize)
-	if fontSize == 0 {
-		fontSize = 16
-	}
-
-	// Calculate height needed for text wrapping
-	padding := float32(12)
-	contentWidth := cell.Width - 2*padding
-	if contentWidth <= 0 {
-		contentWidth = 100 // Fallback
-	}
-
-	lineHeight := fontSize * 1.2
-	
-	// Simple word wrapping calculation
-	words := strings.Fields(text)
-	if len(words) == 0 {
-		return lineHeight + 2*padding
-	}
-
-	currentLineWidth := float32(0)
-	lineCount := 1
-
-	for _, word := range words {
-		wordWidth := ctx.Widget.measureTextWidth(font, word+" ", fontSize)
-		if currentLineWidth + wordWidth > contentWidth && currentLineWidth > 0 {
-			lineCount++
-			currentLineWidth = wordWidth
-		} else {
-			currentLineWidth += wordWidth
-		}
-	}
-
-	return float32(lineCount)*lineHeight + 2*padding
+	padding := ctx.Renderer.Theme.Spacing.CellPadding
+	return linesBottom(lines, 0) + 2*padding
 }
 
-// Phase 3: Render the complete table
+// Phase 3: Lay out the complete table
 func (h *TableRenderHandler) renderTableContent(table *Table, ctx RenderContext) RenderResult {
 	result := RenderResult{NextY: ctx.Y}
+	colors := ctx.Renderer.Theme.Colors.Table
 	
-	currentY := ctx.Y + 10 // Top margin
+	currentY := ctx.Y
 	
-	// Draw table border and background
-	tableRect := rl.NewRectangle(ctx.X, currentY, table.TotalWidth, table.TotalHeight)
-	rl.DrawRectangleRec(tableRect, rl.White)
-	rl.DrawRectangleLinesEx(tableRect, 1, rl.Color{R: 200, G: 200, B: 200, A: 255})
+	// Table border and background
+	result.Box = LayoutBox{
+		Tag:         "table",
+		Bounds:      rl.NewRectangle(ctx.X, currentY, table.TotalWidth, table.TotalHeight),
+		Background:  colors.Background,
+		BorderColor: colors.Border,
+		BorderWidth: 1,
+	}
 
-	// Render each row
+	// Lay out each row
 	for rowIdx, row := range table.Rows {
-		h.renderTableRow(table, rowIdx, row, ctx.X, currentY, ctx)
+		rowBox := h.renderTableRow(table, rowIdx, row, ctx.X, currentY, ctx)
+		result.Box.Children = append(result.Box.Children, rowBox)
 		currentY += table.RowHeights[rowIdx] + 1 // +1 for border
-		
-		// Collect any link areas (future enhancement)
-		// result.LinkAreas = append(result.LinkAreas, rowLinkAreas...)
 	}
 
-	result.NextY = currentY + 10 // Bottom margin
+	result.NextY = currentY
 	result.Height = result.NextY - ctx.Y
 	return result
 }
 
-func (h *TableRenderHandler) renderTableRow(table *Table, rowIdx int, row TableRow, startX, startY float32, ctx RenderContext) {
+func (h *TableRenderHandler) renderTableRow(table *Table, rowIdx int, row TableRow, startX, startY float32, ctx RenderContext) LayoutBox {
+	rowBox := LayoutBox{
+		Tag:    "tr",
+		Bounds: rl.NewRectangle(startX, startY, table.TotalWidth, table.RowHeights[rowIdx]+1),
+	}
 	currentX := startX + 1 // Start inside left border
 	
 	for cellIdx, cell := range row.Cells {
@@ -425,91 +401,72 @@ func (h *TableRenderHandler) renderTableRow(table *Table, rowIdx int, row TableR
 		cellWidth := table.ColumnWidths[cellIdx]
 		cellHeight := table.RowHeights[rowIdx]
 		
-		// Draw cell background (different for headers)
-		cellRect := rl.NewRectangle(currentX, startY+1, cellWidth, cellHeight)
+		// Cell border, with a background for headers
+		colors := ctx.Renderer.Theme.Colors.Cell
+		tag := "td"
 		if cell.IsHeader {
-			rl.DrawRectangleRec(cellRect, rl.Color{R: 248, G: 249, B: 250, A: 255})
+			colors = ctx.Renderer.Theme.Colors.Header
+			tag = "th"
+		}
+		cellBox := LayoutBox{
+			Tag:         tag,
+			Bounds:      rl.NewRectangle(currentX, startY+1, cellWidth, cellHeight),
+			Background:  colors.Background,
+			BorderColor: colors.Border,
+			BorderWidth: 1,
+		}
+		if len(cell.Content) > 0 {
+			style := cell.Content[0].Style
+			if style.Background.A != 0 {
+				cellBox.Background = style.Background
+			}
+			if style.Border.Width.IsSet() {
+				cellBox.BorderColor = ctx.Renderer.borderColor(style)
+				cellBox.BorderWidth = style.Border.Width.resolve(cellWidth, 0)
+			}
 		}
 		
-		// Draw cell border
-		rl.DrawRectangleLinesEx(cellRect, 1, rl.Color{R: 220, G: 220, B: 220, A: 255})
-		
-		// Render cell content
-		h.renderCellContent(cell, currentX, startY+1, ctx)
+		// Lay out cell content
+		cellBox.Lines = h.renderCellContent(cell, currentX, startY+1, ctx)
+		rowBox.Children = append(rowBox.Children, cellBox)
 		
 		currentX += cellWidth + 1 // +1 for border
 	}
+
+	return rowBox
 }
 
-func (h *TableRenderHandler) renderCellContent(cell TableCell, x, y float32, ctx RenderContext) {
+func (h *TableRenderHandler) renderCellContent(cell TableCell, x, y float32, ctx RenderContext) []LineBox {
 	if len(cell.Content) == 0 {
-		return
+		return nil
 	}
 
 	text := h.extractCellText(cell.Content[0])
 	if text == "" {
-		return
+		return nil
 	}
 
-	font := ctx.Widget.Fonts.Regular
-	color := rl.Black
+	font := ctx.Renderer.Theme.Fonts.Regular
+	color := ctx.Renderer.Theme.Colors.Cell.Text
 	if cell.IsHeader {
-		font = ctx.Widget.Fonts.Bold
-		color = rl.Color{R: 52, G: 58, B: 64, A: 255} // Slightly darker for headers
+		font = ctx.Renderer.Theme.Fonts.Bold
+		color = ctx.Renderer.Theme.Colors.Header.Text
 	}
-
-	fontSize := float32(font.BaseSize)
-	if fontSize == 0 {
-		fontSize = 16
+	style := cell.Content[0].Style
+	if style.HasColor() {
+		color = style.Color
 	}
 
-	// Render text with padding and wrapping
-	padding := float32(12)
+	// Text with padding and wrapping, placed as the cell's text-align says
+	padding := ctx.Renderer.Theme.Spacing.CellPadding
 	contentX := x + padding
 	contentY := y + padding
 	contentWidth := cell.Width - 2*padding
-	
-	// Simple text rendering with basic wrapping
-	h.renderWrappedText(text, contentX, contentY, contentWidth, font, color, ctx)
-}
-
-func (h *TableRenderHandler) renderWrappedText(text string, x, y, width float32, font rl.Font, color rl.Color, ctx RenderContext) {
-	fontSize := float32(font.BaseSize)
-	if fontSize == 0 {
-		fontSize = 16
-	}
-	
-	lineHeight := fontSize * 1.2
-	currentY := y
-	
-	words := strings.Fields(text)
-	if len(words) == 0 {
-		return
+	if contentWidth <= 0 {
+		contentWidth = 100 // Fallback
 	}
 
-	currentLine := ""
-	
-	for _, word := range words {
-		testLine := currentLine
-		if testLine != "" {
-			testLine += " "
-		}
-		testLine += word
-		
-		lineWidth := ctx.Widget.measureTextWidth(font, testLine, fontSize)
-		
-		if lineWidth > width && currentLine != "" {
-			// Render current line and start new one
-			ctx.Widget.renderTextWithUnicode(currentLine, x, currentY, font, color)
-			currentY += lineHeight
-			currentLine = word
-		} else {
-			currentLine = testLine
-		}
-	}
-	
-	// Render final line
-	if currentLine != "" {
-		ctx.Widget.renderTextWithUnicode(currentLine, x, currentY, font, color)
-	}
-}
\ No newline at end of file
+	segments := []inlineSegment{{text: text, font: font, color: color}}
+	lineHeight := ctx.Renderer.lineHeight(font)
+	return ctx.Renderer.layoutInlineLines(segments, contentX, contentY, contentWidth, lineHeight, style.TextAlign)
+}
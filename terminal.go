@@ -0,0 +1,786 @@
+package marquee
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TerminalOptions controls how RenderTerminal writes a document.
+type TerminalOptions struct {
+	// Width is the number of columns text is wrapped to. Zero means 80.
+	Width int
+
+	// Plain leaves out every escape sequence, for terminals and pipes that
+	// cannot show them. Links are then followed by their URL in angle
+	// brackets, inline code is set in backticks and the top two levels of
+	// heading are underlined with "=" and "-".
+	Plain bool
+
+	// StyleSheet holds style rules applied before those of the document's
+	// <style> elements. It may be nil.
+	StyleSheet *StyleSheet
+}
+
+// DefaultTerminalOptions returns the options for the terminal the program
+// runs in: the width in $COLUMNS, or 80, and plain text when NO_COLOR is set
+// or TERM is "dumb". Programs that can ask the terminal for its size should
+// set Width themselves.
+func DefaultTerminalOptions() TerminalOptions {
+	options := TerminalOptions{
+		Plain: os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb",
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		options.Width = columns
+	}
+	return options
+}
+
+func (o TerminalOptions) withDefaults() TerminalOptions {
+	if o.Width <= 0 {
+		o.Width = 80
+	}
+	return o
+}
+
+// minTerminalColumns is the narrowest text is wrapped to, however deeply it
+// is indented.
+const minTerminalColumns = 20
+
+// RenderTerminal writes document to w as text for a terminal, wrapped to
+// options.Width columns. Headings and bold text are bold, italic text is
+// italic, links are underlined and can be clicked in terminals that support
+// OSC 8 hyperlinks, lists are indented with bullets or numbers, pre blocks
+// are set off by a rule at the left and tables are drawn with box-drawing
+// characters. Colors set in the document are not used, since they are
+// chosen for the widget's white page and not the terminal's background.
+func RenderTerminal(w io.Writer, document HTMLDocument, options TerminalOptions) error {
+	document = styledCopy(document, options.StyleSheet, ColorSchemeLight)
+
+	t := &terminalWriter{
+		out:      bufio.NewWriter(w),
+		options:  options.withDefaults(),
+		renderer: &HTMLRenderer{Measurer: terminalCells{}},
+	}
+	t.blocks(document.Root, &linePrefix{})
+
+	if t.err != nil {
+		return t.err
+	}
+	return t.out.Flush()
+}
+
+// RenderToTerminal renders document for a terminal and returns the text;
+// see RenderTerminal.
+func RenderToTerminal(document HTMLDocument, options TerminalOptions) string {
+	var text strings.Builder
+	RenderTerminal(&text, document, options)
+	return text.String()
+}
+
+// terminalCells is a TextMeasurer that measures text in terminal columns,
+// so the inline wrapping used by the layout pass wraps terminal text too.
+type terminalCells struct{}
+
+func (terminalCells) MeasureText(font Font, text string) float32 {
+	return float32(displayWidth(text))
+}
+
+func (terminalCells) Metrics(font Font) FontMetrics {
+	return FontMetrics{Ascent: 1}
+}
+
+// displayWidth returns the number of columns text takes up in a terminal.
+// East Asian wide characters and most emoji take two, combining marks and
+// format characters none.
+func displayWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r >= 0x7f && r < 0xa0:
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
+
+// linePrefix is what goes in front of each line of a block: the indent of
+// a list item or definition, the rule beside a quote or pre block. The
+// first line of a list item carries its marker, so a prefix has a first
+// form and a form for the lines after it, and it is nested inside the
+// prefix of the block around it.
+type linePrefix struct {
+	parent      *linePrefix
+	first, rest string
+	sgr         string
+	started     bool
+}
+
+// nest returns a prefix inside p that adds first to the first line and rest
+// to the others, shown with the SGR parameters sgr.
+func (p *linePrefix) nest(first, rest, sgr string) *linePrefix {
+	return &linePrefix{parent: p, first: first, rest: rest, sgr: sgr}
+}
+
+// next returns the prefix for the next line and marks the first as used,
+// here and in every prefix around it.
+func (p *linePrefix) next(plain bool) string {
+	text := p.text(plain, false)
+	for q := p; q != nil; q = q.parent {
+		q.started = true
+	}
+	return text
+}
+
+// text returns the prefix, as it is for the next line or, with blank set,
+// as it is for a blank line between blocks, which carries no markers.
+func (p *linePrefix) text(plain, blank bool) string {
+	if p == nil {
+		return ""
+	}
+	part := p.rest
+	if !p.started && !blank {
+		part = p.first
+	}
+	if part != "" && p.sgr != "" && !plain {
+		part = sgr(p.sgr) + part + sgr("")
+	}
+	return p.parent.text(plain, blank) + part
+}
+
+// width returns the number of columns the prefix takes up.
+func (p *linePrefix) width() int {
+	if p == nil {
+		return 0
+	}
+	return p.parent.width() + displayWidth(p.rest)
+}
+
+// sgr returns the escape sequence that resets the text attributes and then
+// sets params.
+func sgr(params string) string {
+	if params == "" {
+		return "\x1b[0m"
+	}
+	return "\x1b[0;" + params + "m"
+}
+
+// terminalWriter writes a document as terminal text, one block at a time.
+// Blocks are separated by blank lines, except the items of a list.
+type terminalWriter struct {
+	out      *bufio.Writer
+	err      error
+	options  TerminalOptions
+	renderer *HTMLRenderer
+
+	wrote     bool
+	gap       bool
+	gapPrefix string
+	tight     int
+	listDepth int
+}
+
+func (t *terminalWriter) printf(format string, args ...any) {
+	if t.err == nil {
+		_, t.err = fmt.Fprintf(t.out, format, args...)
+	}
+}
+
+// line writes a line of text after prefix, with the blank line owed since
+// the last block first.
+func (t *terminalWriter) line(prefix *linePrefix, text string) {
+	if t.gap && t.wrote {
+		t.printf("%s\n", trimPrefix(t.gapPrefix))
+	}
+	t.gap = false
+	t.wrote = true
+	t.printf("%s%s\n", prefix.next(t.options.Plain), text)
+}
+
+// trimPrefix removes the spaces at the end of a prefix on a blank line,
+// keeping the escape sequence that ends its style.
+func trimPrefix(prefix string) string {
+	if reset := sgr(""); strings.HasSuffix(prefix, reset) {
+		return strings.TrimRight(strings.TrimSuffix(prefix, reset), " ") + reset
+	}
+	return strings.TrimRight(prefix, " ")
+}
+
+// separate asks for a blank line before whatever is written next, unless
+// the block is inside a list. The blank line carries the shortest prefix
+// asked for, so it belongs to the outermost block it separates.
+func (t *terminalWriter) separate(prefix *linePrefix) {
+	if t.tight > 0 {
+		return
+	}
+	blank := prefix.text(t.options.Plain, true)
+	if !t.gap || len(blank) < len(t.gapPrefix) {
+		t.gapPrefix = blank
+	}
+	t.gap = true
+}
+
+// columns returns the width text after prefix is wrapped to.
+func (t *terminalWriter) columns(prefix *linePrefix) int {
+	return max(t.options.Width-prefix.width(), minTerminalColumns)
+}
+
+// blocks writes the children of node. Runs of text and inline elements
+// between block-level children are written as paragraphs.
+func (t *terminalWriter) blocks(node HTMLNode, prefix *linePrefix) {
+	var run []inlineSegment
+	for _, child := range node.Children {
+		if child.Type == NodeTypeElement && child.Style.Hidden {
+			continue
+		}
+		if isBlockLevel(child) {
+			t.paragraph(run, prefix)
+			run = nil
+			t.block(child, prefix)
+			continue
+		}
+		run = t.inline(child, "", run)
+	}
+	t.paragraph(run, prefix)
+}
+
+// block writes a block-level element.
+func (t *terminalWriter) block(node HTMLNode, prefix *linePrefix) {
+	if isMetadataElement(node.Tag) {
+		return
+	}
+
+	switch node.Tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		t.heading(node, prefix)
+	case "ul", "ol":
+		t.list(node, prefix)
+	case "li":
+		t.separate(prefix)
+		t.listItem(node, prefix.nest("• ", "  ", ""))
+		t.separate(prefix)
+	case "pre", "listing", "xmp":
+		t.preformatted(nodeText(node), prefix)
+	case "hr":
+		t.separate(prefix)
+		t.line(prefix, t.styled(strings.Repeat("─", t.columns(prefix)), "2"))
+		t.separate(prefix)
+	case "table":
+		t.table(node, prefix)
+	case "dt":
+		t.paragraph(t.bold(t.inline(node, "", nil)), prefix)
+	case "dd":
+		t.blocks(node, prefix.nest("    ", "    ", ""))
+	case "dl":
+		t.separate(prefix)
+		t.tight++
+		t.blocks(node, prefix)
+		t.tight--
+		t.separate(prefix)
+	case "blockquote":
+		t.separate(prefix)
+		if t.options.Plain {
+			t.blocks(node, prefix.nest("> ", "> ", ""))
+		} else {
+			t.blocks(node, prefix.nest("│ ", "│ ", "2"))
+		}
+		t.separate(prefix)
+	case "div":
+		if (&CalloutBoxRenderHandler{}).CanRender(node) {
+			t.callout(node, prefix)
+		} else {
+			t.blocks(node, prefix)
+		}
+	default:
+		t.blocks(node, prefix)
+	}
+}
+
+// paragraph wraps segments to the width left after prefix and writes them.
+// A run of nothing but whitespace writes nothing.
+func (t *terminalWriter) paragraph(segments []inlineSegment, prefix *linePrefix) {
+	if len(tokenizeInlineSegments(segments)) == 0 {
+		return
+	}
+
+	t.separate(prefix)
+	for _, line := range t.renderer.wrapInlineSegments(segments, float32(t.columns(prefix))) {
+		t.line(prefix, t.segments(line.segments))
+	}
+	t.separate(prefix)
+}
+
+// heading writes a heading in bold, wrapped like a paragraph. The top level
+// is underlined as well; in plain text the top two levels are underlined
+// with a line of "=" or "-".
+func (t *terminalWriter) heading(node HTMLNode, prefix *linePrefix) {
+	segments := t.bold(t.inline(node, "", nil))
+	if len(tokenizeInlineSegments(segments)) == 0 {
+		return
+	}
+	if node.Tag == "h1" && !t.options.Plain {
+		for i := range segments {
+			segments[i].underline = true
+		}
+	}
+
+	t.separate(prefix)
+	width := 0
+	for _, line := range t.renderer.wrapInlineSegments(segments, float32(t.columns(prefix))) {
+		t.line(prefix, t.segments(line.segments))
+		width = max(width, int(line.width))
+	}
+	if t.options.Plain && (node.Tag == "h1" || node.Tag == "h2") {
+		rule := "="
+		if node.Tag == "h2" {
+			rule = "-"
+		}
+		t.line(prefix, strings.Repeat(rule, width))
+	}
+	t.separate(prefix)
+}
+
+// bullets are the list markers of unordered lists, by nesting depth.
+var bullets = []string{"•", "◦", "▪"}
+
+// list writes the items of a list, without blank lines between them. Items
+// are indented by their marker, and numbers are right-aligned.
+func (t *terminalWriter) list(node HTMLNode, prefix *linePrefix) {
+	var items []HTMLNode
+	for _, child := range node.Children {
+		if child.Tag == "li" && !child.Style.Hidden {
+			items = append(items, child)
+		}
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	t.separate(prefix)
+	t.tight++
+	t.listDepth++
+
+	digits := len(strconv.Itoa(len(items)))
+	for i, item := range items {
+		marker := bullets[(t.listDepth-1)%len(bullets)] + " "
+		if node.Tag == "ol" {
+			marker = fmt.Sprintf("%*d. ", digits, i+1)
+		}
+		t.listItem(item, prefix.nest(marker, strings.Repeat(" ", displayWidth(marker)), ""))
+	}
+
+	t.listDepth--
+	t.tight--
+	t.separate(prefix)
+}
+
+// listItem writes the content of a list item after its marker, which is
+// written on its own when the item is empty.
+func (t *terminalWriter) listItem(item HTMLNode, prefix *linePrefix) {
+	t.blocks(item, prefix)
+	if !prefix.started {
+		t.line(prefix, "")
+	}
+}
+
+// preformatted writes the lines of a pre block as they are, indented and
+// with a rule at their left. Lines wider than the terminal are broken
+// rather than wrapped at spaces.
+func (t *terminalWriter) preformatted(content string, prefix *linePrefix) {
+	if content == "" {
+		return
+	}
+
+	gutter := prefix.nest("  │ ", "  │ ", "2")
+	if t.options.Plain {
+		gutter = prefix.nest("    ", "    ", "")
+	}
+	columns := t.columns(gutter)
+
+	t.separate(prefix)
+	for _, line := range preformattedLines(content) {
+		for {
+			head, tail := splitColumns(line, columns)
+			t.line(gutter, head)
+			if tail == "" {
+				break
+			}
+			line = tail
+		}
+	}
+	t.separate(prefix)
+}
+
+// splitColumns splits text after the last character that fits in columns.
+// At least one character always goes in the head.
+func splitColumns(text string, columns int) (head, tail string) {
+	width := 0
+	for i, r := range text {
+		width += runeWidth(r)
+		if width > columns && i > 0 {
+			return text[:i], text[i:]
+		}
+	}
+	return text, ""
+}
+
+// callout writes a callout box as its label and content, with a colored
+// rule at their left.
+func (t *terminalWriter) callout(node HTMLNode, prefix *linePrefix) {
+	calloutType := (&CalloutBoxRenderHandler{}).getCalloutType(node.Attributes["class"])
+	color := map[string]string{
+		"warning": "33", "danger": "31", "success": "32", "tip": "32", "info": "34",
+	}[calloutType]
+	if color == "" {
+		color = "2"
+	}
+
+	inner := prefix.nest("▌ ", "▌ ", color)
+	if t.options.Plain {
+		inner = prefix.nest("| ", "| ", "")
+	}
+
+	label := strings.ToUpper(calloutType[:1]) + calloutType[1:]
+	t.separate(prefix)
+	t.line(inner, t.styled(label, "1;"+color))
+	t.tight++
+	t.blocks(node, inner)
+	t.tight--
+	t.separate(prefix)
+}
+
+// inline appends the text of node and the inline elements in it to
+// segments, with its computed style. href is the link the node is in.
+func (t *terminalWriter) inline(node HTMLNode, href string, segments []inlineSegment) []inlineSegment {
+	switch {
+	case node.Type == NodeTypeText:
+		font := Font{Bold: node.Style.Bold, Italic: node.Style.Italic}
+		if node.Style.Monospace {
+			font.Family = FamilyMonospace
+		}
+		return append(segments, inlineSegment{
+			text:        node.Content,
+			font:        font,
+			href:        href,
+			underline:   node.Style.Underline,
+			lineThrough: node.Style.LineThrough,
+		})
+	case node.Type != NodeTypeElement || isMetadataElement(node.Tag) || node.Style.Hidden:
+		return segments
+	case node.Tag == "br":
+		return append(segments, inlineSegment{lineBreak: true})
+	case node.Tag == "img":
+		if alt := node.Attributes["alt"]; alt != "" {
+			segments = append(segments, inlineSegment{text: "[" + alt + "]", href: href})
+		}
+		return segments
+	}
+
+	if node.Tag == "a" && node.Attributes["href"] != "" {
+		href = node.Attributes["href"]
+	}
+
+	code := node.Tag == "code" && t.options.Plain
+	if code {
+		segments = append(segments, inlineSegment{text: "`"})
+	}
+	for i, child := range node.Children {
+		// Block-level content inside inline content starts a new line.
+		if isBlockLevel(child) && i > 0 {
+			segments = append(segments, inlineSegment{lineBreak: true})
+		}
+		segments = t.inline(child, href, segments)
+	}
+	if code {
+		segments = append(segments, inlineSegment{text: "`"})
+	}
+
+	// Plain text cannot be clicked, so external links show where they go.
+	if node.Tag == "a" && t.options.Plain && isExternalLink(href) && strings.TrimSpace(nodeText(node)) != href {
+		segments = append(segments, inlineSegment{text: " <" + href + ">"})
+	}
+	return segments
+}
+
+func isExternalLink(href string) bool {
+	return href != "" && !strings.HasPrefix(href, "#")
+}
+
+// bold returns segments set in bold.
+func (t *terminalWriter) bold(segments []inlineSegment) []inlineSegment {
+	for i := range segments {
+		segments[i].font.Bold = true
+	}
+	return segments
+}
+
+// segmentSGR returns the SGR parameters for the style of a segment. Links
+// are blue and underlined and inline code is cyan.
+func segmentSGR(segment inlineSegment) string {
+	var params []string
+	if segment.font.Bold {
+		params = append(params, "1")
+	}
+	if segment.font.Italic {
+		params = append(params, "3")
+	}
+	if segment.underline || segment.href != "" {
+		params = append(params, "4")
+	}
+	if segment.lineThrough {
+		params = append(params, "9")
+	}
+	switch {
+	case segment.href != "":
+		params = append(params, "34")
+	case segment.font.Family == FamilyMonospace:
+		params = append(params, "36")
+	}
+	return strings.Join(params, ";")
+}
+
+// segments returns a wrapped line of segments as text, with the escape
+// sequences that style it and make its links clickable.
+func (t *terminalWriter) segments(segments []inlineSegment) string {
+	var text strings.Builder
+	if t.options.Plain {
+		for _, segment := range segments {
+			text.WriteString(segment.text)
+		}
+		return text.String()
+	}
+
+	style, link := "", ""
+	for i, segment := range segments {
+		// The spaces the wrapping puts between words belong to the words on
+		// either side when those are styled and linked alike, and are plain
+		// otherwise, so underlines do not run on past a link.
+		if segment.text == " " && segment.href == "" {
+			plain := inlineSegment{text: " "}
+			if i > 0 && i+1 < len(segments) && sameTerminalStyle(segments[i-1], segments[i+1]) {
+				plain = segments[i+1]
+				plain.text = " "
+			}
+			segment = plain
+		}
+
+		if segmentStyle := segmentSGR(segment); segmentStyle != style {
+			text.WriteString(sgr(segmentStyle))
+			style = segmentStyle
+		}
+		if target := segment.href; target != link && (isExternalLink(target) || link != "") {
+			if !isExternalLink(target) {
+				target = ""
+			}
+			text.WriteString(hyperlink(target))
+			link = target
+		}
+		text.WriteString(segment.text)
+	}
+
+	if link != "" {
+		text.WriteString(hyperlink(""))
+	}
+	if style != "" {
+		text.WriteString(sgr(""))
+	}
+	return text.String()
+}
+
+func sameTerminalStyle(a, b inlineSegment) bool {
+	return a.href == b.href && segmentSGR(a) == segmentSGR(b)
+}
+
+// styled returns text shown with the SGR parameters params, or as it is in
+// plain text.
+func (t *terminalWriter) styled(text, params string) string {
+	if t.options.Plain {
+		return text
+	}
+	return sgr(params) + text + sgr("")
+}
+
+// hyperlink returns the OSC 8 sequence that starts a link to url, or ends
+// the current link when url is empty.
+func hyperlink(url string) string {
+	return "\x1b]8;;" + url + "\x1b\\"
+}
+
+// table writes a table drawn with box-drawing characters. Columns get the
+// width of their widest cell if the table fits the terminal; otherwise the
+// widest columns are narrowed and their cells wrapped.
+func (t *terminalWriter) table(node HTMLNode, prefix *linePrefix) {
+	parsed := (&TableRenderHandler{}).parseTableStructure(node)
+	if len(parsed.Rows) == 0 || parsed.ColumnCount == 0 {
+		return
+	}
+
+	columns := parsed.ColumnCount
+	cells := make([][][]inlineSegment, len(parsed.Rows))
+	widths := make([]int, columns)
+	minimums := make([]int, columns)
+	for i, row := range parsed.Rows {
+		cells[i] = make([][]inlineSegment, columns)
+		for j, cell := range row.Cells {
+			segments := t.inline(cell.Content[0], "", nil)
+			if cell.IsHeader {
+				segments = t.bold(segments)
+			}
+			cells[i][j] = segments
+			widths[j] = max(widths[j], t.wrappedWidth(segments, 1<<20))
+			minimums[j] = max(minimums[j], t.wrappedWidth(segments, 1))
+		}
+	}
+
+	// Each column has a border to its left and a space either side, and
+	// the last a border to its right as well.
+	available := t.options.Width - prefix.width() - 3*columns - 1
+	fitColumns(widths, minimums, available)
+
+	border := func(left, middle, right string) string {
+		parts := make([]string, columns)
+		for j, width := range widths {
+			parts[j] = strings.Repeat("─", width+2)
+		}
+		return left + strings.Join(parts, middle) + right
+	}
+
+	t.separate(prefix)
+	t.line(prefix, border("┌", "┬", "┐"))
+	for i, row := range parsed.Rows {
+		t.tableRow(cells[i], widths, prefix)
+
+		// A rule separates the header rows from the body.
+		if row.IsHeader || isHeaderCells(row.Cells) {
+			if i+1 < len(parsed.Rows) && !parsed.Rows[i+1].IsHeader && !isHeaderCells(parsed.Rows[i+1].Cells) {
+				t.line(prefix, border("├", "┼", "┤"))
+			}
+		}
+	}
+	t.line(prefix, border("└", "┴", "┘"))
+	t.separate(prefix)
+}
+
+// tableRow writes one row of a table, as many lines tall as its tallest
+// cell.
+func (t *terminalWriter) tableRow(cells [][]inlineSegment, widths []int, prefix *linePrefix) {
+	lines := make([][]inlineLine, len(cells))
+	height := 1
+	for j, segments := range cells {
+		lines[j] = t.renderer.wrapInlineSegments(segments, float32(widths[j]))
+		height = max(height, len(lines[j]))
+	}
+
+	for k := 0; k < height; k++ {
+		var text strings.Builder
+		for j := range cells {
+			text.WriteString("│ ")
+			width := 0
+			if k < len(lines[j]) {
+				segments := clipSegments(lines[j][k].segments, widths[j])
+				text.WriteString(t.segments(segments))
+				for _, segment := range segments {
+					width += displayWidth(segment.text)
+				}
+			}
+			text.WriteString(strings.Repeat(" ", widths[j]-width+1))
+		}
+		text.WriteString("│")
+		t.line(prefix, text.String())
+	}
+}
+
+// wrappedWidth returns the width of the widest line of segments wrapped to
+// columns.
+func (t *terminalWriter) wrappedWidth(segments []inlineSegment, columns int) int {
+	width := 0
+	for _, line := range t.renderer.wrapInlineSegments(segments, float32(columns)) {
+		width = max(width, int(line.width))
+	}
+	return width
+}
+
+// fitColumns narrows widths until they add up to no more than available,
+// taking from the columns with the most room above their minimum first and
+// then, if that is not enough, from the widest. No column goes below one.
+func fitColumns(widths, minimums []int, available int) {
+	total := 0
+	for _, width := range widths {
+		total += width
+	}
+
+	for total > available {
+		widest, room := -1, 0
+		for j := range widths {
+			if widths[j]-minimums[j] > room {
+				widest, room = j, widths[j]-minimums[j]
+			}
+		}
+		if widest < 0 {
+			for j := range widths {
+				if widths[j] > 1 && (widest < 0 || widths[j] > widths[widest]) {
+					widest = j
+				}
+			}
+		}
+		if widest < 0 {
+			return
+		}
+		widths[widest]--
+		total--
+	}
+}
+
+// clipSegments cuts a line of segments off at columns, for a word too long
+// for its table column.
+func clipSegments(segments []inlineSegment, columns int) []inlineSegment {
+	var clipped []inlineSegment
+	for _, segment := range segments {
+		width := displayWidth(segment.text)
+		if width <= columns {
+			clipped = append(clipped, segment)
+			columns -= width
+			continue
+		}
+		segment.text, _ = splitColumns(segment.text, columns)
+		if displayWidth(segment.text) > columns {
+			segment.text = ""
+		}
+		return append(clipped, segment)
+	}
+	return clipped
+}
+
+func isHeaderCells(cells []TableCell) bool {
+	if len(cells) == 0 {
+		return false
+	}
+	for _, cell := range cells {
+		if !cell.IsHeader {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package marquee
+
+import "testing"
+
+func TestRenderTerminal(t *testing.T) {
+	tests := []struct {
+		name  string
+		html  string
+		plain string
+		color string
+	}{
+		{"heading", `<h1>Title</h1><p>x</p>`,
+			"Title\n=====\n\nx\n",
+			"\x1b[0;1;4mTitle\x1b[0m\n\nx\n"},
+		{"subheading", `<h2>Sub</h2><h3>Three</h3>`,
+			"Sub\n---\n\nThree\n",
+			"\x1b[0;1mSub\x1b[0m\n\n\x1b[0;1mThree\x1b[0m\n"},
+		{"inline styles", `<p>a <b>b</b> <i>i</i> <code>c</code></p>`,
+			"a b i `c`\n",
+			"a \x1b[0;1mb\x1b[0m \x1b[0;3mi\x1b[0m \x1b[0;36mc\x1b[0m\n"},
+		{"links", `<p><a href="https://e.com/">e</a> <a href="#x">in</a></p>`,
+			"e <https://e.com/> in\n",
+			"\x1b[0;4;34m\x1b]8;;https://e.com/\x1b\\e\x1b[0m\x1b]8;;\x1b\\ \x1b[0;4;34min\x1b[0m\n"},
+		{"lists", `<ul><li>one<li>two</ul><ol><li>a<li>b</ol>`,
+			"• one\n• two\n\n1. a\n2. b\n",
+			"• one\n• two\n\n1. a\n2. b\n"},
+		{"preformatted", "<pre>x  y\nz</pre>",
+			"    x  y\n    z\n",
+			"\x1b[0;2m  │ \x1b[0mx  y\n\x1b[0;2m  │ \x1b[0mz\n"},
+		{"table", `<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>22</td></tr></table>`,
+			"┌───┬────┐\n│ A │ B  │\n├───┼────┤\n│ 1 │ 22 │\n└───┴────┘\n",
+			"┌───┬────┐\n│ \x1b[0;1mA\x1b[0m │ \x1b[0;1mB\x1b[0m  │\n├───┼────┤\n│ 1 │ 22 │\n└───┴────┘\n"},
+		{"wrapping", `<p>one two three four five six seven eight nine ten eleven twelve</p>`,
+			"one two three four five six\nseven eight nine ten eleven\ntwelve\n",
+			"one two three four five six\nseven eight nine ten eleven\ntwelve\n"},
+	}
+
+	for _, test := range tests {
+		doc := parse(t, test.html)
+		if got := RenderToTerminal(doc, TerminalOptions{Width: 30, Plain: true}); got != test.plain {
+			t.Errorf("%s, plain: got %q, want %q", test.name, got, test.plain)
+		}
+		if got := RenderToTerminal(doc, TerminalOptions{Width: 30}); got != test.color {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.color)
+		}
+	}
+}
+
+func TestDefaultTerminalOptions(t *testing.T) {
+	tests := []struct {
+		columns, noColor, term string
+		want                   TerminalOptions
+	}{
+		{"", "", "xterm", TerminalOptions{}},
+		{"120", "", "xterm", TerminalOptions{Width: 120}},
+		{"wide", "", "xterm", TerminalOptions{}},
+		{"", "1", "xterm", TerminalOptions{Plain: true}},
+		{"", "", "dumb", TerminalOptions{Plain: true}},
+	}
+
+	for _, test := range tests {
+		t.Setenv("COLUMNS", test.columns)
+		t.Setenv("NO_COLOR", test.noColor)
+		t.Setenv("TERM", test.term)
+		if got := DefaultTerminalOptions(); got != test.want {
+			t.Errorf("COLUMNS=%q NO_COLOR=%q TERM=%q: got %+v, want %+v",
+				test.columns, test.noColor, test.term, got, test.want)
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"日本", 4},
+		{"é", 1},
+		{"a‍b", 2},
+		{"한글", 4},
+	}
+
+	for _, test := range tests {
+		if got := displayWidth(test.text); got != test.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package marquee
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Theme is how a document looks: its fonts, the colors of the page and of
+// each kind of element, the spacing between blocks, the palettes of the
+// callout boxes and the style of the scrollbar. LightTheme is what the
+// widget has always looked like; DarkTheme and HighContrastTheme are
+// alternatives, and any of them can be copied and changed.
+type Theme struct {
+	Fonts     FontSet
+	Colors    ThemeColors
+	Spacing   ThemeSpacing
+	Callouts  CalloutPalettes
+	Scrollbar ScrollbarStyle
+}
+
+// ThemeColors are the colors of the page and of the elements on it.
+type ThemeColors struct {
+	// Background fills the page and Border frames it.
+	Background rl.Color
+	Border     rl.Color
+
+	// Text is the color of body text. Bold, Italic and Link are the colors
+	// text takes on when it becomes bold, italic or part of a link, and
+	// keeps for everything inside.
+	Text   rl.Color
+	Bold   rl.Color
+	Italic rl.Color
+	Link   rl.Color
+
+	// Heading is the color of h1 to h6, Term that of dt, ListMarker that of
+	// bullets and numbers, and Rule that of hr.
+	Heading    rl.Color
+	Term       rl.Color
+	ListMarker rl.Color
+	Rule       rl.Color
+
+	// Code colors inline code, and Pre the blocks of pre and block-level
+	// code. Code in a run of text takes Code.Text; its box is drawn only
+	// for code that stands on its own.
+	Code BoxColors
+	Pre  BoxColors
+
+	// Table is the outer frame of a table, Cell its body cells and Header
+	// its header cells.
+	Table  BoxColors
+	Cell   BoxColors
+	Header BoxColors
+
+	// CalloutOutline is the thin border around every callout box.
+	CalloutOutline rl.Color
+}
+
+// BoxColors are the colors of an element drawn as a box. A zero Background
+// or Border is not drawn.
+type BoxColors struct {
+	Text       rl.Color
+	Background rl.Color
+	Border     rl.Color
+}
+
+// VerticalMargins are the space left above and below a block, in pixels.
+type VerticalMargins struct {
+	Top, Bottom float32
+}
+
+// ThemeSpacing is the spacing of the layout, in pixels except for the line
+// height scales.
+type ThemeSpacing struct {
+	// LineHeightScale multiplies the height of a line that its font's
+	// metrics give, ascent plus descent plus line gap, to give the distance
+	// between lines of text, so that lines of larger text are further
+	// apart. LineHeight is the distance in pixels used instead when
+	// LineHeightScale is zero. BlockSpacing is the space left after a
+	// paragraph or a run of text.
+	LineHeight      float32
+	LineHeightScale float32
+	BlockSpacing    float32
+
+	// Headings are the margins of h1 to h6.
+	Headings [6]VerticalMargins
+
+	// ListIndent is how far list items are indented, and NestedListIndent
+	// how much further each level of nesting takes them. ListMargin is the
+	// space above a list, and ListMarkerGap the space between a bullet or
+	// number and the text of its item.
+	ListIndent       float32
+	NestedListIndent float32
+	ListMargin       float32
+	ListMarkerGap    float32
+
+	// DefinitionIndent is how far dd is indented, DefinitionSpacing the
+	// space after it and DefinitionListMargin the space above and below a
+	// dl.
+	DefinitionIndent     float32
+	DefinitionSpacing    float32
+	DefinitionListMargin float32
+
+	// PreLineHeight and PreLineHeightScale set the distance between lines
+	// of a pre block, as LineHeight and LineHeightScale do for body text.
+	// PrePadding is the space between its border and its text and
+	// PreMargin the space above and below it. CodePadding is the space
+	// either side of inline code.
+	PreLineHeight      float32
+	PreLineHeightScale float32
+	PrePadding         float32
+	PreMargin          float32
+	CodePadding        float32
+
+	// RuleMargin is the space above and below hr.
+	RuleMargin VerticalMargins
+
+	// CellPadding is the space between the border of a table cell and its
+	// text, and TableMargin the space above and below a table.
+	CellPadding float32
+	TableMargin float32
+
+	// CalloutPadding is the space inside a callout box, CalloutIconWidth
+	// the room its icon takes at the left, CalloutAccentWidth the width of
+	// the colored bar down its left edge and CalloutMargin the space below
+	// it.
+	CalloutPadding     float32
+	CalloutIconWidth   float32
+	CalloutAccentWidth float32
+	CalloutMargin      float32
+}
+
+// CalloutStyle is the palette of one kind of callout box: its background,
+// the accent of its icon and left edge, the color of its text, and its
+// icon.
+type CalloutStyle struct {
+	Background rl.Color
+	Accent     rl.Color
+	Text       rl.Color
+	Icon       string
+}
+
+// CalloutPalettes holds the style of each kind of callout box, chosen by
+// the class of the div.
+type CalloutPalettes struct {
+	Note    CalloutStyle
+	Info    CalloutStyle
+	Tip     CalloutStyle
+	Success CalloutStyle
+	Warning CalloutStyle
+	Danger  CalloutStyle
+}
+
+// style returns the palette for calloutType, or Note for one it does not
+// know.
+func (p CalloutPalettes) style(calloutType string) CalloutStyle {
+	switch calloutType {
+	case "info":
+		return p.Info
+	case "tip":
+		return p.Tip
+	case "success":
+		return p.Success
+	case "warning":
+		return p.Warning
+	case "danger":
+		return p.Danger
+	}
+	return p.Note
+}
+
+// ScrollbarStyle is the look of the widget's scrollbar thumb. Color is the
+// thumb at full opacity; it fades with HTMLWidget.ScrollbarAlpha.
+type ScrollbarStyle struct {
+	Width          float32
+	MinThumbHeight float32
+	Color          rl.Color
+}
+
+// ColorScheme is whether a document is shown light or dark. It chooses the
+// widget's theme and which @media (prefers-color-scheme) rules apply.
+type ColorScheme int
+
+const (
+	// ColorSchemeAuto follows the scheme the host reports, and is light
+	// until it reports one.
+	ColorSchemeAuto ColorScheme = iota
+	ColorSchemeLight
+	ColorSchemeDark
+)
+
+// resolve returns the scheme s stands for, light or dark, when the host
+// prefers host.
+func (s ColorScheme) resolve(host ColorScheme) ColorScheme {
+	if s == ColorSchemeAuto {
+		s = host
+	}
+	if s == ColorSchemeDark {
+		return ColorSchemeDark
+	}
+	return ColorSchemeLight
+}
+
+// LightTheme returns the theme the widget uses unless told otherwise: dark
+// text on a white page.
+func LightTheme() Theme {
+	return Theme{
+		Fonts: DefaultFonts(),
+		Colors: ThemeColors{
+			Background: rl.White,
+			Border:     rl.Color{R: 200, G: 200, B: 200, A: 255},
+			Text:       rl.Black,
+			Bold:       rl.DarkBlue,
+			Italic:     rl.DarkGreen,
+			Link:       rl.Blue,
+			Heading:    rl.DarkBlue,
+			Term:       rl.DarkBlue,
+			ListMarker: rl.Black,
+			Rule:       rl.Gray,
+			Code: BoxColors{
+				Text:       rl.Color{R: 40, G: 40, B: 40, A: 255},
+				Background: rl.Color{R: 240, G: 240, B: 240, A: 255},
+				Border:     rl.Color{R: 220, G: 220, B: 220, A: 255},
+			},
+			Pre: BoxColors{
+				Text:       rl.Color{R: 40, G: 40, B: 40, A: 255},
+				Background: rl.Color{R: 248, G: 248, B: 248, A: 255},
+				Border:     rl.Color{R: 220, G: 220, B: 220, A: 255},
+			},
+			Table: BoxColors{
+				Background: rl.White,
+				Border:     rl.Color{R: 200, G: 200, B: 200, A: 255},
+			},
+			Cell: BoxColors{
+				Text:   rl.Black,
+				Border: rl.Color{R: 220, G: 220, B: 220, A: 255},
+			},
+			Header: BoxColors{
+				Text:       rl.Color{R: 52, G: 58, B: 64, A: 255},
+				Background: rl.Color{R: 248, G: 249, B: 250, A: 255},
+				Border:     rl.Color{R: 220, G: 220, B: 220, A: 255},
+			},
+			CalloutOutline: rl.Color{R: 200, G: 200, B: 200, A: 100},
+		},
+		Spacing: defaultSpacing(),
+		Callouts: CalloutPalettes{
+			Note: CalloutStyle{
+				Background: rl.Color{R: 248, G: 249, B: 250, A: 255},
+				Accent:     rl.Color{R: 108, G: 117, B: 125, A: 255},
+				Text:       rl.Color{R: 33, G: 37, B: 41, A: 255},
+				Icon:       "📝",
+			},
+			Info: CalloutStyle{
+				Background: rl.Color{R: 217, G: 237, B: 247, A: 255},
+				Accent:     rl.Color{R: 52, G: 144, B: 220, A: 255},
+				Text:       rl.Color{R: 12, G: 84, B: 96, A: 255},
+				Icon:       "ℹ️",
+			},
+			Tip: CalloutStyle{
+				Background: rl.Color{R: 230, G: 245, B: 233, A: 255},
+				Accent:     rl.Color{R: 40, G: 167, B: 69, A: 255},
+				Text:       rl.Color{R: 21, G: 87, B: 36, A: 255},
+				Icon:       "💡",
+			},
+			Success: CalloutStyle{
+				Background: rl.Color{R: 230, G: 245, B: 233, A: 255},
+				Accent:     rl.Color{R: 40, G: 167, B: 69, A: 255},
+				Text:       rl.Color{R: 21, G: 87, B: 36, A: 255},
+				Icon:       "✅",
+			},
+			Warning: CalloutStyle{
+				Background: rl.Color{R: 255, G: 248, B: 220, A: 255},
+				Accent:     rl.Color{R: 255, G: 193, B: 7, A: 255},
+				Text:       rl.Color{R: 133, G: 77, B: 14, A: 255},
+				Icon:       "⚠️",
+			},
+			Danger: CalloutStyle{
+				Background: rl.Color{R: 253, G: 237, B: 237, A: 255},
+				Accent:     rl.Color{R: 220, G: 38, B: 127, A: 255},
+				Text:       rl.Color{R: 114, G: 28, B: 36, A: 255},
+				Icon:       "🚫",
+			},
+		},
+		Scrollbar: ScrollbarStyle{
+			Width:          10,
+			MinThumbHeight: 40,
+			Color:          rl.Color{R: 60, G: 60, B: 60, A: 120},
+		},
+	}
+}
+
+// DarkTheme returns a theme with light text on a dark page, for
+// applications with dark interfaces.
+func DarkTheme() Theme {
+	theme := LightTheme()
+	theme.Colors = ThemeColors{
+		Background: rl.Color{R: 24, G: 26, B: 31, A: 255},
+		Border:     rl.Color{R: 60, G: 64, B: 72, A: 255},
+		Text:       rl.Color{R: 220, G: 222, B: 226, A: 255},
+		Bold:       rl.Color{R: 140, G: 180, B: 255, A: 255},
+		Italic:     rl.Color{R: 130, G: 205, B: 150, A: 255},
+		Link:       rl.Color{R: 100, G: 165, B: 255, A: 255},
+		Heading:    rl.Color{R: 160, G: 192, B: 255, A: 255},
+		Term:       rl.Color{R: 160, G: 192, B: 255, A: 255},
+		ListMarker: rl.Color{R: 220, G: 222, B: 226, A: 255},
+		Rule:       rl.Color{R: 90, G: 94, B: 102, A: 255},
+		Code: BoxColors{
+			Text:       rl.Color{R: 230, G: 230, B: 230, A: 255},
+			Background: rl.Color{R: 45, G: 48, B: 55, A: 255},
+			Border:     rl.Color{R: 70, G: 74, B: 82, A: 255},
+		},
+		Pre: BoxColors{
+			Text:       rl.Color{R: 230, G: 230, B: 230, A: 255},
+			Background: rl.Color{R: 34, G: 37, B: 43, A: 255},
+			Border:     rl.Color{R: 70, G: 74, B: 82, A: 255},
+		},
+		Table: BoxColors{
+			Background: rl.Color{R: 28, G: 30, B: 36, A: 255},
+			Border:     rl.Color{R: 80, G: 84, B: 92, A: 255},
+		},
+		Cell: BoxColors{
+			Text:   rl.Color{R: 220, G: 222, B: 226, A: 255},
+			Border: rl.Color{R: 64, G: 68, B: 76, A: 255},
+		},
+		Header: BoxColors{
+			Text:       rl.Color{R: 240, G: 241, B: 243, A: 255},
+			Background: rl.Color{R: 44, G: 47, B: 54, A: 255},
+			Border:     rl.Color{R: 64, G: 68, B: 76, A: 255},
+		},
+		CalloutOutline: rl.Color{R: 90, G: 94, B: 102, A: 100},
+	}
+	theme.Callouts = CalloutPalettes{
+		Note:    darkCallout(theme.Callouts.Note, rl.Color{R: 38, G: 41, B: 47, A: 255}, rl.Color{R: 210, G: 214, B: 220, A: 255}),
+		Info:    darkCallout(theme.Callouts.Info, rl.Color{R: 22, G: 42, B: 58, A: 255}, rl.Color{R: 160, G: 210, B: 240, A: 255}),
+		Tip:     darkCallout(theme.Callouts.Tip, rl.Color{R: 24, G: 46, B: 32, A: 255}, rl.Color{R: 160, G: 225, B: 175, A: 255}),
+		Success: darkCallout(theme.Callouts.Success, rl.Color{R: 24, G: 46, B: 32, A: 255}, rl.Color{R: 160, G: 225, B: 175, A: 255}),
+		Warning: darkCallout(theme.Callouts.Warning, rl.Color{R: 56, G: 46, B: 18, A: 255}, rl.Color{R: 250, G: 215, B: 140, A: 255}),
+		Danger:  darkCallout(theme.Callouts.Danger, rl.Color{R: 58, G: 26, B: 32, A: 255}, rl.Color{R: 250, G: 170, B: 180, A: 255}),
+	}
+	theme.Scrollbar.Color = rl.Color{R: 200, G: 200, B: 200, A: 120}
+	return theme
+}
+
+// darkCallout returns light with the background and text of a dark theme,
+// keeping its accent and icon.
+func darkCallout(light CalloutStyle, background, text rl.Color) CalloutStyle {
+	light.Background = background
+	light.Text = text
+	return light
+}
+
+// HighContrastTheme returns a theme with white text on a black page, yellow
+// links and strong borders, for readers who need the most contrast.
+func HighContrastTheme() Theme {
+	white := rl.White
+	black := rl.Black
+	yellow := rl.Color{R: 255, G: 255, B: 0, A: 255}
+	cyan := rl.Color{R: 0, G: 255, B: 255, A: 255}
+
+	theme := LightTheme()
+	theme.Colors = ThemeColors{
+		Background:     black,
+		Border:         white,
+		Text:           white,
+		Bold:           white,
+		Italic:         cyan,
+		Link:           yellow,
+		Heading:        white,
+		Term:           white,
+		ListMarker:     white,
+		Rule:           white,
+		Code:           BoxColors{Text: white, Background: black, Border: white},
+		Pre:            BoxColors{Text: white, Background: black, Border: white},
+		Table:          BoxColors{Background: black, Border: white},
+		Cell:           BoxColors{Text: white, Border: white},
+		Header:         BoxColors{Text: black, Background: white, Border: white},
+		CalloutOutline: white,
+	}
+
+	callout := func(accent rl.Color, icon string) CalloutStyle {
+		return CalloutStyle{Background: black, Accent: accent, Text: white, Icon: icon}
+	}
+	theme.Callouts = CalloutPalettes{
+		Note:    callout(white, theme.Callouts.Note.Icon),
+		Info:    callout(cyan, theme.Callouts.Info.Icon),
+		Tip:     callout(rl.Color{R: 0, G: 255, B: 0, A: 255}, theme.Callouts.Tip.Icon),
+		Success: callout(rl.Color{R: 0, G: 255, B: 0, A: 255}, theme.Callouts.Success.Icon),
+		Warning: callout(yellow, theme.Callouts.Warning.Icon),
+		Danger:  callout(rl.Color{R: 255, G: 80, B: 80, A: 255}, theme.Callouts.Danger.Icon),
+	}
+	theme.Scrollbar.Color = rl.Color{R: 255, G: 255, B: 255, A: 255}
+	return theme
+}
+
+// defaultSpacing returns the spacing the widget has always used. Lines of
+// body text in the default fonts come out 20 pixels apart, as they always
+// were, and further with fonts whose metrics ask for more.
+func defaultSpacing() ThemeSpacing {
+	return ThemeSpacing{
+		LineHeight:      20,
+		LineHeightScale: 1.25,
+		BlockSpacing:    5,
+		Headings: [6]VerticalMargins{
+			{Top: 25, Bottom: 15},
+			{Top: 20, Bottom: 12},
+			{Top: 18, Bottom: 10},
+			{Top: 15, Bottom: 8},
+			{Top: 12, Bottom: 6},
+			{Top: 10, Bottom: 5},
+		},
+		ListIndent:           25,
+		NestedListIndent:     20,
+		ListMargin:           10,
+		ListMarkerGap:        6,
+		DefinitionIndent:     30,
+		DefinitionSpacing:    8,
+		DefinitionListMargin: 10,
+		PreLineHeight:        18,
+		PreLineHeightScale:   1.15,
+		PrePadding:           12,
+		PreMargin:            10,
+		CodePadding:          4,
+		RuleMargin:           VerticalMargins{Top: 10, Bottom: 15},
+		CellPadding:          12,
+		TableMargin:          10,
+		CalloutPadding:       15,
+		CalloutIconWidth:     50,
+		CalloutAccentWidth:   4,
+		CalloutMargin:        15,
+	}
+}
@@ -0,0 +1,176 @@
+package marquee
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// fragmentColors returns the color of each piece of text in the box and its
+// children, by its text.
+func fragmentColors(b LayoutBox, colors map[string]rl.Color) map[string]rl.Color {
+	for _, line := range b.Lines {
+		for _, fragment := range line.Fragments {
+			colors[fragment.Text] = fragment.Color
+		}
+	}
+	for _, child := range b.Children {
+		fragmentColors(child, colors)
+	}
+	return colors
+}
+
+func TestThemeColors(t *testing.T) {
+	const html = `<h2>heading</h2><p>text <b>bold</b> <i>italic</i> <a href="/x">link</a> <code>code</code></p>` +
+		`<ul><li>item</li></ul><pre>pre</pre>`
+
+	tests := []struct {
+		name  string
+		theme Theme
+	}{
+		{"light", LightTheme()},
+		{"dark", DarkTheme()},
+		{"high contrast", HighContrastTheme()},
+	}
+
+	for _, test := range tests {
+		r := NewHTMLRenderer()
+		r.Theme = test.theme
+		r.Measurer = FixedMetrics{}
+		layout := r.LayoutDocument(parse(t, html), RenderContext{Width: 400})
+
+		colors := test.theme.Colors
+		want := map[string]rl.Color{
+			"heading": colors.Heading,
+			"text":    colors.Text,
+			"bold":    colors.Bold,
+			"italic":  colors.Italic,
+			"link":    colors.Link,
+			"code":    colors.Code.Text,
+			"item":    colors.Text,
+			"pre":     colors.Pre.Text,
+		}
+		got := fragmentColors(layout.Root, map[string]rl.Color{})
+		for text, color := range want {
+			if got[text] != color {
+				t.Errorf("%s: %q is %v, want %v", test.name, text, got[text], color)
+			}
+		}
+
+		pre := layout.Root.Children[len(layout.Root.Children)-1]
+		if pre.Background != colors.Pre.Background || pre.BorderColor != colors.Pre.Border {
+			t.Errorf("%s: pre has background %v and border %v, want %v and %v",
+				test.name, pre.Background, pre.BorderColor, colors.Pre.Background, colors.Pre.Border)
+		}
+	}
+}
+
+func TestCalloutPalettes(t *testing.T) {
+	callouts := LightTheme().Callouts
+	tests := []struct {
+		calloutType string
+		want        CalloutStyle
+	}{
+		{"note", callouts.Note},
+		{"info", callouts.Info},
+		{"tip", callouts.Tip},
+		{"success", callouts.Success},
+		{"warning", callouts.Warning},
+		{"danger", callouts.Danger},
+		{"unknown", callouts.Note},
+	}
+
+	for _, test := range tests {
+		if got := callouts.style(test.calloutType); got != test.want {
+			t.Errorf("style(%q) = %+v, want %+v", test.calloutType, got, test.want)
+		}
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	w := newTestWidget(`<p>text</p>`)
+	render(w)
+
+	tests := []struct {
+		name  string
+		theme Theme
+	}{
+		{"high contrast", HighContrastTheme()},
+		{"dark", DarkTheme()},
+		{"light", LightTheme()},
+	}
+
+	for _, test := range tests {
+		w.SetTheme(test.theme)
+		w.Canvas = &RecordingCanvas{}
+		render(w)
+
+		ops := w.Canvas.(*RecordingCanvas).Ops
+		if len(ops) == 0 || ops[0].Color != test.theme.Colors.Background {
+			t.Errorf("%s: page is not filled with %v: %v", test.name, test.theme.Colors.Background, ops)
+		}
+		if got := fragmentColors(w.layout.Root, map[string]rl.Color{})["text"]; got != test.theme.Colors.Text {
+			t.Errorf("%s: text is %v, want %v", test.name, got, test.theme.Colors.Text)
+		}
+		if w.Theme.Colors != test.theme.Colors {
+			t.Errorf("%s: SetTheme did not replace the light theme", test.name)
+		}
+	}
+}
+
+func TestColorSchemeResolve(t *testing.T) {
+	tests := []struct {
+		scheme, host, want ColorScheme
+	}{
+		{ColorSchemeAuto, ColorSchemeAuto, ColorSchemeLight},
+		{ColorSchemeAuto, ColorSchemeLight, ColorSchemeLight},
+		{ColorSchemeAuto, ColorSchemeDark, ColorSchemeDark},
+		{ColorSchemeLight, ColorSchemeDark, ColorSchemeLight},
+		{ColorSchemeDark, ColorSchemeLight, ColorSchemeDark},
+		{ColorSchemeDark, ColorSchemeAuto, ColorSchemeDark},
+	}
+
+	for _, test := range tests {
+		if got := test.scheme.resolve(test.host); got != test.want {
+			t.Errorf("%v.resolve(%v) = %v, want %v", test.scheme, test.host, got, test.want)
+		}
+	}
+}
+
+func TestSetColorScheme(t *testing.T) {
+	w := newTestWidget(`<style>@media (prefers-color-scheme: dark) { p { color: #00f } }</style><p id="x">x</p>`)
+	p, _ := w.document.QuerySelector("#x")
+	blue := rl.Color{B: 255, A: 255}
+
+	tests := []struct {
+		name       string
+		set        func()
+		want       ColorScheme
+		background rl.Color
+		color      rl.Color
+	}{
+		{"default", func() {}, ColorSchemeLight, w.Theme.Colors.Background, rl.Color{}},
+		{"host dark", func() { w.SetHostColorScheme(ColorSchemeDark) }, ColorSchemeDark, w.DarkTheme.Colors.Background, blue},
+		{"light over host", func() { w.SetColorScheme(ColorSchemeLight) }, ColorSchemeLight, w.Theme.Colors.Background, rl.Color{}},
+		{"auto again", func() { w.SetColorScheme(ColorSchemeAuto) }, ColorSchemeDark, w.DarkTheme.Colors.Background, blue},
+		{"host light", func() { w.SetHostColorScheme(ColorSchemeLight) }, ColorSchemeLight, w.Theme.Colors.Background, rl.Color{}},
+		{"dark", func() { w.SetColorScheme(ColorSchemeDark) }, ColorSchemeDark, w.DarkTheme.Colors.Background, blue},
+	}
+
+	for _, test := range tests {
+		test.set()
+		w.Canvas = &RecordingCanvas{}
+		render(w)
+
+		if got := w.ColorScheme(); got != test.want {
+			t.Errorf("%s: ColorScheme() = %v, want %v", test.name, got, test.want)
+		}
+		if ops := w.Canvas.(*RecordingCanvas).Ops; len(ops) == 0 || ops[0].Color != test.background {
+			t.Errorf("%s: page is not filled with %v", test.name, test.background)
+		}
+		// The document is styled again in place, not parsed again.
+		if node, _ := w.document.QuerySelector("#x"); node != p || p.Style.Color != test.color {
+			t.Errorf("%s: p is %v, want %v, and the same node", test.name, p.Style.Color, test.color)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package marquee
+
+import (
+	"fmt"
+)
+
+// This file holds the subset of the HTML5 tree-construction rules that the
+// parser applies: void elements, implied end tags and element scopes.
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements hold text only; markup inside them is not parsed.
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "title": true, "textarea": true,
+}
+
+// closesParagraph lists the start tags that end an open <p>.
+var closesParagraph = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"center": true, "details": true, "dialog": true, "dir": true, "div": true,
+	"dl": true, "dd": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true,
+	"hgroup": true, "hr": true, "li": true, "listing": true, "main": true,
+	"menu": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"search": true, "section": true, "summary": true, "table": true,
+	"ul": true, "xmp": true,
+}
+
+// Elements that bound the search for an open element, per the HTML5
+// "has an element in scope" algorithms.
+var (
+	defaultScope = map[string]bool{
+		"applet": true, "caption": true, "html": true, "table": true,
+		"td": true, "th": true, "marquee": true, "object": true,
+		"template": true,
+	}
+	buttonScope   = extendScope(defaultScope, "button")
+	listItemScope = extendScope(defaultScope, "ol", "ul")
+	tableScope    = map[string]bool{"html": true, "table": true, "template": true}
+)
+
+// specialElements are the elements that stop the search for an open li, dd
+// or dt. address, div and p are special too but are skipped over.
+var specialElements = map[string]bool{
+	"applet": true, "area": true, "article": true, "aside": true,
+	"base": true, "blockquote": true, "body": true, "br": true,
+	"button": true, "caption": true, "center": true, "col": true,
+	"colgroup": true, "dd": true, "details": true, "dir": true, "dl": true,
+	"dt": true, "embed": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "head": true,
+	"header": true, "hgroup": true, "hr": true, "html": true, "img": true,
+	"input": true, "li": true, "link": true, "listing": true, "main": true,
+	"marquee": true, "menu": true, "meta": true, "nav": true, "object": true,
+	"ol": true, "pre": true, "section": true, "select": true,
+	"summary": true, "table": true, "tbody": true, "td": true,
+	"template": true, "textarea": true, "tfoot": true, "th": true,
+	"thead": true, "tr": true, "ul": true, "wbr": true, "xmp": true,
+}
+
+func extendScope(base map[string]bool, tags ...string) map[string]bool {
+	scope := make(map[string]bool, len(base)+len(tags))
+	for tag := range base {
+		scope[tag] = true
+	}
+	for _, tag := range tags {
+		scope[tag] = true
+	}
+	return scope
+}
+
+func isVoidElement(tagName string) bool {
+	return voidElements[tagName]
+}
+
+func isRawTextElement(tagName string) bool {
+	return rawTextElements[tagName]
+}
+
+func isHeadingTag(tagName string) bool {
+	switch tagName {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		return true
+	}
+	return false
+}
+
+// currentTag returns the original tag name of the current node.
+func (p *StateMachineParser) currentTag() string {
+	return p.nodeStack[len(p.nodeStack)-1].OriginalTag
+}
+
+// popTo closes every open element from the top of the stack down to and
+// including the one at index.
+func (p *StateMachineParser) popTo(index int) {
+	if index < 1 {
+		return
+	}
+	p.nodeStack = p.nodeStack[:index]
+	p.currentDepth = len(p.nodeStack) - 1
+}
+
+// closeImplied closes the element at index and everything above it because
+// a start tag implies their end. Elements whose end tag is not optional are
+// reported as unclosed.
+func (p *StateMachineParser) closeImplied(index int) {
+	p.reportUnclosed(index)
+	p.popTo(index)
+}
+
+// findInScope returns the stack index of the nearest open element named
+// tagName, or -1 if a scope boundary is reached first.
+func (p *StateMachineParser) findInScope(tagName string, scope map[string]bool) int {
+	for i := len(p.nodeStack) - 1; i > 0; i-- {
+		tag := p.nodeStack[i].OriginalTag
+		if tag == tagName {
+			return i
+		}
+		if scope[tag] {
+			return -1
+		}
+	}
+	return -1
+}
+
+// closeImpliedElements applies the end tags that HTML5 implies when tagName
+// starts, so "<p>one<p>two" builds two sibling paragraphs and a new <li>
+// closes the previous one.
+func (p *StateMachineParser) closeImpliedElements(tagName string) {
+	switch tagName {
+	case "li":
+		p.closeListItem(map[string]bool{"li": true})
+	case "dd", "dt":
+		p.closeListItem(map[string]bool{"dd": true, "dt": true})
+	case "tr":
+		p.closeTableContent("td", "th", "tr")
+	case "td", "th":
+		p.closeTableContent("td", "th")
+	case "thead", "tbody", "tfoot":
+		p.closeTableContent("td", "th", "tr", "thead", "tbody", "tfoot")
+	case "option":
+		if p.currentTag() == "option" {
+			p.popTo(len(p.nodeStack) - 1)
+		}
+	case "optgroup":
+		if p.currentTag() == "option" {
+			p.popTo(len(p.nodeStack) - 1)
+		}
+		if p.currentTag() == "optgroup" {
+			p.popTo(len(p.nodeStack) - 1)
+		}
+	case "a":
+		if index := p.findInScope("a", defaultScope); index > 0 {
+			p.closeImplied(index)
+		}
+	}
+
+	if closesParagraph[tagName] {
+		if index := p.findInScope("p", buttonScope); index > 0 {
+			p.closeImplied(index)
+		}
+	}
+
+	if isHeadingTag(tagName) && isHeadingTag(p.currentTag()) {
+		p.closeImplied(len(p.nodeStack) - 1)
+	}
+}
+
+// closeListItem closes an open li, dd or dt before a new one starts. The
+// search stops at the enclosing list or any other special element.
+func (p *StateMachineParser) closeListItem(items map[string]bool) {
+	for i := len(p.nodeStack) - 1; i > 0; i-- {
+		tag := p.nodeStack[i].OriginalTag
+		if items[tag] {
+			p.closeImplied(i)
+			return
+		}
+		if specialElements[tag] && tag != "address" && tag != "div" && tag != "p" {
+			return
+		}
+	}
+}
+
+// closeTableContent closes open table parts named in tags, without leaving
+// the nearest table.
+func (p *StateMachineParser) closeTableContent(tags ...string) {
+	closing := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		closing[tag] = true
+	}
+
+	index := -1
+	for i := len(p.nodeStack) - 1; i > 0; i-- {
+		tag := p.nodeStack[i].OriginalTag
+		if tag == "table" || tableScope[tag] {
+			break
+		}
+		if closing[tag] {
+			index = i
+		}
+	}
+
+	if index > 0 {
+		p.closeImplied(index)
+	}
+}
+
+// closeElement handles an end tag. Only an element within the tag's scope is
+// closed, so a stray "</div>" inside a table cell cannot close the div
+// around the table.
+func (p *StateMachineParser) closeElement(tagName string) {
+	switch {
+	case tagName == "br":
+		// "</br>" is treated as "<br>".
+		p.addDiagnostic(SeverityWarning, DiagnosticStrayEndTag, p.tagStart, tagName,
+			"</br> is not allowed and is treated as <br>")
+		p.closeImpliedElements(tagName)
+		p.insertElement(tagName, nil)
+		return
+	case isVoidElement(tagName):
+		p.addDiagnostic(SeverityWarning, DiagnosticStrayEndTag, p.tagStart, tagName,
+			fmt.Sprintf("<%s> is a void element and has no end tag", tagName))
+		return
+	}
+
+	scope := defaultScope
+	switch tagName {
+	case "p":
+		scope = buttonScope
+	case "li":
+		scope = listItemScope
+	case "table", "thead", "tbody", "tfoot", "tr", "td", "th":
+		scope = tableScope
+	}
+
+	index := -1
+	if isHeadingTag(tagName) {
+		// Any heading end tag closes whichever heading is open.
+		for _, heading := range []string{"h1", "h2", "h3", "h4", "h5", "h6"} {
+			if i := p.findInScope(heading, scope); i > index {
+				index = i
+			}
+		}
+	} else {
+		index = p.findInScope(tagName, scope)
+	}
+
+	if index > 0 {
+		p.reportUnclosed(index + 1)
+		p.popTo(index)
+		return
+	}
+
+	p.addDiagnostic(SeverityWarning, DiagnosticStrayEndTag, p.tagStart, tagName,
+		fmt.Sprintf("</%s> has no matching open element", tagName))
+
+	if tagName == "p" {
+		// "</p>" without an open paragraph produces an empty one.
+		p.insertElement("p", nil)
+		if p.currentTag() == "p" {
+			p.popTo(len(p.nodeStack) - 1)
+		}
+	}
+}
@@ -43,6 +43,7 @@ const (
 	NodeTypeText NodeType = iota
 	NodeTypeElement
 	NodeTypeDocument
+	NodeTypeComment
 )
 
 type NodeContext int
@@ -63,10 +64,17 @@ const (
 )
 
 type inlineSegment struct {
-	text  string
-	font  rl.Font
-	color rl.Color
-	href  string
+	text       string
+	font       Font
+	color      rl.Color
+	background rl.Color
+	href       string
+
+	underline   bool
+	lineThrough bool
+
+	// lineBreak marks a forced line break from <br>; text is empty.
+	lineBreak bool
 }
 
 type ParserState int
@@ -82,6 +90,11 @@ const (
 	StateTagClose
 	StateEndTag
 	StateComment
+	StateBogusComment
+	StateDoctype
+	StateCDATA
+	StateProcessingInstruction
+	StateRawText
 )
 
 type HTMLElement struct {
@@ -94,19 +107,21 @@ type HTMLElement struct {
 	Children []HTMLElement
 }
 
+// FontSet holds the fonts the renderers use for body text, headings and
+// code. DefaultFonts returns the sizes the widget has always used.
 type FontSet struct {
-	Regular        rl.Font
-	Bold           rl.Font
-	Italic         rl.Font
-	BoldItalic     rl.Font
-	H1             rl.Font
-	H2             rl.Font
-	H3             rl.Font
-	H4             rl.Font
-	H5             rl.Font
-	H6             rl.Font
-	Monospace      rl.Font
-	MonospaceLarge rl.Font
+	Regular        Font
+	Bold           Font
+	Italic         Font
+	BoldItalic     Font
+	H1             Font
+	H2             Font
+	H3             Font
+	H4             Font
+	H5             Font
+	H6             Font
+	Monospace      Font
+	MonospaceLarge Font
 }
 
 type LinkArea struct {